@@ -1,76 +1,54 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"math/rand"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/adservice/rest"
-	"github.com/sirupsen/logrus"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
 const MAX_ADS_TO_SERVE int = 2
 
 var (
 	adservice *Adservice
-	log       *logrus.Logger
+	log       *logging.Logger
+	router    *fission.Router
 )
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
 
-	log = logrus.New()
-	log.Formatter = &logrus.JSONFormatter{
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "severity",
-			logrus.FieldKeyMsg:   "message",
-		},
-		TimestampFormat: time.RFC3339Nano,
+	log = logging.NewFromEnv()
+	if _, err := telemetry.Setup(context.Background(), telemetry.FromEnv(shop.AdService.String())); err != nil {
+		log.Warnf("could not set up telemetry: %v", err)
 	}
-	log.Out = os.Stdout
 
 	adservice = new(Adservice)
 	adservice.Set_max_ads_to_serve(MAX_ADS_TO_SERVE)
 	adservice.CreateAdsMap()
+	router = fission.NewRouter(shop.AdService, log,
+		fission.Route{
+			Name:   "GetAds",
+			Method: http.MethodGet,
+			Params: []string{"context_keys"},
+			New:    func() interface{} { return new(rest.AdRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return adservice.GetAds(ctx, in.(*rest.AdRequest))
+			},
+		},
+	)
+	serveGRPC()
 }
 
 // Handler is the entry point for this fission function
 func Handler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		log.Errorf("methods other than GET are not supported")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	v := r.URL.Query()
-	if v.Has("context_keys") {
-		result, err := adservice.GetAds(&rest.AdRequest{ContextKeys: strings.Split(v.Get("context_keys"), ",")})
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(result)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else {
-		log.Errorf("cannot get context_keys")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
+	router.ServeHTTP(w, r)
 }
 
 type Adservice struct {
@@ -78,22 +56,29 @@ type Adservice struct {
 	adsMap           map[string][]*rest.Ad
 }
 
-func (as *Adservice) GetAds(req *rest.AdRequest) (*rest.AdResponse, error) {
+func (as *Adservice) GetAds(ctx context.Context, req *rest.AdRequest) (*rest.AdResponse, error) {
 	var allads []*rest.Ad
+	log := fission.Logger(ctx)
 	log.Info("received ad request (context_words=" + req.GetContextKeysList() + ")")
 	if req.GetContextKeysCount() > 0 {
 		for i := 0; i < req.GetContextKeysCount(); i++ {
-			ads := as.GetAdsByCategory(req.GetContextkeys()[i])
+			category := req.GetContextkeys()[i]
+			ads := as.GetAdsByCategory(category)
 			allads = append(allads, ads...)
+			if len(ads) > 0 {
+				telemetry.Count(ctx, telemetry.AdImpressions, int64(len(ads)), telemetry.CategoryKey.String(category))
+			}
 		}
 	} else {
 		log.Info("No Context provided. Constructing random Ads.")
 		allads = as.GetRandomAds()
+		telemetry.Count(ctx, telemetry.AdImpressions, int64(len(allads)), telemetry.CategoryKey.String("random"))
 	}
 	if len(allads) == 0 {
 		// Serve random ads.
 		log.Info("No Ads found based on context. Constructing random Ads.")
 		allads = as.GetRandomAds()
+		telemetry.Count(ctx, telemetry.AdImpressions, int64(len(allads)), telemetry.CategoryKey.String("random"))
 	}
 	return &rest.AdResponse{Ads: allads}, nil
 }
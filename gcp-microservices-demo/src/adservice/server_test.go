@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -8,6 +9,13 @@ import (
 	"testing"
 
 	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/adservice/rest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/contract"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/openapi"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry/telemetrytest"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -89,3 +97,54 @@ func TestEmpty(t *testing.T) {
 		t.Errorf("should be bad request")
 	}
 }
+
+func TestImpressions(t *testing.T) {
+	rec := telemetrytest.New(t)
+	Handler(httptest.NewRecorder(), httptest.NewRequest("GET", "http://example.com/ad?context_keys=clothing,kitchen,none", nil))
+	for category, want := range map[string]int64{"clothing": 1, "kitchen": 2} {
+		m := rec.Metric(t, telemetry.AdImpressions, telemetry.CategoryKey.String(category))
+		if got := m.Sum.AsInt64(); got != want {
+			t.Errorf("%s impressions = %d, want %d", category, got, want)
+		}
+	}
+}
+
+func TestConformsToSpec(t *testing.T) {
+	spec, err := openapi.Load("../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := map[string]interface{}{"AdRequest": rest.AdRequest{}, "AdResponse": rest.AdResponse{}, "Ad": rest.Ad{}}
+	for name, v := range types {
+		for _, err := range spec.CheckType(name, v) {
+			t.Error(err)
+		}
+	}
+	for _, err := range spec.CheckHandler("adservice", http.HandlerFunc(Handler)) {
+		t.Error(err)
+	}
+}
+
+// TestHonorsContracts replays the pacts recorded by the consumers of this
+// function.
+func TestHonorsContracts(t *testing.T) {
+	contract.Verify(t, "../../contracts", "adservice", http.HandlerFunc(Handler), nil)
+}
+
+// TestTransports calls the ads over HTTP/JSON, gRPC and Connect.
+func TestTransports(t *testing.T) {
+	clients := rpctest.Clients(t, shop.AdService, http.HandlerFunc(Handler), router.RPC())
+	for _, transport := range rpc.Transports {
+		c := clients[transport]
+		t.Run(transport, func(t *testing.T) {
+			res, err := c.Ads.GetAds(context.Background(), &shop.AdRequest{ContextKeys: []string{"clothing"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []*shop.Ad{{RedirectUrl: "/product/66VCHSJNUP", Text: "Tank top for sale. 20% off."}}
+			if diff := cmp.Diff(res.GetAds(), want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import rest
 import http
 
 def main():
+    if request.path.endswith("/item"):
+        return item()
     if request.method == "POST":    #AddItem
         try:
             body = request.get_data()
@@ -19,7 +21,7 @@ def main():
             req = rest.GetCartRequest(user_id)
             resp = rest.cartservice.getCart(req)
             resp_body = json.dumps(resp.toDict())
-            return Response(response=resp_body, status=http.HTTPStatus.OK)
+            return Response(response=resp_body, status=http.HTTPStatus.OK, mimetype="application/json")
         except Exception as e:
             print(e)
             return Response(status=http.HTTPStatus.BAD_REQUEST)
@@ -34,4 +36,29 @@ def main():
             return Response(status=http.HTTPStatus.BAD_REQUEST)
     else:
         print("methods other than POST and GET and DELETE are not supported")
+        return Response(status=http.HTTPStatus.BAD_REQUEST)
+
+def item():
+    if request.method == "PUT":     #UpdateItem
+        try:
+            body = request.get_data()
+            req = rest.dict2UpdateItemRequest(json.loads(body))
+            if req.item is None or req.item.product_id is None or req.item.quantity is None or req.item.quantity < 0:
+                return Response(status=http.HTTPStatus.BAD_REQUEST)
+            resp = rest.cartservice.updateItem(req)
+            return Response(response=json.dumps(resp.toDict()), status=http.HTTPStatus.OK, mimetype="application/json")
+        except Exception as e:
+            print(e)
+            return Response(status=http.HTTPStatus.BAD_REQUEST)
+    elif request.method == "DELETE":    #RemoveItem
+        try:
+            body = request.get_data()
+            req = rest.dict2RemoveItemRequest(json.loads(body))
+            resp = rest.cartservice.removeItem(req)
+            return Response(response=json.dumps(resp.toDict()), status=http.HTTPStatus.OK, mimetype="application/json")
+        except Exception as e:
+            print(e)
+            return Response(status=http.HTTPStatus.BAD_REQUEST)
+    else:
+        print("methods other than PUT and DELETE are not supported on /cart/item")
         return Response(status=http.HTTPStatus.BAD_REQUEST)
\ No newline at end of file
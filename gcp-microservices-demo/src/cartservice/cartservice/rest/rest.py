@@ -80,6 +80,38 @@ def dict2EmptyCartRequest(dic):
         user_id = dic["user_id"]
     return EmptyCartRequest(user_id)
 
+class UpdateItemRequest:
+    def __init__(self, user_id, item):
+        self.user_id = user_id
+        self.item = item
+
+    def toDict(self):
+        return {"user_id": self.user_id, "item": self.item.toDict()}
+
+def dict2UpdateItemRequest(dic):
+    user_id, item = None, None
+    if "user_id" in dic:
+        user_id = dic["user_id"]
+    if "item" in dic:
+        item = dict2CartItem(dic["item"])
+    return UpdateItemRequest(user_id, item)
+
+class RemoveItemRequest:
+    def __init__(self, user_id, product_id):
+        self.user_id = user_id
+        self.product_id = product_id
+
+    def toDict(self):
+        return {"user_id": self.user_id, "product_id": self.product_id}
+
+def dict2RemoveItemRequest(dic):
+    user_id, product_id = None, None
+    if "user_id" in dic:
+        user_id = dic["user_id"]
+    if "product_id" in dic:
+        product_id = dic["product_id"]
+    return RemoveItemRequest(user_id, product_id)
+
 class CartService:
     CART_FIELD_NAME = "cart"
 
@@ -126,4 +158,52 @@ class CartService:
         try:
             self.redisClient.hdel(emptyCartRequest.user_id, self.CART_FIELD_NAME)
         except redis.exceptions.ConnectionError:
-            print("cannot connect redis database!")
\ No newline at end of file
+            print("cannot connect redis database!")
+
+    def updateItem(self, updateItemRequest):
+        # sets the quantity of a product, adding it if the cart lacks it and
+        # removing it if the quantity is 0
+        print("UpdateItem called with userId=" + updateItemRequest.user_id)
+        try:
+            value = self.redisClient.hget(updateItemRequest.user_id, self.CART_FIELD_NAME)
+            if value != None:
+                cart = dict2Cart(json.loads(value))
+            else:
+                cart = Cart(updateItemRequest.user_id, [])
+            item = updateItemRequest.item
+            items = [i for i in cart.items if i.product_id != item.product_id]
+            if item.quantity > 0:
+                existing = [i for i in cart.items if i.product_id == item.product_id]
+                if existing:
+                    existing[0].quantity = item.quantity
+                    items = cart.items
+                else:
+                    items.append(item)
+            cart.items = items
+            self.saveCart(cart, updateItemRequest.user_id)
+            return cart
+        except redis.exceptions.ConnectionError:
+            print("cannot connect redis database!")
+            raise
+
+    def removeItem(self, removeItemRequest):
+        print("RemoveItem called with userId=" + removeItemRequest.user_id)
+        try:
+            value = self.redisClient.hget(removeItemRequest.user_id, self.CART_FIELD_NAME)
+            if value == None:
+                return Cart(None, [])
+            cart = dict2Cart(json.loads(value))
+            cart.items = [i for i in cart.items if i.product_id != removeItemRequest.product_id]
+            self.saveCart(cart, removeItemRequest.user_id)
+            return cart
+        except redis.exceptions.ConnectionError:
+            print("cannot connect redis database!")
+            raise
+
+    def saveCart(self, cart, user_id):
+        # an empty cart is deleted, as EmptyCart does
+        if len(cart.items) == 0:
+            self.redisClient.hdel(user_id, self.CART_FIELD_NAME)
+            cart.user_id = None
+        else:
+            self.redisClient.hset(user_id, self.CART_FIELD_NAME, json.dumps(cart.toDict()))
\ No newline at end of file
@@ -38,6 +38,40 @@ class TestCartService(unittest.TestCase):
         # clean up
         rest.cartservice.emptyCart(rest.EmptyCartRequest(user_id))
 
+    def test_updateItem(self):
+        user_id = ''.join(random.sample(string.ascii_letters + string.digits, 10))
+        rest.cartservice.addItem(rest.AddItemRequest(user_id, rest.CartItem("shoes", 2)))
+        rest.cartservice.addItem(rest.AddItemRequest(user_id, rest.CartItem("hat", 1)))
+        expect = rest.Cart(user_id, [rest.CartItem("shoes", 4), rest.CartItem("hat", 1)])
+        res = rest.cartservice.updateItem(rest.UpdateItemRequest(user_id, rest.CartItem("shoes", 4)))
+        self.assertDictEqual(expect.toDict(), res.toDict(), "number of shoes should be 4!")
+        res = rest.cartservice.getCart(rest.GetCartRequest(user_id))
+        self.assertDictEqual(expect.toDict(), res.toDict(), "number of shoes should be 4!")
+        # clean up
+        rest.cartservice.emptyCart(rest.EmptyCartRequest(user_id))
+
+    def test_updateItemToZero(self):
+        user_id = ''.join(random.sample(string.ascii_letters + string.digits, 10))
+        rest.cartservice.addItem(rest.AddItemRequest(user_id, rest.CartItem("shoes", 2)))
+        expect = rest.Cart(None, [])
+        res = rest.cartservice.updateItem(rest.UpdateItemRequest(user_id, rest.CartItem("shoes", 0)))
+        self.assertDictEqual(expect.toDict(), res.toDict(), "the cart should be empty!")
+        res = rest.cartservice.getCart(rest.GetCartRequest(user_id))
+        self.assertDictEqual(expect.toDict(), res.toDict(), "the cart should be empty!")
+
+    def test_removeItem(self):
+        user_id = ''.join(random.sample(string.ascii_letters + string.digits, 10))
+        rest.cartservice.addItem(rest.AddItemRequest(user_id, rest.CartItem("shoes", 2)))
+        rest.cartservice.addItem(rest.AddItemRequest(user_id, rest.CartItem("hat", 1)))
+        expect = rest.Cart(user_id, [rest.CartItem("hat", 1)])
+        res = rest.cartservice.removeItem(rest.RemoveItemRequest(user_id, "shoes"))
+        self.assertDictEqual(expect.toDict(), res.toDict(), "only the hat should be left!")
+        # removing a product the cart lacks changes nothing
+        res = rest.cartservice.removeItem(rest.RemoveItemRequest(user_id, "shoes"))
+        self.assertDictEqual(expect.toDict(), res.toDict(), "only the hat should be left!")
+        # clean up
+        rest.cartservice.emptyCart(rest.EmptyCartRequest(user_id))
+
 
 if __name__ == "__main__":
     unittest.main()
\ No newline at end of file
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+)
+
+// serveGRPC serves the routes of the function over gRPC on GRPC_ADDR in
+// the background if it is set.
+func serveGRPC() {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		return
+	}
+	go func() {
+		log.Infof("serving gRPC on %s", addr)
+		if err := rpc.ListenAndServe(addr, router.RPC()); err != nil {
+			log.Errorf("gRPC server failed: %v", err)
+		}
+	}()
+}
+
+// transportOptions selects how checkout reaches the Go functions it calls
+// from SHOP_TRANSPORT: http (the default), connect through the router, or
+// grpc to the targets in PRODUCT_CATALOG_SERVICE_GRPC_ADDR and
+// SHIPPING_SERVICE_GRPC_ADDR.
+func transportOptions() ([]shop.Option, error) {
+	transport := os.Getenv("SHOP_TRANSPORT")
+	addrs := map[shop.Service]string{
+		shop.CatalogService:  os.Getenv("PRODUCT_CATALOG_SERVICE_GRPC_ADDR"),
+		shop.ShippingService: os.Getenv("SHIPPING_SERVICE_GRPC_ADDR"),
+	}
+	if transport == rpc.Connect {
+		router := shop.New(shop.WithBaseURL(routerAddr))
+		for svc := range addrs {
+			addrs[svc] = router.Addr(svc)
+		}
+	}
+	return rpc.Options(transport, addrs)
+}
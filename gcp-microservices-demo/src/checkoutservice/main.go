@@ -15,142 +15,145 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
 
 	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/checkoutservice/money"
-	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/checkoutservice/rest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
-const (
-	productCatalogSvcAddr = "http://router.fission.svc.cluster.local/product"
-	cartSvcAddr           = "http://router.fission.svc.cluster.local/cart"
-	currencySvcAddr       = "http://router.fission.svc.cluster.local/currency"
-	shippingSvcAddr       = "http://router.fission.svc.cluster.local/shipping"
-	emailSvcAddr          = "http://router.fission.svc.cluster.local/email"
-	paymentSvcAddr        = "http://router.fission.svc.cluster.local/payment"
-)
+// routerAddr is the Fission router every downstream function is reached
+// through.
+const routerAddr = shop.DefaultBaseURL
 
-var log *logrus.Logger
+var log *logging.Logger
 var svc *checkoutService
+var router *fission.Router
 
 func init() {
-	log = logrus.New()
-	log.Level = logrus.DebugLevel
-	log.Formatter = &logrus.JSONFormatter{
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "severity",
-			logrus.FieldKeyMsg:   "message",
-		},
-		TimestampFormat: time.RFC3339Nano,
+	log = logging.NewFromEnv()
+	if _, err := telemetry.Setup(context.Background(), telemetry.FromEnv(shop.CheckoutService.String())); err != nil {
+		log.Warnf("could not set up telemetry: %v", err)
 	}
-	log.Out = os.Stdout
-	svc = &checkoutService{
-		productCatalogSvcAddr: productCatalogSvcAddr,
-		cartSvcAddr:           cartSvcAddr,
-		currencySvcAddr:       currencySvcAddr,
-		shippingSvcAddr:       shippingSvcAddr,
-		emailSvcAddr:          emailSvcAddr,
-		paymentSvcAddr:        paymentSvcAddr,
+
+	opts, err := transportOptions()
+	if err != nil {
+		log.Fatalf("could not set up the shop client: %v", err)
 	}
+	svc = newCheckoutService(shop.New(append([]shop.Option{shop.WithBaseURL(routerAddr)}, opts...)...), orderStoreFromEnv())
+	router = fission.NewRouter(shop.CheckoutService, log,
+		fission.Route{
+			Name:   "PlaceOrder",
+			Method: http.MethodPost,
+			New:    func() interface{} { return new(shop.PlaceOrderRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				resp, err := svc.PlaceOrder(ctx, in.(*shop.PlaceOrderRequest))
+				if err != nil {
+					return nil, downstreamError(err)
+				}
+				return resp, nil
+			},
+		},
+		fission.Route{
+			Name:     "ListOrders",
+			Method:   http.MethodGet,
+			Params:   []string{"user_id"},
+			Optional: []string{"page_size", "page_token"},
+			New:      func() interface{} { return new(shop.ListOrdersRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return svc.ListOrders(ctx, in.(*shop.ListOrdersRequest))
+			},
+		},
+		fission.Route{
+			Name:   "GetOrder",
+			Method: http.MethodGet,
+			Params: []string{"user_id", "order_id"},
+			New:    func() interface{} { return new(shop.GetOrderRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return svc.GetOrder(ctx, in.(*shop.GetOrderRequest))
+			},
+		},
+	)
+	serveGRPC()
 }
 
 // Handler is the entry point for this fission function
 func Handler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "POST" {
-		raw_req, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		req := new(rest.PlaceOrderRequest)
-		err = json.Unmarshal(raw_req, req)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		res, err := svc.PlaceOrder(req)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(res)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else {
-		log.Errorf("methods other than POST are not supported")
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	router.ServeHTTP(w, r)
+}
+
+// downstreamError maps the failure of a downstream call to the status
+// PlaceOrder answers with: 503 when the call may succeed if sent again, 422
+// when the downstream function rejected the order, and 500 otherwise.
+func downstreamError(err error) error {
+	var e *shop.Error
+	switch {
+	case !errors.As(err, &e):
+		return err
+	case shop.IsRetryable(err):
+		return fission.Errorf(http.StatusServiceUnavailable, "%w", err)
+	case e.StatusCode >= 400 && e.StatusCode < 500:
+		return fission.Errorf(http.StatusUnprocessableEntity, "%w", err)
 	}
+	return err
 }
 
 type checkoutService struct {
-	productCatalogSvcAddr string
-	cartSvcAddr           string
-	currencySvcAddr       string
-	shippingSvcAddr       string
-	emailSvcAddr          string
-	paymentSvcAddr        string
+	client *shop.Client
+	orders orderStore
+	now    func() time.Time
+}
+
+func newCheckoutService(client *shop.Client, orders orderStore) *checkoutService {
+	return &checkoutService{client: client, orders: orders, now: time.Now}
 }
 
-func (cs *checkoutService) PlaceOrder(req *rest.PlaceOrderRequest) (*rest.PlaceOrderResponse, error) {
-	log.Infof("[PlaceOrder] user_id=%q user_currency=%q", req.UserId, req.UserCurrency)
+func (cs *checkoutService) PlaceOrder(ctx context.Context, req *shop.PlaceOrderRequest) (*shop.PlaceOrderResponse, error) {
+	log := fission.Logger(ctx)
+	log.Infof("[PlaceOrder] user_currency=%q", req.UserCurrency)
 
 	orderID, err := uuid.NewUUID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate order uuid")
 	}
 
-	prep, err := cs.prepareOrderItemsAndShippingQuoteFromCart(req.UserId, req.UserCurrency, req.Address)
+	prep, err := cs.prepareOrderItemsAndShippingQuoteFromCart(ctx, req.UserId, req.UserCurrency, req.Address)
 	if err != nil {
 		return nil, err
 	}
 
-	total := rest.Money{CurrencyCode: req.UserCurrency, Units: 0, Nanos: 0}
+	total := shop.Money{CurrencyCode: req.UserCurrency, Units: 0, Nanos: 0}
 	total = money.Must(money.Sum(total, *prep.shippingCostLocalized))
 	for _, it := range prep.orderItems {
 		multPrice := money.MultiplySlow(*it.Cost, uint32(it.GetItem().GetQuantity()))
 		total = money.Must(money.Sum(total, multPrice))
 	}
 
-	txID, err := cs.chargeCard(&total, req.CreditCard)
+	txID, err := cs.chargeCard(ctx, &total, req.CreditCard)
 	if err != nil {
-		return nil, fmt.Errorf("failed to charge card: %+v", err)
+		return nil, fmt.Errorf("failed to charge card: %w", err)
 	}
 	log.Infof("payment went through (transaction_id: %s)", txID)
 
-	shippingTrackingID, err := cs.shipOrder(req.Address, prep.cartItems)
+	shippingTrackingID, err := cs.shipOrder(ctx, req.Address, prep.cartItems)
 	if err != nil {
-		return nil, fmt.Errorf("shipping error: %+v", err)
+		return nil, fmt.Errorf("shipping error: %w", err)
 	}
 
-	err = cs.emptyUserCart(req.UserId)
+	err = cs.emptyUserCart(ctx, req.UserId)
 	if err != nil {
 		return nil, err
 	}
 
-	orderResult := &rest.OrderResult{
+	orderResult := &shop.OrderResult{
 		OrderId:            orderID.String(),
 		ShippingTrackingId: shippingTrackingID,
 		ShippingCost:       prep.shippingCostLocalized,
@@ -158,38 +161,42 @@ func (cs *checkoutService) PlaceOrder(req *rest.PlaceOrderRequest) (*rest.PlaceO
 		Items:              prep.orderItems,
 	}
 
-	if err := cs.sendOrderConfirmation(req.Email, orderResult); err != nil {
-		log.Warnf("failed to send order confirmation to %q: %+v", req.Email, err)
+	if err := cs.sendOrderConfirmation(ctx, req.Email, orderResult); err != nil {
+		log.WithError(err).Warn("failed to send the order confirmation")
 	} else {
-		log.Infof("order confirmation email sent to %q", req.Email)
+		log.Info("order confirmation email sent")
 	}
-	resp := &rest.PlaceOrderResponse{Order: orderResult}
+	cs.record(ctx, req.UserId, orderResult, total)
+	currency := telemetry.CurrencyKey.String(total.GetCurrencyCode())
+	telemetry.Count(ctx, telemetry.OrdersPlaced, 1, currency)
+	telemetry.Sum(ctx, telemetry.OrderRevenue, float64(total.GetUnits())+float64(total.GetNanos())/1e9, currency)
+	resp := &shop.PlaceOrderResponse{Order: orderResult}
 	return resp, nil
 }
 
 type orderPrep struct {
-	orderItems            []*rest.OrderItem
-	cartItems             []*rest.CartItem
-	shippingCostLocalized *rest.Money
+	orderItems            []*shop.OrderItem
+	cartItems             []*shop.CartItem
+	shippingCostLocalized *shop.Money
 }
 
-func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(userID, userCurrency string, address *rest.Address) (orderPrep, error) {
+func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(ctx context.Context, userID, userCurrency string, address *shop.Address) (orderPrep, error) {
 	var out orderPrep
-	cartItems, err := cs.getUserCart(userID)
+	cartItems, err := cs.getUserCart(ctx, userID)
 	if err != nil {
-		return out, fmt.Errorf("cart failure: %+v", err)
+		return out, fmt.Errorf("cart failure: %w", err)
 	}
-	orderItems, err := cs.prepOrderItems(cartItems, userCurrency)
+	orderItems, err := cs.prepOrderItems(ctx, cartItems, userCurrency)
 	if err != nil {
-		return out, fmt.Errorf("failed to prepare order: %+v", err)
+		return out, fmt.Errorf("failed to prepare order: %w", err)
 	}
-	shippingUSD, err := cs.quoteShipping(address, cartItems)
+	shippingUSD, err := cs.quoteShipping(ctx, address, cartItems)
 	if err != nil {
-		return out, fmt.Errorf("shipping quote failure: %+v", err)
+		return out, fmt.Errorf("shipping quote failure: %w", err)
 	}
-	shippingPrice, err := cs.convertCurrency(shippingUSD, userCurrency)
+	shippingPrice, err := cs.convertCurrency(ctx, shippingUSD, userCurrency)
 	if err != nil {
-		return out, fmt.Errorf("failed to convert shipping cost to currency: %+v", err)
+		return out, fmt.Errorf("failed to convert shipping cost to currency: %w", err)
 	}
 
 	out.shippingCostLocalized = shippingPrice
@@ -198,89 +205,104 @@ func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(userID, use
 	return out, nil
 }
 
-func (cs *checkoutService) quoteShipping(address *rest.Address, items []*rest.CartItem) (*rest.Money, error) {
-	shippingQuote, err := rest.GetQuote(cs.shippingSvcAddr, &rest.GetQuoteRequest{
+func (cs *checkoutService) quoteShipping(ctx context.Context, address *shop.Address, items []*shop.CartItem) (*shop.Money, error) {
+	shippingQuote, err := cs.client.Shipping.GetQuote(ctx, &shop.GetQuoteRequest{
 		Address: address,
 		Items:   items,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get shipping quote: %+v", err)
+		return nil, fmt.Errorf("failed to get shipping quote: %w", err)
 	}
 	return shippingQuote.GetCostUsd(), nil
 }
 
-func (cs *checkoutService) getUserCart(userID string) ([]*rest.CartItem, error) {
-	cart, err := rest.GetCart(cs.cartSvcAddr, &rest.GetCartRequest{UserId: userID})
+func (cs *checkoutService) getUserCart(ctx context.Context, userID string) ([]*shop.CartItem, error) {
+	cart, err := cs.client.Cart.GetCart(ctx, &shop.GetCartRequest{UserId: userID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user cart during checkout: %+v", err)
+		return nil, fmt.Errorf("failed to get user cart during checkout: %w", err)
 	}
 	return cart.GetItems(), nil
 }
 
-func (cs *checkoutService) emptyUserCart(userID string) error {
-	if err := rest.EmptyCart(cs.cartSvcAddr, &rest.EmptyCartRequest{UserId: userID}); err != nil {
-		return fmt.Errorf("failed to empty user cart during checkout: %+v", err)
+func (cs *checkoutService) emptyUserCart(ctx context.Context, userID string) error {
+	if err := cs.client.Cart.EmptyCart(ctx, &shop.EmptyCartRequest{UserId: userID}); err != nil {
+		return fmt.Errorf("failed to empty user cart during checkout: %w", err)
 	}
 	return nil
 }
 
-func (cs *checkoutService) prepOrderItems(items []*rest.CartItem, userCurrency string) ([]*rest.OrderItem, error) {
-	out := make([]*rest.OrderItem, len(items))
+func (cs *checkoutService) prepOrderItems(ctx context.Context, items []*shop.CartItem, userCurrency string) ([]*shop.OrderItem, error) {
+	out := make([]*shop.OrderItem, len(items))
+	prices := make([]*shop.Money, len(items))
 
 	for i, item := range items {
-		product, err := rest.GetProduct(cs.productCatalogSvcAddr, &rest.GetProductRequest{Id: item.GetProductId()})
+		product, err := cs.client.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: item.GetProductId()})
 		if err != nil {
-			return nil, fmt.Errorf("failed to get product #%q", item.GetProductId())
+			return nil, fmt.Errorf("failed to get product #%q: %w", item.GetProductId(), err)
 		}
-		price, err := cs.convertCurrency(product.GetPriceUsd(), userCurrency)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert price of %q to %s", item.GetProductId(), userCurrency)
-		}
-		out[i] = &rest.OrderItem{
+		prices[i] = product.GetPriceUsd()
+	}
+	converted, err := cs.convertCurrencies(ctx, prices, userCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert prices of cart items to %s: %w", userCurrency, err)
+	}
+	for i, item := range items {
+		out[i] = &shop.OrderItem{
 			Item: item,
-			Cost: price,
+			Cost: converted[i],
 		}
 	}
 	return out, nil
 }
 
-func (cs *checkoutService) convertCurrency(from *rest.Money, toCurrency string) (*rest.Money, error) {
-	result, err := rest.Convert(cs.currencySvcAddr, &rest.CurrencyConversionRequest{
+func (cs *checkoutService) convertCurrency(ctx context.Context, from *shop.Money, toCurrency string) (*shop.Money, error) {
+	result, err := cs.client.Currency.Convert(ctx, &shop.CurrencyConversionRequest{
 		From:   from,
 		ToCode: toCurrency,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert currency: %+v", err)
+		return nil, fmt.Errorf("failed to convert currency: %w", err)
 	}
 	return result, err
 }
 
-func (cs *checkoutService) chargeCard(amount *rest.Money, paymentInfo *rest.CreditCardInfo) (string, error) {
-	paymentResp, err := rest.Charge(cs.paymentSvcAddr, &rest.ChargeRequest{
+func (cs *checkoutService) convertCurrencies(ctx context.Context, from []*shop.Money, toCurrency string) ([]*shop.Money, error) {
+	result, err := cs.client.Currency.ConvertBatch(ctx, &shop.CurrencyConversionBatchRequest{
+		From:   from,
+		ToCode: toCurrency,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert currency: %w", err)
+	}
+	return result.GetResults(), nil
+}
+
+func (cs *checkoutService) chargeCard(ctx context.Context, amount *shop.Money, paymentInfo *shop.CreditCardInfo) (string, error) {
+	paymentResp, err := cs.client.Payment.Charge(ctx, &shop.ChargeRequest{
 		Amount:     amount,
 		CreditCard: paymentInfo,
 	})
 	if err != nil {
-		return "", fmt.Errorf("could not charge the card: %+v", err)
+		return "", fmt.Errorf("could not charge the card: %w", err)
 	}
 	return paymentResp.GetTransactionId(), nil
 }
 
-func (cs *checkoutService) sendOrderConfirmation(email string, order *rest.OrderResult) error {
-	err := rest.SendOrderConfirmation(cs.emailSvcAddr, &rest.SendOrderConfirmationRequest{
+func (cs *checkoutService) sendOrderConfirmation(ctx context.Context, email string, order *shop.OrderResult) error {
+	err := cs.client.Email.SendOrderConfirmation(ctx, &shop.SendOrderConfirmationRequest{
 		Email: email,
 		Order: order,
 	})
 	return err
 }
 
-func (cs *checkoutService) shipOrder(address *rest.Address, items []*rest.CartItem) (string, error) {
-	resp, err := rest.ShipOrder(cs.shippingSvcAddr, &rest.ShipOrderRequest{
+func (cs *checkoutService) shipOrder(ctx context.Context, address *shop.Address, items []*shop.CartItem) (string, error) {
+	resp, err := cs.client.Shipping.ShipOrder(ctx, &shop.ShipOrderRequest{
 		Address: address,
 		Items:   items,
 	})
 	if err != nil {
-		return "", fmt.Errorf("shipment failed: %+v", err)
+		return "", fmt.Errorf("shipment failed: %w", err)
 	}
 	return resp.GetTrackingId(), nil
 }
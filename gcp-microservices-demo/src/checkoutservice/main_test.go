@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/checkoutservice/money"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/contract"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/openapi"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry/telemetrytest"
+)
+
+// contractDir holds the pact files recorded by the consumer tests.
+const contractDir = "../../contracts"
+
+var testAddress = &shop.Address{StreetAddress: "1600 Amphitheatre Parkway", City: "Mountain View", State: "CA", Country: "United States", ZipCode: 94043}
+
+// TestConformsToSpec only checks the accepted methods: replaying the
+// PlaceOrder example would call the downstream functions.
+func TestConformsToSpec(t *testing.T) {
+	spec, err := openapi.Load("../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range spec.CheckMethods("checkoutservice", http.HandlerFunc(Handler)) {
+		t.Error(err)
+	}
+}
+
+var (
+	testPrice = &shop.Money{CurrencyCode: "USD", Units: 19, Nanos: 990000000}
+	testQuote = &shop.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000}
+)
+
+func reply(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// fakeCatalogService and fakeShippingService serve the fake catalog and
+// shipping over gRPC and Connect.
+var (
+	fakeCatalogService = rpc.NewService(shop.CatalogService, rpc.Method{
+		Name: "GetProduct",
+		New:  func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return &shop.Product{Id: in.(*shop.GetProductRequest).Id, Name: "Sunglasses", PriceUsd: testPrice}, nil
+		},
+	})
+	fakeShippingService = rpc.NewService(shop.ShippingService,
+		rpc.Method{
+			Name: "GetQuote",
+			New:  func() interface{} { return new(shop.GetQuoteRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return &shop.GetQuoteResponse{CostUsd: testQuote}, nil
+			},
+		},
+		rpc.Method{
+			Name: "ShipOrder",
+			New:  func() interface{} { return new(shop.ShipOrderRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return &shop.ShipOrderResponse{TrackingId: "RS-12345-678901234"}, nil
+			},
+		},
+	)
+)
+
+// fakeCatalog and fakeShipping serve the fakes over HTTP/JSON and Connect.
+var (
+	fakeCatalog = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fakeCatalogService.ServeConnect(w, r) {
+			return
+		}
+		reply(w, &shop.Product{Id: r.URL.Query().Get("id"), Name: "Sunglasses", PriceUsd: testPrice})
+	})
+	fakeShipping = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fakeShippingService.ServeConnect(w, r) {
+			return
+		}
+		if r.Method == http.MethodPut {
+			reply(w, &shop.ShipOrderResponse{TrackingId: "RS-12345-678901234"})
+			return
+		}
+		reply(w, &shop.GetQuoteResponse{CostUsd: testQuote})
+	})
+)
+
+// fakeRouter stands in for the Fission router. The cart of every user holds
+// two OLJCESPC7Z and currencies are converted one to one.
+func fakeRouter(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cart", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			reply(w, &shop.Cart{UserId: r.URL.Query().Get("user_id"), Items: []*shop.CartItem{{ProductId: "OLJCESPC7Z", Quantity: 2}}})
+		}
+	})
+	mux.Handle("/product", fakeCatalog)
+	mux.HandleFunc("/currency", func(w http.ResponseWriter, r *http.Request) {
+		in := new(shop.CurrencyConversionRequest)
+		json.NewDecoder(r.Body).Decode(in)
+		reply(w, in.GetFrom())
+	})
+	mux.HandleFunc("/currency/batch", func(w http.ResponseWriter, r *http.Request) {
+		in := new(shop.CurrencyConversionBatchRequest)
+		json.NewDecoder(r.Body).Decode(in)
+		reply(w, &shop.CurrencyConversionBatchResponse{Results: in.GetFrom()})
+	})
+	mux.Handle("/shipping", fakeShipping)
+	mux.HandleFunc("/payment", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, &shop.ChargeResponse{TransactionId: "c7b2f4e0-0000-4000-8000-000000000000"})
+	})
+	mux.HandleFunc("/email", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// useRouter replaces the package-level service with one built from opts
+// for the rest of the test.
+func useRouter(t *testing.T, opts ...shop.Option) {
+	prev := svc
+	svc = newCheckoutService(shop.New(opts...), newMemoryOrderStore())
+	t.Cleanup(func() { svc = prev })
+}
+
+// TestContracts records the calls PlaceOrder makes to the Go functions it
+// depends on.
+func TestContracts(t *testing.T) {
+	downstream := fakeRouter(t)
+	catalog := contract.NewMock(t, "checkoutservice", "productcatalogservice")
+	catalog.Expect("", "the product of a cart item", http.StatusOK, &shop.Product{
+		Id:       "OLJCESPC7Z",
+		PriceUsd: &shop.Money{CurrencyCode: "USD", Units: 19, Nanos: 990000000},
+	})
+	shipping := contract.NewMock(t, "checkoutservice", "shippingservice")
+	shipping.Expect("", "a quote for the cart", http.StatusOK, &shop.GetQuoteResponse{
+		CostUsd: &shop.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000},
+	})
+	shipping.Expect("", "shipping the cart", http.StatusOK, &shop.ShipOrderResponse{TrackingId: "RS-12345-678901234"})
+
+	useRouter(t,
+		shop.WithBaseURL(downstream.URL),
+		shop.WithServiceURL(shop.CatalogService, catalog.URL()),
+		shop.WithServiceURL(shop.ShippingService, shipping.URL()),
+	)
+	_, err := svc.PlaceOrder(context.Background(), &shop.PlaceOrderRequest{
+		UserId:       "contract-user",
+		UserCurrency: "USD",
+		Address:      testAddress,
+		Email:        "someone@example.com",
+		CreditCard:   &shop.CreditCardInfo{CreditCardNumber: "4432-8015-6152-0454", CreditCardExpirationMonth: 1, CreditCardExpirationYear: 2030, CreditCardCvv: 672},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog.Write(contractDir)
+	shipping.Write(contractDir)
+}
+
+// TestHonorsContracts replays the pacts consumers recorded against Handler,
+// with the downstream functions faked.
+func TestHonorsContracts(t *testing.T) {
+	contract.Verify(t, contractDir, "checkoutservice", http.HandlerFunc(Handler), contract.States{
+		"the cart of the user holds OLJCESPC7Z": func(t *testing.T) {
+			useRouter(t, shop.WithBaseURL(fakeRouter(t).URL))
+		},
+		"the user placed an order": func(t *testing.T) {
+			useRouter(t, shop.WithBaseURL(fakeRouter(t).URL))
+			svc.orders.add(context.Background(), "contract-user", &shop.Order{
+				Order: &shop.OrderResult{
+					OrderId:            "e7a8e5b6-4c5d-11ee-be56-0242ac120002",
+					ShippingTrackingId: "RS-12345-678901234",
+					ShippingCost:       testQuote,
+					ShippingAddress:    testAddress,
+					Items:              []*shop.OrderItem{{Item: &shop.CartItem{ProductId: "OLJCESPC7Z", Quantity: 2}, Cost: testPrice}},
+				},
+				Total:    &shop.Money{CurrencyCode: "USD", Units: 48, Nanos: 970000000},
+				PlacedAt: "2023-09-06T10:00:00Z",
+			})
+		},
+	})
+}
+
+// TestTransports places an order over HTTP/JSON, gRPC and Connect, with
+// checkout reaching the catalog and shipping over the same transport.
+func TestTransports(t *testing.T) {
+	downstream := fakeRouter(t)
+	catalog := rpctest.Options(t, shop.CatalogService, fakeCatalog, fakeCatalogService)
+	shipping := rpctest.Options(t, shop.ShippingService, fakeShipping, fakeShippingService)
+	checkout := rpctest.Options(t, shop.CheckoutService, http.HandlerFunc(Handler), router.RPC())
+	for _, transport := range rpc.Transports {
+		transport := transport
+		t.Run(transport, func(t *testing.T) {
+			useRouter(t, shop.WithBaseURL(downstream.URL), catalog[transport], shipping[transport])
+			c := shop.New(checkout[transport])
+			res, err := c.Checkout.PlaceOrder(context.Background(), &shop.PlaceOrderRequest{
+				UserId:       "user-1",
+				UserCurrency: "USD",
+				Address:      testAddress,
+				Email:        "someone@example.com",
+				CreditCard:   &shop.CreditCardInfo{CreditCardNumber: "4432-8015-6152-0454", CreditCardExpirationMonth: 1, CreditCardExpirationYear: 2030, CreditCardCvv: 672},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := res.GetOrder()
+			if order.GetShippingTrackingId() != "RS-12345-678901234" || len(order.GetItems()) != 1 {
+				t.Errorf("PlaceOrder = %+v", order)
+			}
+			if cost := order.GetShippingCost(); cost.GetUnits() != 8 {
+				t.Errorf("shipping cost = %+v", cost)
+			}
+		})
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	t.Setenv("SHOP_TRANSPORT", rpc.GRPC)
+	if _, err := transportOptions(); err == nil {
+		t.Error("grpc without PRODUCT_CATALOG_SERVICE_GRPC_ADDR must fail")
+	}
+	t.Setenv("PRODUCT_CATALOG_SERVICE_GRPC_ADDR", "productcatalogservice:5000")
+	t.Setenv("SHIPPING_SERVICE_GRPC_ADDR", "shippingservice:5000")
+	if opts, err := transportOptions(); err != nil || len(opts) != 2 {
+		t.Errorf("transportOptions() = %d options, %v", len(opts), err)
+	}
+	t.Setenv("SHOP_TRANSPORT", "")
+	if opts, err := transportOptions(); err != nil || len(opts) != 0 {
+		t.Errorf("transportOptions() = %d options, %v; want plain HTTP", len(opts), err)
+	}
+}
+
+// TestDownstreamErrors checks that a failed payment is answered with the
+// status and error envelope matching the failure.
+func TestDownstreamErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		want      int
+		retryable bool
+	}{
+		{http.StatusBadRequest, `{"code":"bad_request","message":"card expired"}`, http.StatusUnprocessableEntity, false},
+		{http.StatusServiceUnavailable, `{"code":"unavailable","message":"try later","retryable":true}`, http.StatusServiceUnavailable, true},
+		{http.StatusInternalServerError, `{"code":"internal","message":"internal error"}`, http.StatusInternalServerError, false},
+	}
+	downstream := fakeRouter(t)
+	for _, tt := range tests {
+		payment := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		defer payment.Close()
+		useRouter(t, shop.WithBaseURL(downstream.URL), shop.WithServiceURL(shop.PaymentService, payment.URL), shop.WithRetries(0, 0))
+
+		body, _ := json.Marshal(&shop.PlaceOrderRequest{UserId: "user-1", UserCurrency: "USD", Address: testAddress, Email: "someone@example.com"})
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("X-Request-ID", "req-1")
+		Handler(rec, req)
+		env := new(shop.ErrorResponse)
+		if err := json.Unmarshal(rec.Body.Bytes(), env); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.want || env.GetRetryable() != tt.retryable || env.GetRequestId() != "req-1" {
+			t.Errorf("payment %d: got %d %s, want %d", tt.status, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
+
+// TestRequestIDPropagation checks that checkout logs the request ID the
+// frontend sent and passes it on, with the trace context, to the catalog.
+func TestRequestIDPropagation(t *testing.T) {
+	var buf bytes.Buffer
+	log.Out = &buf
+	defer func() { log.Out = os.Stdout }()
+
+	downstream, _ := url.Parse(fakeRouter(t).URL)
+	proxy := httputil.NewSingleHostReverseProxy(downstream)
+	var seen []http.Header
+	var mu sync.Mutex
+	recorder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/product" {
+			mu.Lock()
+			seen = append(seen, r.Header.Clone())
+			mu.Unlock()
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	defer recorder.Close()
+	useRouter(t, shop.WithBaseURL(recorder.URL))
+
+	body, _ := json.Marshal(&shop.PlaceOrderRequest{UserId: "user-1", UserCurrency: "USD", Address: testAddress, Email: "someone@example.com"})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(shop.RequestIDHeader, "req-1")
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	Handler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PlaceOrder = %d %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(buf.String(), `"http.req.id":"req-1"`) {
+		t.Errorf("request ID not logged:\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "someone@") {
+		t.Errorf("the email of the order was logged:\n%s", buf.String())
+	}
+	if len(seen) == 0 {
+		t.Fatal("the catalog was not called")
+	}
+	for _, h := range seen {
+		md := shop.MetadataFromHeader(h)
+		traceID := trace.SpanContextFromContext(telemetry.Extract(context.Background(), h)).TraceID().String()
+		if md.RequestID != "req-1" || traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("catalog got request ID %q, trace ID %q", md.RequestID, traceID)
+		}
+	}
+}
+
+// TestOrderMetrics checks that placed orders are counted with their
+// revenue. The fake currency service converts nothing, so the order is in
+// USD.
+func TestOrderMetrics(t *testing.T) {
+	rec := telemetrytest.New(t)
+	useRouter(t, shop.WithBaseURL(fakeRouter(t).URL))
+	resp, err := svc.PlaceOrder(context.Background(), &shop.PlaceOrderRequest{UserId: "user-1", UserCurrency: "USD", Address: testAddress, Email: "someone@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := *resp.GetOrder().GetShippingCost()
+	for _, it := range resp.GetOrder().GetItems() {
+		total = money.Must(money.Sum(total, money.MultiplySlow(*it.GetCost(), uint32(it.GetItem().GetQuantity()))))
+	}
+
+	usd := telemetry.CurrencyKey.String("USD")
+	orders := rec.Metric(t, telemetry.OrdersPlaced, usd)
+	if got := orders.Sum.AsInt64(); got != 1 {
+		t.Errorf("%s = %d, want 1", telemetry.OrdersPlaced, got)
+	}
+	revenue := rec.Metric(t, telemetry.OrderRevenue, usd)
+	want := float64(total.GetUnits()) + float64(total.GetNanos())/1e9
+	if got := revenue.Sum.AsFloat64(); got != want || got == 0 {
+		t.Errorf("%s = %v, want %v", telemetry.OrderRevenue, got, want)
+	}
+}
+
+// TestOrderHistory places orders for two users and pages through them over
+// HTTP, as the frontend does.
+func TestOrderHistory(t *testing.T) {
+	useRouter(t, shop.WithBaseURL(fakeRouter(t).URL))
+	placed := func(userID string) string {
+		resp, err := svc.PlaceOrder(context.Background(), &shop.PlaceOrderRequest{UserId: userID, UserCurrency: "USD", Address: testAddress, Email: "someone@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.GetOrder().GetOrderId()
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, placed("user-1"))
+	}
+	other := placed("user-2")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		Handler(rec, httptest.NewRequest(http.MethodGet, "/checkout?"+query, nil))
+		return rec
+	}
+	var pages [][]string
+	token := ""
+	for {
+		rec := get("user_id=user-1&page_size=2&page_token=" + token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("ListOrders = %d %s", rec.Code, rec.Body)
+		}
+		var resp shop.ListOrdersResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		var page []string
+		for _, o := range resp.GetOrders() {
+			page = append(page, o.GetOrder().GetOrderId())
+			if o.GetTotal().GetUnits() != 48 || o.GetPlacedAt() == "" {
+				t.Errorf("order %s: total %+v, placed at %q", o.GetOrder().GetOrderId(), o.GetTotal(), o.GetPlacedAt())
+			}
+		}
+		pages = append(pages, page)
+		if token = resp.GetNextPageToken(); token == "" {
+			break
+		}
+		if len(pages) == 1 {
+			// An order placed between two pages must not shift the next one.
+			ids = append(ids, placed("user-1"))
+		}
+	}
+	if want := [][]string{{ids[2], ids[1]}, {ids[0]}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+
+	for query, want := range map[string]int{"user_id=user-1": 4, "user_id=user-1&page_size=2": 2, "user_id=user-1&page_token=" + ids[1]: 1, "user_id=user-2&page_token=": 1} {
+		rec := get(query)
+		var resp shop.ListOrdersResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); rec.Code != http.StatusOK || err != nil || len(resp.GetOrders()) != want {
+			t.Errorf("ListOrders %s = %d %s, want %d orders", query, rec.Code, rec.Body, want)
+		}
+	}
+
+	if rec := get("user_id=user-1&order_id=" + ids[0]); rec.Code != http.StatusOK {
+		t.Errorf("GetOrder of its own order = %d %s", rec.Code, rec.Body)
+	}
+	for _, query := range []string{"user_id=user-1&order_id=" + other, "user_id=user-1&order_id=unknown"} {
+		if rec := get(query); rec.Code != http.StatusNotFound {
+			t.Errorf("GetOrder %s = %d, want 404", query, rec.Code)
+		}
+	}
+	for _, query := range []string{"user_id=user-1&page_size=51&page_token=", "user_id=user-1&page_size=2&page_token=x", "user_id=user-1&page_token=" + other, "user_id=&page_size=0&page_token="} {
+		if rec := get(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("ListOrders %s = %d, want 400", query, rec.Code)
+		}
+	}
+}
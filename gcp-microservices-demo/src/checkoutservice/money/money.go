@@ -3,7 +3,7 @@ package money
 import (
 	"errors"
 
-	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/checkoutservice/rest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
 )
 
 const (
@@ -18,47 +18,47 @@ var (
 )
 
 // IsValid checks if specified value has a valid units/nanos signs and ranges.
-func IsValid(m rest.Money) bool {
+func IsValid(m shop.Money) bool {
 	return signMatches(m) && validNanos(m.GetNanos())
 }
 
-func signMatches(m rest.Money) bool {
+func signMatches(m shop.Money) bool {
 	return m.GetNanos() == 0 || m.GetUnits() == 0 || (m.GetNanos() < 0) == (m.GetUnits() < 0)
 }
 
 func validNanos(nanos int32) bool { return nanosMin <= nanos && nanos <= nanosMax }
 
 // IsZero returns true if the specified money value is equal to zero.
-func IsZero(m rest.Money) bool { return m.GetUnits() == 0 && m.GetNanos() == 0 }
+func IsZero(m shop.Money) bool { return m.GetUnits() == 0 && m.GetNanos() == 0 }
 
 // IsPositive returns true if the specified money value is valid and is
 // positive.
-func IsPositive(m rest.Money) bool {
+func IsPositive(m shop.Money) bool {
 	return IsValid(m) && m.GetUnits() > 0 || (m.GetUnits() == 0 && m.GetNanos() > 0)
 }
 
 // IsNegative returns true if the specified money value is valid and is
 // negative.
-func IsNegative(m rest.Money) bool {
+func IsNegative(m shop.Money) bool {
 	return IsValid(m) && m.GetUnits() < 0 || (m.GetUnits() == 0 && m.GetNanos() < 0)
 }
 
 // AreSameCurrency returns true if values l and r have a currency code and
 // they are the same values.
-func AreSameCurrency(l, r rest.Money) bool {
+func AreSameCurrency(l, r shop.Money) bool {
 	return l.GetCurrencyCode() == r.GetCurrencyCode() && l.GetCurrencyCode() != ""
 }
 
 // AreEquals returns true if values l and r are the equal, including the
 // currency. This does not check validity of the provided values.
-func AreEquals(l, r rest.Money) bool {
+func AreEquals(l, r shop.Money) bool {
 	return l.GetCurrencyCode() == r.GetCurrencyCode() &&
 		l.GetUnits() == r.GetUnits() && l.GetNanos() == r.GetNanos()
 }
 
 // Negate returns the same amount with the sign negated.
-func Negate(m rest.Money) rest.Money {
-	return rest.Money{
+func Negate(m shop.Money) shop.Money {
+	return shop.Money{
 		Units:        -m.GetUnits(),
 		Nanos:        -m.GetNanos(),
 		CurrencyCode: m.GetCurrencyCode()}
@@ -66,7 +66,7 @@ func Negate(m rest.Money) rest.Money {
 
 // Must panics if the given error is not nil. This can be used with other
 // functions like: "m := Must(Sum(a,b))".
-func Must(v rest.Money, err error) rest.Money {
+func Must(v shop.Money, err error) shop.Money {
 	if err != nil {
 		panic(err)
 	}
@@ -76,11 +76,11 @@ func Must(v rest.Money, err error) rest.Money {
 // Sum adds two values. Returns an error if one of the values are invalid or
 // currency codes are not matching (unless currency code is unspecified for
 // both).
-func Sum(l, r rest.Money) (rest.Money, error) {
+func Sum(l, r shop.Money) (shop.Money, error) {
 	if !IsValid(l) || !IsValid(r) {
-		return rest.Money{}, ErrInvalidValue
+		return shop.Money{}, ErrInvalidValue
 	} else if l.GetCurrencyCode() != r.GetCurrencyCode() {
-		return rest.Money{}, ErrMismatchingCurrency
+		return shop.Money{}, ErrMismatchingCurrency
 	}
 	units := l.GetUnits() + r.GetUnits()
 	nanos := l.GetNanos() + r.GetNanos()
@@ -100,7 +100,7 @@ func Sum(l, r rest.Money) (rest.Money, error) {
 		}
 	}
 
-	return rest.Money{
+	return shop.Money{
 		Units:        units,
 		Nanos:        nanos,
 		CurrencyCode: l.GetCurrencyCode()}, nil
@@ -108,7 +108,7 @@ func Sum(l, r rest.Money) (rest.Money, error) {
 
 // MultiplySlow is a slow multiplication operation done through adding the value
 // to itself n-1 times.
-func MultiplySlow(m rest.Money, n uint32) rest.Money {
+func MultiplySlow(m shop.Money, n uint32) shop.Money {
 	out := m
 	for n > 1 {
 		out = Must(Sum(out, m))
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+)
+
+const (
+	// defaultPageSize and maxPageSize bound the pages ListOrders answers.
+	defaultPageSize = 10
+	maxPageSize     = 50
+	// maxOrdersPerUser is how many orders are kept for each user; older ones
+	// are dropped.
+	maxOrdersPerUser = 100
+)
+
+// orderStore keeps the orders placed by each user, for the order history.
+type orderStore interface {
+	// add records order for userID, dropping the oldest orders beyond
+	// maxOrdersPerUser.
+	add(ctx context.Context, userID string, order *shop.Order) error
+	// get returns the order orderID of userID, or nil if userID placed no
+	// such order.
+	get(ctx context.Context, userID, orderID string) (*shop.Order, error)
+	// list returns the orders of userID, newest first. There are at most
+	// maxOrdersPerUser of them, so ListOrders pages through them itself.
+	list(ctx context.Context, userID string) ([]*shop.Order, error)
+}
+
+// defaultOrderRedisAddr is the Redis of the carts, which keeps the orders
+// unless ORDER_REDIS_ADDR names another one.
+const defaultOrderRedisAddr = "redis-cart.gcpdemo.svc.cluster.local:6379"
+
+// orderStoreFromEnv returns the order store of the Redis at
+// ORDER_REDIS_ADDR.
+func orderStoreFromEnv() orderStore {
+	addr := os.Getenv("ORDER_REDIS_ADDR")
+	if addr == "" {
+		addr = defaultOrderRedisAddr
+	}
+	return newRedisOrderStore(redis.NewClient(&redis.Options{Addr: addr}))
+}
+
+// redisOrderPrefix prefixes the keys of the order lists in Redis, which is
+// shared with the carts.
+const redisOrderPrefix = "orders:"
+
+// redisOrderStore keeps the orders of each user in a Redis list, newest
+// first, as JSON, so that every instance of the function shares them and
+// they outlive the pods.
+type redisOrderStore struct {
+	client *redis.Client
+}
+
+func newRedisOrderStore(client *redis.Client) *redisOrderStore {
+	return &redisOrderStore{client: client}
+}
+
+func (s *redisOrderStore) add(ctx context.Context, userID string, order *shop.Order) error {
+	b, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	key := redisOrderPrefix + userID
+	_, err = s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.LPush(ctx, key, b)
+		p.LTrim(ctx, key, 0, maxOrdersPerUser-1)
+		return nil
+	})
+	return err
+}
+
+func (s *redisOrderStore) get(ctx context.Context, userID, orderID string) (*shop.Order, error) {
+	orders, err := s.list(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		if o.GetOrder().GetOrderId() == orderID {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *redisOrderStore) list(ctx context.Context, userID string) ([]*shop.Order, error) {
+	return s.decode(s.client.LRange(ctx, redisOrderPrefix+userID, 0, -1).Result())
+}
+
+func (s *redisOrderStore) decode(values []string, err error) ([]*shop.Order, error) {
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]*shop.Order, len(values))
+	for i, v := range values {
+		orders[i] = new(shop.Order)
+		if err := json.Unmarshal([]byte(v), orders[i]); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// record keeps the order placed by userID, with its total, for the order
+// history. The order is placed whether or not it is recorded.
+func (cs *checkoutService) record(ctx context.Context, userID string, order *shop.OrderResult, total shop.Money) {
+	err := cs.orders.add(ctx, userID, &shop.Order{
+		Order:    order,
+		Total:    &total,
+		PlacedAt: cs.now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		fission.Logger(ctx).WithError(err).Warn("failed to record the order in the order history")
+	}
+}
+
+// ListOrders answers a page of the orders of a user, newest first. The page
+// token is the ID of the last order of the previous page, so that the orders
+// placed between two pages do not shift the later ones.
+func (cs *checkoutService) ListOrders(ctx context.Context, req *shop.ListOrdersRequest) (*shop.ListOrdersResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, fission.BadRequest("user_id is required")
+	}
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0 || size > maxPageSize:
+		return nil, fission.BadRequest("page_size must be between 0 and %d", maxPageSize)
+	case size == 0:
+		size = defaultPageSize
+	}
+	orders, err := cs.orders.list(ctx, req.GetUserId())
+	if err != nil {
+		return nil, fission.Unavailable("could not read the orders: %v", err)
+	}
+	start := 0
+	if token := req.GetPageToken(); token != "" {
+		start = -1
+		for i, o := range orders {
+			if o.GetOrder().GetOrderId() == token {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, fission.BadRequest("invalid page_token %q", token)
+		}
+	}
+	end := start + size
+	if end > len(orders) {
+		end = len(orders)
+	}
+	resp := &shop.ListOrdersResponse{Orders: orders[start:end]}
+	if end < len(orders) {
+		resp.NextPageToken = orders[end-1].GetOrder().GetOrderId()
+	}
+	return resp, nil
+}
+
+// GetOrder answers an order of a user. The orders of other users are not
+// found, so that an order ID does not reveal whether it exists.
+func (cs *checkoutService) GetOrder(ctx context.Context, req *shop.GetOrderRequest) (*shop.Order, error) {
+	if req.GetUserId() == "" || req.GetOrderId() == "" {
+		return nil, fission.BadRequest("user_id and order_id are required")
+	}
+	order, err := cs.orders.get(ctx, req.GetUserId(), req.GetOrderId())
+	if err != nil {
+		return nil, fission.Unavailable("could not read the orders: %v", err)
+	}
+	if order == nil {
+		return nil, fission.NotFound("no order %s", req.GetOrderId())
+	}
+	return order, nil
+}
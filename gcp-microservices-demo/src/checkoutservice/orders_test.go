@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// memoryOrderStore keeps the orders of the tests in memory, by user, oldest
+// first.
+type memoryOrderStore struct {
+	mu     sync.Mutex
+	byUser map[string][]*shop.Order
+}
+
+func newMemoryOrderStore() *memoryOrderStore {
+	return &memoryOrderStore{byUser: make(map[string][]*shop.Order)}
+}
+
+func (s *memoryOrderStore) add(ctx context.Context, userID string, order *shop.Order) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := append(s.byUser[userID], order)
+	if len(orders) > maxOrdersPerUser {
+		orders = orders[len(orders)-maxOrdersPerUser:]
+	}
+	s.byUser[userID] = orders
+	return nil
+}
+
+func (s *memoryOrderStore) get(ctx context.Context, userID, orderID string) (*shop.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.byUser[userID] {
+		if o.GetOrder().GetOrderId() == orderID {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryOrderStore) list(ctx context.Context, userID string) ([]*shop.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := s.byUser[userID]
+	out := make([]*shop.Order, 0, len(orders))
+	for i := len(orders) - 1; i >= 0; i-- {
+		out = append(out, orders[i])
+	}
+	return out, nil
+}
+
+func TestOrderStores(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	ctx := context.Background()
+	for name, store := range map[string]orderStore{"memory": newMemoryOrderStore(), "redis": newRedisOrderStore(client)} {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < maxOrdersPerUser+2; i++ {
+				order := &shop.Order{Order: &shop.OrderResult{OrderId: fmt.Sprint("order-", i)}, Total: testPrice}
+				if err := store.add(ctx, "user-1", order); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ids := func(orders []*shop.Order) []string {
+				var out []string
+				for _, o := range orders {
+					out = append(out, o.GetOrder().GetOrderId())
+				}
+				return out
+			}
+
+			orders, err := store.list(ctx, "user-1")
+			if err != nil || len(orders) != maxOrdersPerUser {
+				t.Fatalf("list = %d orders, %v, want only the newest %d kept", len(orders), err, maxOrdersPerUser)
+			}
+			if got := ids(orders[:2]); !reflect.DeepEqual(got, []string{"order-101", "order-100"}) || orders[len(orders)-1].GetOrder().GetOrderId() != "order-2" {
+				t.Errorf("list = %v ... %s, want the newest first", got, orders[len(orders)-1].GetOrder().GetOrderId())
+			}
+			if orders[0].GetTotal().GetUnits() != testPrice.GetUnits() {
+				t.Errorf("the total was not kept: %+v", orders[0].GetTotal())
+			}
+			if orders, err := store.list(ctx, "user-2"); err != nil || len(orders) != 0 {
+				t.Errorf("orders of a user without any = %v, %v", ids(orders), err)
+			}
+
+			if o, err := store.get(ctx, "user-1", "order-50"); err != nil || o.GetOrder().GetOrderId() != "order-50" {
+				t.Errorf("get = %+v, %v", o, err)
+			}
+			for _, c := range []struct{ user, order string }{{"user-1", "order-0"}, {"user-2", "order-50"}} {
+				if o, err := store.get(ctx, c.user, c.order); err != nil || o != nil {
+					t.Errorf("get(%s, %s) = %+v, %v, want none", c.user, c.order, o, err)
+				}
+			}
+		})
+	}
+
+	mr.Close()
+	if _, err := newRedisOrderStore(client).list(ctx, "user-1"); err == nil {
+		t.Error("listing without Redis succeeded")
+	}
+}
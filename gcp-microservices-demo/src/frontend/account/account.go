@@ -0,0 +1,123 @@
+// Package account keeps the accounts shoppers may open in the frontend: an
+// email and a password, of which only a bcrypt hash is kept.
+package account
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength and MaxPasswordLength bound the length of a
+	// password in bytes; bcrypt ignores what follows the 72nd byte.
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var (
+	// ErrNotFound is answered by a Store for an email without an account.
+	ErrNotFound = errors.New("no account for this email")
+	// ErrExists is answered by a Store for an email that already has an
+	// account.
+	ErrExists = errors.New("an account already exists for this email")
+	// ErrInvalidCredentials is answered by Authenticate for an unknown
+	// email and for a wrong password alike.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrPasswordLength     = errors.New("passwords must be 8 to 72 bytes long")
+)
+
+// cost is the bcrypt cost of the password hashes, lowered by tests.
+var cost = bcrypt.DefaultCost
+
+// User is an account.
+type User struct {
+	// ID is the ID the cart and the orders of the account are kept under.
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	Created      time.Time `json:"created"`
+}
+
+// Store keeps accounts by email, as NormalizeEmail spells it.
+type Store interface {
+	// Create adds u, or answers ErrExists.
+	Create(ctx context.Context, u *User) error
+	// ByEmail answers the account of email, or ErrNotFound.
+	ByEmail(ctx context.Context, email string) (*User, error)
+}
+
+// NormalizeEmail returns email as accounts are kept by, lowercase and
+// without surrounding spaces, or ErrInvalidEmail if it is not a plain
+// address.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	a, err := mail.ParseAddress(email)
+	if err != nil || a.Address != email || a.Name != "" {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// Register opens an account for email with password.
+func Register(ctx context.Context, store Store, email, password string) (*User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return nil, ErrPasswordLength
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return nil, err
+	}
+	u := &User{ID: uuid.New().String(), Email: email, PasswordHash: string(hash), Created: time.Now().UTC()}
+	if err := store.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Authenticate returns the account of email if password is its password,
+// and ErrInvalidCredentials otherwise. An unknown email takes as long to
+// reject as a wrong password, so that the time does not tell which
+// emails have an account.
+func Authenticate(ctx context.Context, store Store, email, password string) (*User, error) {
+	email, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	u, err := store.ByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		bcrypt.CompareHashAndPassword(unknownHash(), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+var (
+	unknownOnce sync.Once
+	unknown     []byte
+)
+
+// unknownHash is the hash unknown emails are checked against.
+func unknownHash() []byte {
+	unknownOnce.Do(func() {
+		unknown, _ = bcrypt.GenerateFromPassword([]byte("no account has this password"), cost)
+	})
+	return unknown
+}
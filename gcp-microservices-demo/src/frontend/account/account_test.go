@@ -0,0 +1,109 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	cost = bcrypt.MinCost
+}
+
+// stores returns a memory and a file store, with the path of the file.
+func stores(t *testing.T) (map[string]Store, string) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	fs, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "file": fs}, path
+}
+
+func TestRegisterAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	all, _ := stores(t)
+	for name, store := range all {
+		t.Run(name, func(t *testing.T) {
+			u, err := Register(ctx, store, " Ada@Example.com ", "correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Email != "ada@example.com" || u.ID == "" || strings.Contains(u.PasswordHash, "correct horse") {
+				t.Errorf("registered %+v", u)
+			}
+			if _, err := Register(ctx, store, "ada@example.com", "another password"); !errors.Is(err, ErrExists) {
+				t.Errorf("registering the email again: %v, want ErrExists", err)
+			}
+
+			got, err := Authenticate(ctx, store, "ADA@example.com", "correct horse")
+			if err != nil || got.ID != u.ID {
+				t.Errorf("Authenticate = %+v, %v, want %s", got, err, u.ID)
+			}
+			for _, c := range []struct{ email, password string }{
+				{"ada@example.com", "wrong horse"},
+				{"bob@example.com", "correct horse"},
+				{"not an email", "correct horse"},
+			} {
+				if _, err := Authenticate(ctx, store, c.email, c.password); !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Authenticate(%q, %q): %v, want ErrInvalidCredentials", c.email, c.password, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	store := NewMemoryStore()
+	for _, c := range []struct {
+		email, password string
+		want            error
+	}{
+		{"ada", "correct horse", ErrInvalidEmail},
+		{"Ada <ada@example.com>", "correct horse", ErrInvalidEmail},
+		{"ada@example.com", "short", ErrPasswordLength},
+		{"ada@example.com", strings.Repeat("x", MaxPasswordLength+1), ErrPasswordLength},
+	} {
+		if _, err := Register(context.Background(), store, c.email, c.password); !errors.Is(err, c.want) {
+			t.Errorf("Register(%q, %d bytes): %v, want %v", c.email, len(c.password), err, c.want)
+		}
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	ctx := context.Background()
+	all, path := stores(t)
+	u, err := Register(ctx, all["file"], "ada@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("the accounts file: %v, %v, want mode 0600", fi, err)
+	}
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Authenticate(ctx, reopened, "ada@example.com", "correct horse"); err != nil || got.ID != u.ID {
+		t.Errorf("after reopening: %+v, %v, want %s", got, err, u.ID)
+	}
+	if _, err := Register(ctx, reopened, "bob@example.com", "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("%d files next to the accounts file, want none", len(entries)-1)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path); err == nil {
+		t.Error("a corrupt accounts file was opened")
+	}
+}
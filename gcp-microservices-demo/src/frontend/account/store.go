@@ -0,0 +1,122 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryStore keeps accounts in the memory of the process: they are lost
+// when it exits.
+type MemoryStore struct {
+	mu      sync.Mutex
+	byEmail map[string]User
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{byEmail: make(map[string]User)}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byEmail[u.Email]; ok {
+		return ErrExists
+	}
+	s.byEmail[u.Email] = *u
+	return nil
+}
+
+func (s *MemoryStore) ByEmail(ctx context.Context, email string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.byEmail[email]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+// FileStore keeps accounts in a JSON file, which it reads when opened and
+// rewrites whole on every new account. It suits a single replica.
+type FileStore struct {
+	path string
+	mem  *MemoryStore
+	// mu serializes the writes of the file.
+	mu sync.Mutex
+}
+
+// OpenFileStore opens the accounts kept in the file at path, which is
+// created with the first account if it does not exist.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, mem: NewMemoryStore()}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	if err := json.Unmarshal(b, &users); err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		s.mem.byEmail[u.Email] = u
+	}
+	return s, nil
+}
+
+func (s *FileStore) Create(ctx context.Context, u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.mem.Create(ctx, u); err != nil {
+		return err
+	}
+	if err := s.write(); err != nil {
+		s.mem.mu.Lock()
+		delete(s.mem.byEmail, u.Email)
+		s.mem.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) ByEmail(ctx context.Context, email string) (*User, error) {
+	return s.mem.ByEmail(ctx, email)
+}
+
+// write replaces the file with the accounts in memory. The accounts are
+// written to a temporary file first, then renamed over the file, so that
+// a crash leaves either the old or the new accounts.
+func (s *FileStore) write() error {
+	s.mem.mu.Lock()
+	users := make([]User, 0, len(s.mem.byEmail))
+	for _, u := range s.mem.byEmail {
+		users = append(users, u)
+	}
+	s.mem.mu.Unlock()
+	b, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
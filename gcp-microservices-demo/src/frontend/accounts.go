@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/account"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/session"
+)
+
+// accountEmailKey is the session value that keeps the email of the account
+// the session is signed in to, if any.
+const accountEmailKey = "account_email"
+
+// accountsFromEnv opens the accounts as ACCOUNT_STORE ("memory", the
+// default, or "file" at ACCOUNT_FILE) says.
+func accountsFromEnv() (account.Store, error) {
+	switch kind := os.Getenv("ACCOUNT_STORE"); kind {
+	case "", "memory":
+		return account.NewMemoryStore(), nil
+	case "file":
+		path := os.Getenv("ACCOUNT_FILE")
+		if path == "" {
+			return nil, errors.New("ACCOUNT_STORE is file but ACCOUNT_FILE is not set")
+		}
+		store, err := account.OpenFileStore(path)
+		return store, errors.Wrapf(err, "could not open the accounts at %s", path)
+	default:
+		return nil, errors.Errorf("unknown ACCOUNT_STORE %q, want memory or file", kind)
+	}
+}
+
+// accountEmail returns the email of the account s is signed in to, or ""
+// for an anonymous session.
+func accountEmail(s *session.Session) string {
+	if s == nil {
+		return ""
+	}
+	return s.Values[accountEmailKey]
+}
+
+func (fe *frontendServer) loginHandler(w http.ResponseWriter, r *http.Request) {
+	fe.renderAccountForm(w, r, "login", http.StatusOK, "")
+}
+
+// loginPostHandler signs the session in to the account of the email and
+// password posted.
+func (fe *frontendServer) loginPostHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	u, err := account.Authenticate(r.Context(), fe.accounts, r.FormValue("email"), r.FormValue("password"))
+	if errors.Is(err, account.ErrInvalidCredentials) {
+		log.Info("rejected a login with invalid credentials")
+		fe.renderAccountForm(w, r, "login", http.StatusUnauthorized, "The email or the password is not right.")
+		return
+	}
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not look up account"), http.StatusInternalServerError)
+		return
+	}
+	fe.logIn(w, r, u)
+}
+
+func (fe *frontendServer) registerHandler(w http.ResponseWriter, r *http.Request) {
+	fe.renderAccountForm(w, r, "register", http.StatusOK, "")
+}
+
+// registerPostHandler opens an account for the email and password posted,
+// and signs the session in to it.
+func (fe *frontendServer) registerPostHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	if r.FormValue("password") != r.FormValue("confirm_password") {
+		fe.renderAccountForm(w, r, "register", http.StatusUnprocessableEntity, "The passwords do not match.")
+		return
+	}
+	u, err := account.Register(r.Context(), fe.accounts, r.FormValue("email"), r.FormValue("password"))
+	switch {
+	case errors.Is(err, account.ErrInvalidEmail):
+		fe.renderAccountForm(w, r, "register", http.StatusUnprocessableEntity, "Enter a valid email address.")
+		return
+	case errors.Is(err, account.ErrPasswordLength):
+		fe.renderAccountForm(w, r, "register", http.StatusUnprocessableEntity, "Passwords are 8 to 72 characters long.")
+		return
+	case errors.Is(err, account.ErrExists):
+		fe.renderAccountForm(w, r, "register", http.StatusConflict, "An account already exists for this email: sign in instead.")
+		return
+	case err != nil:
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not open account"), http.StatusInternalServerError)
+		return
+	}
+	log.WithField("account", u.ID).Info("opened an account")
+	fe.logIn(w, r, u)
+}
+
+// logIn signs the session of r in to the account u. The items the shopper
+// put in the cart before signing in are moved to the cart of the account,
+// which, like the orders, is kept under the ID of the account from now on.
+// The orders placed before are not moved: checkoutservice keeps them under
+// the ID of the anonymous session, so the account does not list them.
+// The session gets a new ID and CSRF token, so that those known before the
+// login are of no use after it.
+func (fe *frontendServer) logIn(w http.ResponseWriter, r *http.Request, u *account.User) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	s := currentSession(r)
+	if accountEmail(s) == "" && s.UserID != u.ID {
+		if err := fe.mergeCart(r, s.UserID, u.ID); err != nil {
+			renderHTTPError(log, r, w, errors.Wrap(err, "could not move cart to account"), http.StatusInternalServerError)
+			return
+		}
+	}
+	s.UserID = u.ID
+	if s.Values == nil {
+		s.Values = make(map[string]string)
+	}
+	s.Values[accountEmailKey] = u.Email
+	delete(s.Values, csrfSessionKey)
+	if err := fe.sessions.Renew(w, r, s); err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not save session"), http.StatusServiceUnavailable)
+		return
+	}
+	log.WithField("account", u.ID).Info("signed in")
+	w.Header().Set("Location", sameOrigin(r, r.FormValue("next")))
+	w.WriteHeader(http.StatusFound)
+}
+
+// mergeCart adds the items of the cart of from to the cart of to, and
+// empties the cart of from.
+func (fe *frontendServer) mergeCart(r *http.Request, from, to string) error {
+	items, err := fe.getCart(r.Context(), from)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	for _, it := range items {
+		if err := fe.insertCart(r.Context(), to, it.GetProductId(), it.GetQuantity()); err != nil {
+			return err
+		}
+	}
+	return fe.emptyCart(r.Context(), from)
+}
+
+// renderAccountForm answers the login or the register page with code, and
+// the problem with the form posted, if any. The email posted is kept, the
+// password is not.
+func (fe *frontendServer) renderAccountForm(w http.ResponseWriter, r *http.Request, name string, code int, problem string) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	cart, err := l.cart(sessionID(r))(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(code)
+	if err := executeTemplate(r.Context(), w, name, map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":     currentCurrency(r),
+		"show_currency":     false,
+		"cart_size":         cartSize(cart),
+		"email":             r.FormValue("email"),
+		"next":              sameOrigin(r, r.FormValue("next")),
+		"problem":           problem,
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
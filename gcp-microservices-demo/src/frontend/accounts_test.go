@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/account"
+)
+
+// browser keeps the session cookie of a shopper across the requests it
+// sends to a shop.
+type browser struct {
+	t      *testing.T
+	shop   http.Handler
+	cookie *http.Cookie
+}
+
+func (b *browser) do(req *http.Request) *httptest.ResponseRecorder {
+	if b.cookie != nil {
+		req.AddCookie(b.cookie)
+	}
+	rec := httptest.NewRecorder()
+	b.shop.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == cookieSessionID {
+			b.cookie = c
+		}
+	}
+	return rec
+}
+
+func (b *browser) get(path string) *httptest.ResponseRecorder {
+	return b.do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+// post posts form to path with the CSRF token of the page at path.
+func (b *browser) post(path string, form url.Values) *httptest.ResponseRecorder {
+	b.t.Helper()
+	return b.postFrom(path, path, form)
+}
+
+// postFrom posts form to path with the CSRF token of the page at page, as
+// the forms of the header do.
+func (b *browser) postFrom(page, path string, form url.Values) *httptest.ResponseRecorder {
+	b.t.Helper()
+	m := csrfInput.FindStringSubmatch(b.get(page).Body.String())
+	if m == nil {
+		b.t.Fatalf("%s has no CSRF token", page)
+	}
+	form.Set(csrfField, m[1])
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return b.do(req)
+}
+
+// userID returns the ID the cart and the orders of the session are kept
+// under.
+func (b *browser) userID() string {
+	return b.get("/whoami").Body.String()
+}
+
+// accountShop serves the account pages over the carts of fc, through the
+// middleware of the frontend.
+func accountShop(t *testing.T, fc *fakeCart) http.Handler {
+	fe := standIns(t, map[string]http.HandlerFunc{"/cart": fc.ServeHTTP, "/cart/item": fc.ServeHTTP})
+	fe.sessions = testSessions()
+	fe.accounts = account.NewMemoryStore()
+	r := mux.NewRouter()
+	r.HandleFunc("/login", fe.loginHandler).Methods(http.MethodGet)
+	r.HandleFunc("/login", fe.loginPostHandler).Methods(http.MethodPost)
+	r.HandleFunc("/register", fe.registerHandler).Methods(http.MethodGet)
+	r.HandleFunc("/register", fe.registerPostHandler).Methods(http.MethodPost)
+	r.HandleFunc("/logout", fe.logoutHandler).Methods(http.MethodPost)
+	r.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(sessionID(r))) })
+	return fe.ensureSession(&logHandler{log: jsonLogger(new(bytes.Buffer)), next: fe.checkCSRF(r)})
+}
+
+func TestAccounts(t *testing.T) {
+	fc := newFakeCart()
+	b := &browser{t: t, shop: accountShop(t, fc)}
+	put := func(userID, productID string, quantity int32) {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+		fc.set(userID, productID, quantity, true)
+	}
+
+	anonymous := b.userID()
+	anonymousCookie := b.cookie
+	for _, path := range []string{"/login", "/register"} {
+		if !strings.Contains(b.get(path).Body.String(), "Orders you placed before signing in are not moved") {
+			t.Errorf("%s does not tell that the orders are not moved to the account", path)
+		}
+	}
+	put(anonymous, "OLJCESPC7Z", 2)
+	rec := b.post("/register", url.Values{
+		"email": {"Ada@example.com"}, "password": {"correct horse"}, "confirm_password": {"correct horse"}, "next": {"/cart"},
+	})
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/cart" {
+		t.Fatalf("registering: status %d to %q:\n%s", rec.Code, rec.Header().Get("Location"), rec.Body)
+	}
+	if b.cookie.Value == anonymousCookie.Value {
+		t.Error("the session kept its cookie across the login")
+	}
+	user := b.userID()
+	if user == anonymous {
+		t.Fatal("the account shares the ID of the anonymous session")
+	}
+	if got := fc.items(user); !reflect.DeepEqual(got, map[string]int32{"OLJCESPC7Z": 2}) {
+		t.Errorf("the cart of the account is %v, want the anonymous cart", got)
+	}
+	if got := fc.items(anonymous); len(got) != 0 {
+		t.Errorf("the anonymous cart still has %v", got)
+	}
+	if page := b.get("/login").Body.String(); !strings.Contains(page, "ada@example.com") || !strings.Contains(page, `action="/logout"`) {
+		t.Errorf("the header does not show the account:\n%s", page)
+	}
+	stale := &browser{t: t, shop: b.shop, cookie: anonymousCookie}
+	if stale.userID() == user {
+		t.Error("the cookie from before the login reaches the account")
+	}
+
+	for _, req := range []*http.Request{httptest.NewRequest(http.MethodGet, "/logout", nil), httptest.NewRequest(http.MethodPost, "/logout", nil)} {
+		if b.do(req); b.userID() != user {
+			t.Fatalf("%s /logout without the CSRF token logged out", req.Method)
+		}
+	}
+	if rec := b.postFrom("/login", "/logout", url.Values{}); rec.Code != http.StatusFound {
+		t.Fatalf("logging out: status %d:\n%s", rec.Code, rec.Body)
+	}
+	later := b.userID()
+	if later == user || later == anonymous {
+		t.Fatalf("after logging out, the session is %s", later)
+	}
+	put(later, "OLJCESPC7Z", 1)
+	put(later, "66VCHSJNUP", 1)
+	rec = b.post("/login", url.Values{"email": {"ada@example.com"}, "password": {"wrong horse"}})
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "not right") {
+		t.Errorf("a wrong password: status %d:\n%s", rec.Code, rec.Body)
+	}
+	if b.userID() != later {
+		t.Error("a failed login changed the session")
+	}
+	rec = b.post("/login", url.Values{"email": {"ada@example.com"}, "password": {"correct horse"}, "next": {"https://evil.example/"}})
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/" {
+		t.Fatalf("logging in: status %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if got := b.userID(); got != user {
+		t.Fatalf("logged in as %s, want the account %s", got, user)
+	}
+	if got := fc.items(user); !reflect.DeepEqual(got, map[string]int32{"OLJCESPC7Z": 3, "66VCHSJNUP": 1}) {
+		t.Errorf("the merged cart is %v", got)
+	}
+
+	other := &browser{t: t, shop: b.shop}
+	for _, tt := range []struct {
+		form url.Values
+		want int
+	}{
+		{url.Values{"email": {"ada@example.com"}, "password": {"another one"}, "confirm_password": {"another one"}}, http.StatusConflict},
+		{url.Values{"email": {"bob@example.com"}, "password": {"battery staple"}, "confirm_password": {"battery stable"}}, http.StatusUnprocessableEntity},
+		{url.Values{"email": {"bob"}, "password": {"battery staple"}, "confirm_password": {"battery staple"}}, http.StatusUnprocessableEntity},
+		{url.Values{"email": {"bob@example.com"}, "password": {"short"}, "confirm_password": {"short"}}, http.StatusUnprocessableEntity},
+	} {
+		rec := other.post("/register", tt.form)
+		if rec.Code != tt.want {
+			t.Errorf("registering %v: status %d, want %d", tt.form, rec.Code, tt.want)
+		}
+		if strings.Contains(rec.Body.String(), `value="`+tt.form.Get("password")+`"`) {
+			t.Errorf("registering %v: the form was sent back with the password", tt.form)
+		}
+	}
+}
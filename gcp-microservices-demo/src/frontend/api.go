@@ -1,17 +1,19 @@
 package main
 
 import (
-	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/rest"
+	"context"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
 	"github.com/pkg/errors"
 )
 
-func (fe *frontendServer) getCurrencies() ([]string, error) {
-	currs, err := rest.GetSupportedCurrencies(fe.currencySvcAddr)
+func (fe *frontendServer) getCurrencies(ctx context.Context) ([]string, error) {
+	currs, err := fe.client.Currency.GetSupportedCurrencies(ctx)
 	if err != nil {
 		return nil, err
 	}
 	var out []string
-	for _, c := range currs.CurrencyCodes {
+	for _, c := range currs.GetCurrencyCodes() {
 		if _, ok := whitelistedCurrencies[c]; ok {
 			out = append(out, c)
 		}
@@ -19,59 +21,70 @@ func (fe *frontendServer) getCurrencies() ([]string, error) {
 	return out, nil
 }
 
-func (fe *frontendServer) getProducts() ([]*rest.Product, error) {
-	resp, err := rest.ListProducts(fe.productCatalogSvcAddr)
+func (fe *frontendServer) getProducts(ctx context.Context) ([]*shop.Product, error) {
+	resp, err := fe.client.Catalog.ListProducts(ctx)
 	return resp.GetProducts(), err
 }
 
-func (fe *frontendServer) getProduct(id string) (*rest.Product, error) {
-	resp, err := rest.GetProduct(fe.productCatalogSvcAddr, &rest.GetProductRequest{Id: id})
+func (fe *frontendServer) getProduct(ctx context.Context, id string) (*shop.Product, error) {
+	resp, err := fe.client.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: id})
 	return resp, err
 }
 
-func (fe *frontendServer) getCart(userID string) ([]*rest.CartItem, error) {
-	resp, err := rest.GetCart(fe.cartSvcAddr, &rest.GetCartRequest{UserId: userID})
+func (fe *frontendServer) getCart(ctx context.Context, userID string) ([]*shop.CartItem, error) {
+	resp, err := fe.client.Cart.GetCart(ctx, &shop.GetCartRequest{UserId: userID})
 	return resp.GetItems(), err
 }
 
-func (fe *frontendServer) emptyCart(userID string) error {
-	return rest.EmptyCart(fe.cartSvcAddr, &rest.EmptyCartRequest{UserId: userID})
+func (fe *frontendServer) emptyCart(ctx context.Context, userID string) error {
+	return fe.client.Cart.EmptyCart(ctx, &shop.EmptyCartRequest{UserId: userID})
+}
+
+func (fe *frontendServer) insertCart(ctx context.Context, userID, productID string, quantity int32) error {
+	return fe.client.Cart.AddItem(ctx, &shop.AddItemRequest{
+		UserId: userID,
+		Item: &shop.CartItem{
+			ProductId: productID,
+			Quantity:  quantity},
+	})
 }
 
-func (fe *frontendServer) insertCart(userID, productID string, quantity int32) error {
-	return rest.AddItem(fe.cartSvcAddr, &rest.AddItemRequest{
+func (fe *frontendServer) updateCart(ctx context.Context, userID, productID string, quantity int32) error {
+	_, err := fe.client.Cart.UpdateItem(ctx, &shop.UpdateItemRequest{
 		UserId: userID,
-		Item: &rest.CartItem{
+		Item: &shop.CartItem{
 			ProductId: productID,
 			Quantity:  quantity},
 	})
+	return err
 }
 
-func (fe *frontendServer) convertCurrency(money *rest.Money, currency string) (*rest.Money, error) {
-	return rest.Convert(fe.currencySvcAddr, &rest.CurrencyConversionRequest{
+func (fe *frontendServer) removeFromCart(ctx context.Context, userID, productID string) error {
+	_, err := fe.client.Cart.RemoveItem(ctx, &shop.RemoveItemRequest{UserId: userID, ProductId: productID})
+	return err
+}
+
+func (fe *frontendServer) convertCurrency(ctx context.Context, money *shop.Money, currency string) (*shop.Money, error) {
+	return fe.client.Currency.Convert(ctx, &shop.CurrencyConversionRequest{
 		From:   money,
 		ToCode: currency})
 }
 
-func (fe *frontendServer) getShippingQuote(items []*rest.CartItem, currency string) (*rest.Money, error) {
-	quote, err := rest.GetQuote(fe.shippingSvcAddr, &rest.GetQuoteRequest{
-		Address: nil,
-		Items:   items})
-	if err != nil {
-		return nil, err
-	}
-	localized, err := fe.convertCurrency(quote.GetCostUsd(), currency)
-	return localized, errors.Wrap(err, "failed to convert currency for shipping cost")
+func (fe *frontendServer) convertCurrencies(ctx context.Context, from []*shop.Money, currency string) ([]*shop.Money, error) {
+	resp, err := fe.client.Currency.ConvertBatch(ctx, &shop.CurrencyConversionBatchRequest{
+		From:   from,
+		ToCode: currency})
+	return resp.GetResults(), err
 }
 
-func (fe *frontendServer) getRecommendations(userID string, productIDs []string) ([]*rest.Product, error) {
-	resp, err := rest.ListRecommendations(fe.recommendationSvcAddr, &rest.ListRecommendationsRequest{UserId: userID, ProductIds: productIDs})
+func (fe *frontendServer) getRecommendations(ctx context.Context, userID string, productIDs []string) ([]*shop.Product, error) {
+	resp, err := fe.client.Recommendations.ListRecommendations(ctx, &shop.ListRecommendationsRequest{UserId: userID, ProductIds: productIDs})
 	if err != nil {
 		return nil, err
 	}
-	out := make([]*rest.Product, len(resp.GetProductIds()))
+	out := make([]*shop.Product, len(resp.GetProductIds()))
 	for i, v := range resp.GetProductIds() {
-		p, err := fe.getProduct(v)
+		p, err := fe.getProduct(ctx, v)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get recommended product info (#%s)", v)
 		}
@@ -83,8 +96,8 @@ func (fe *frontendServer) getRecommendations(userID string, productIDs []string)
 	return out, err
 }
 
-func (fe *frontendServer) getAd(ctxKeys []string) ([]*rest.Ad, error) {
-	resp, err := rest.GetAds(fe.adSvcAddr, &rest.AdRequest{
+func (fe *frontendServer) getAd(ctx context.Context, ctxKeys []string) ([]*shop.Ad, error) {
+	resp, err := fe.client.Ads.GetAds(ctx, &shop.AdRequest{
 		ContextKeys: ctxKeys,
 	})
 	return resp.GetAds(), errors.Wrap(err, "failed to get ads")
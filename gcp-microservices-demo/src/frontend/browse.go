@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// homePageSize is the number of products on each page of the home page.
+const homePageSize = 6
+
+// sortOptions are the orders offered on the home page, the catalog order
+// first.
+var sortOptions = []struct{ value, label string }{
+	{"", "Featured"},
+	{"price", "Price: low to high"},
+	{"-price", "Price: high to low"},
+	{"name", "Name"},
+}
+
+// browse returns a page of the catalog, filtered and sorted as req says.
+func (l *loader) browse(req shop.BrowseProductsRequest) func(ctx context.Context) (*shop.BrowseProductsResponse, error) {
+	key := fmt.Sprintf("browse/%s/%d/%d/%s/%d/%d", req.Category, req.MinPriceUsd, req.MaxPriceUsd, req.Sort, req.Page, req.PageSize)
+	p := l.start(l.ctx, key, func(ctx context.Context) (interface{}, error) {
+		return l.fe.client.Catalog.BrowseProducts(ctx, &req)
+	})
+	return func(ctx context.Context) (*shop.BrowseProductsResponse, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.(*shop.BrowseProductsResponse)
+		return out, err
+	}
+}
+
+// browseQuery is the part of the catalog shown on the home page, as its
+// query parameters say.
+type browseQuery struct {
+	category, sort     string
+	minPrice, maxPrice int64
+	page               int32
+}
+
+// parseBrowseQuery reads the query parameters of the home page. The sort
+// order and the price range are checked by the catalog.
+func parseBrowseQuery(r *http.Request) (browseQuery, error) {
+	q := browseQuery{category: r.FormValue("category"), sort: r.FormValue("sort"), page: 1}
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"min_price_usd", &q.minPrice}, {"max_price_usd", &q.maxPrice}} {
+		if v := r.FormValue(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return q, errors.Errorf("%s must be a whole number of dollars", p.name)
+			}
+			*p.dst = n
+		}
+	}
+	if v := r.FormValue("page"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 1 {
+			return q, errors.New("page must be a positive integer")
+		}
+		q.page = int32(n)
+	}
+	return q, nil
+}
+
+// request is the catalog request for the page of q.
+func (q browseQuery) request() shop.BrowseProductsRequest {
+	return shop.BrowseProductsRequest{
+		Category:    q.category,
+		MinPriceUsd: q.minPrice,
+		MaxPriceUsd: q.maxPrice,
+		Sort:        q.sort,
+		Page:        q.page,
+		PageSize:    homePageSize,
+	}
+}
+
+// url returns the home page URL of q. Parameters left at their defaults
+// are left out, so that the first page of the whole catalog is "/". The
+// currency is not part of it: it is kept in its cookie.
+func (q browseQuery) url() string {
+	v := url.Values{}
+	if q.category != "" {
+		v.Set("category", q.category)
+	}
+	if q.sort != "" {
+		v.Set("sort", q.sort)
+	}
+	if q.minPrice != 0 {
+		v.Set("min_price_usd", strconv.FormatInt(q.minPrice, 10))
+	}
+	if q.maxPrice != 0 {
+		v.Set("max_price_usd", strconv.FormatInt(q.maxPrice, 10))
+	}
+	if q.page > 1 {
+		v.Set("page", strconv.Itoa(int(q.page)))
+	}
+	if len(v) == 0 {
+		return "/"
+	}
+	return "/?" + v.Encode()
+}
+
+type browseLink struct {
+	Label  string
+	URL    string
+	Active bool
+}
+
+// categoryLinks links every category of the catalog, after the whole
+// catalog, keeping the sort order and the price range of q. Moving to
+// another category starts over from its first page.
+func (q browseQuery) categoryLinks(categories []string) []browseLink {
+	to := q
+	to.page, to.category = 1, ""
+	links := []browseLink{{Label: "All", URL: to.url(), Active: q.category == ""}}
+	for _, c := range categories {
+		to.category = c
+		links = append(links, browseLink{Label: c, URL: to.url(), Active: q.category == c})
+	}
+	return links
+}
+
+// sortLinks links every sort order of the catalog, keeping the category
+// and the price range of q, from the first page.
+func (q browseQuery) sortLinks() []browseLink {
+	links := make([]browseLink, len(sortOptions))
+	for i, o := range sortOptions {
+		to := q
+		to.page, to.sort = 1, o.value
+		links[i] = browseLink{Label: o.label, URL: to.url(), Active: q.sort == o.value}
+	}
+	return links
+}
+
+// pageLinks links every page of total products.
+func (q browseQuery) pageLinks(total int32) []browseLink {
+	pages := (total + homePageSize - 1) / homePageSize
+	if pages < 2 {
+		return nil
+	}
+	links := make([]browseLink, pages)
+	for i := range links {
+		to := q
+		to.page = int32(i + 1)
+		links[i] = browseLink{Label: strconv.Itoa(i + 1), URL: to.url(), Active: q.page == to.page}
+	}
+	return links
+}
+
+// adjacentPage returns the URL of the page delta pages away from that of
+// q, or "" if there is no such page of total products.
+func (q browseQuery) adjacentPage(total int32, delta int32) string {
+	to := q
+	to.page += delta
+	if to.page < 1 || (to.page-1)*homePageSize >= total {
+		return ""
+	}
+	return to.url()
+}
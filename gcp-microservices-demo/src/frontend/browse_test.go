@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// browseStandIns serves a catalog of 14 products in two categories, of
+// which every page holds the Mug, priced in euros. The queries of the
+// catalog calls are sent to queries.
+func browseStandIns(t *testing.T, queries chan<- url.Values) *frontendServer {
+	return standIns(t, map[string]http.HandlerFunc{
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			select {
+			case queries <- q:
+			default:
+			}
+			switch {
+			case q.Get("sort") == "rating":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"code":"invalid_argument","message":"unknown sort \"rating\""}`))
+			case q.Get("category") == "garden":
+				w.Write([]byte(`{"categories":["accessories","kitchen"]}`))
+			default:
+				w.Write([]byte(`{"products":[{"id":"9SIQT8TOJO","name":"Mug","price_usd":{"currency_code":"USD","units":8,"nanos":990000000}}],` +
+					`"total_size":14,"categories":["accessories","kitchen"]}`))
+			}
+		},
+		"/currency/batch": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results":[{"currency_code":"EUR","units":8,"nanos":250000000}]}`))
+		},
+	})
+}
+
+func TestHomeBrowsing(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	fe := browseStandIns(t, queries)
+	req := httptest.NewRequest(http.MethodGet, "/?category=kitchen&sort=price&page=2", nil)
+	req.AddCookie(&http.Cookie{Name: cookieCurrency, Value: "EUR"})
+	rec := servePage(fe.homeHandler, req, "/")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d:\n%s", rec.Code, rec.Body)
+	}
+	want := url.Values{"category": {"kitchen"}, "sort": {"price"}, "page": {"2"}, "page_size": {"6"}}
+	if got := <-queries; got.Encode() != want.Encode() {
+		t.Errorf("the catalog was asked for %s, want %s", got.Encode(), want.Encode())
+	}
+	page := rec.Body.String()
+	for _, s := range []string{
+		"€8.25",
+		`<a href="/?sort=price">All</a>`,
+		`<a href="/?category=kitchen&amp;sort=price" class="active" aria-current="page">kitchen</a>`,
+		`<a href="/?category=accessories&amp;sort=price">accessories</a>`,
+		`<a href="/?category=kitchen">Featured</a>`,
+		`<a href="/?category=kitchen&amp;sort=-price">Price: high to low</a>`,
+		`<a href="/?category=kitchen&amp;sort=price" rel="prev">Previous</a>`,
+		`<a href="/?category=kitchen&amp;page=3&amp;sort=price" rel="next">Next</a>`,
+		`<a href="/?category=kitchen&amp;page=2&amp;sort=price" class="active" aria-current="page">2</a>`,
+		`<input type="hidden" name="category" value="kitchen">`,
+	} {
+		if !strings.Contains(page, s) {
+			t.Errorf("the page lacks %s", s)
+		}
+	}
+	if strings.Contains(page, "page=4") {
+		t.Error("the page links past the last page")
+	}
+}
+
+func TestHomeBrowsingErrors(t *testing.T) {
+	fe := browseStandIns(t, nil)
+	tests := []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/?category=garden", http.StatusOK, "No products match these filters."},
+		{"/?page=0", http.StatusBadRequest, "page must be a positive integer"},
+		{"/?min_price_usd=cheap", http.StatusBadRequest, "min_price_usd must be a whole number of dollars"},
+		{"/?sort=rating", http.StatusUnprocessableEntity, ""},
+	}
+	for _, tt := range tests {
+		rec := servePage(fe.homeHandler, httptest.NewRequest(http.MethodGet, tt.path, nil), "/")
+		if rec.Code != tt.code {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, tt.code)
+		}
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: the page lacks %q", tt.path, tt.want)
+		}
+	}
+}
+
+// TestCurrencyKeepsBrowsing checks that choosing a currency returns to the
+// same page of the catalog.
+func TestCurrencyKeepsBrowsing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/setCurrency", strings.NewReader("currency_code=JPY"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", "/?category=kitchen&page=2&sort=price")
+	rec := servePage((&frontendServer{sessions: testSessions()}).setCurrencyHandler, req, "/setCurrency")
+	if got := rec.Header().Get("Location"); got != "/?category=kitchen&page=2&sort=price" {
+		t.Errorf("redirected to %q", got)
+	}
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].Value != "JPY" {
+		t.Errorf("cookies %v, want the currency JPY", c)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func cartStandIns(t *testing.T) (*frontendServer, *fakeCart) {
+	cart := newFakeCart()
+	cart.set("session-1", "OLJCESPC7Z", 2, true)
+	cart.set("session-1", "66VCHSJNUP", 1, true)
+	return standIns(t, map[string]http.HandlerFunc{
+		"/cart":      cart.ServeHTTP,
+		"/cart/item": cart.ServeHTTP,
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"` + r.URL.Query().Get("id") + `","price_usd":{"currency_code":"USD","units":10}}`))
+		},
+		"/currency": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"currency_code":"USD","units":10}`))
+		},
+		"/currency/batch": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results":[{"currency_code":"USD","units":10},{"currency_code":"USD","units":10}]}`))
+		},
+	}), cart
+}
+
+func postForm(h http.HandlerFunc, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return servePage(h, req, path)
+}
+
+func TestUpdateCart(t *testing.T) {
+	tests := []struct {
+		form url.Values
+		code int
+		want map[string]int32
+	}{
+		{url.Values{"product_id": {"OLJCESPC7Z"}, "quantity": {"5"}}, http.StatusFound, map[string]int32{"OLJCESPC7Z": 5, "66VCHSJNUP": 1}},
+		{url.Values{"product_id": {"OLJCESPC7Z"}, "quantity": {"0"}}, http.StatusFound, map[string]int32{"66VCHSJNUP": 1}},
+		{url.Values{"product_id": {"OLJCESPC7Z"}, "quantity": {"100"}}, http.StatusBadRequest, map[string]int32{"OLJCESPC7Z": 2, "66VCHSJNUP": 1}},
+		{url.Values{"product_id": {"OLJCESPC7Z"}, "quantity": {"-1"}}, http.StatusBadRequest, map[string]int32{"OLJCESPC7Z": 2, "66VCHSJNUP": 1}},
+		{url.Values{"quantity": {"1"}}, http.StatusBadRequest, map[string]int32{"OLJCESPC7Z": 2, "66VCHSJNUP": 1}},
+	}
+	for _, tt := range tests {
+		fe, cart := cartStandIns(t)
+		rec := postForm(fe.updateCartHandler, "/cart/update", tt.form)
+		if rec.Code != tt.code {
+			t.Errorf("%v: status %d, want %d", tt.form, rec.Code, tt.code)
+		}
+		if rec.Code == http.StatusFound && rec.Header().Get("Location") != "/cart" {
+			t.Errorf("%v: redirected to %q, want /cart", tt.form, rec.Header().Get("Location"))
+		}
+		if got := cart.items("session-1"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: cart %v, want %v", tt.form, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveFromCart(t *testing.T) {
+	fe, cart := cartStandIns(t)
+	for _, id := range []string{"OLJCESPC7Z", "OLJCESPC7Z"} {
+		if rec := postForm(fe.removeFromCartHandler, "/cart/remove", url.Values{"product_id": {id}}); rec.Code != http.StatusFound {
+			t.Errorf("status %d, want 302", rec.Code)
+		}
+	}
+	if got, want := cart.items("session-1"), map[string]int32{"66VCHSJNUP": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cart %v, want %v", got, want)
+	}
+	if rec := postForm(fe.removeFromCartHandler, "/cart/remove", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("without a product: status %d, want 400", rec.Code)
+	}
+}
+
+// TestCartControls checks that the cart page edits each line with plain
+// forms.
+func TestCartControls(t *testing.T) {
+	fe, _ := cartStandIns(t)
+	rec := servePage(fe.viewCartHandler, httptest.NewRequest(http.MethodGet, "/cart", nil), "/cart")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d:\n%s", rec.Code, rec.Body)
+	}
+	page := rec.Body.String()
+	for _, s := range []string{
+		`action="/cart/update"`, `action="/cart/remove"`,
+		`id="quantity-OLJCESPC7Z"`, `value="2" min="0" max="99"`,
+		`name="product_id" value="66VCHSJNUP"`,
+	} {
+		if !strings.Contains(page, s) {
+			t.Errorf("the page lacks %s", s)
+		}
+	}
+}
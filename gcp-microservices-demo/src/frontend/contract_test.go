@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/contract"
+)
+
+// contractDir holds the pact files replayed by the provider tests.
+const contractDir = "../../contracts"
+
+var (
+	contractMoney   = &shop.Money{CurrencyCode: "USD", Units: 19, Nanos: 990000000}
+	contractAddress = &shop.Address{StreetAddress: "1600 Amphitheatre Parkway", City: "Mountain View", State: "CA", Country: "United States", ZipCode: 94043}
+)
+
+// TestCatalogContract records the calls the frontend makes to
+// productcatalogservice. Only the fields the templates use are expected.
+func TestCatalogContract(t *testing.T) {
+	m := contract.NewMock(t, "frontend", "productcatalogservice")
+	product := &shop.Product{
+		Id:          "OLJCESPC7Z",
+		Name:        "Sunglasses",
+		Description: "Add a modern touch to your outfits with these sleek aviator sunglasses.",
+		Picture:     "/static/img/products/sunglasses.jpg",
+		PriceUsd:    contractMoney,
+		Categories:  []string{"accessories"},
+	}
+	m.Expect("", "a list of products", http.StatusOK, &shop.ListProductsResponse{Products: []*shop.Product{product}})
+	m.Expect("", "the product OLJCESPC7Z", http.StatusOK, product)
+	m.Expect("", "the products matching sunglasses", http.StatusOK, &shop.SearchProductsResponse{Results: []*shop.Product{product}})
+	m.Expect("", "a page of the accessories by price", http.StatusOK, &shop.BrowseProductsResponse{
+		Products:   []*shop.Product{product},
+		TotalSize:  2,
+		Categories: []string{"accessories", "kitchen"},
+	})
+
+	fe := &frontendServer{client: shop.New(shop.WithServiceURL(shop.CatalogService, m.URL()))}
+	ctx := context.Background()
+	if _, err := fe.getProducts(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fe.getProduct(ctx, "OLJCESPC7Z"); err != nil {
+		t.Fatal(err)
+	}
+	l := fe.newLoader(ctx)
+	defer l.close()
+	if _, err := l.searchProducts("sunglasses")(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.browse(browseQuery{category: "accessories", sort: "price", page: 1}.request())(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m.Write(contractDir)
+}
+
+func TestAdContract(t *testing.T) {
+	m := contract.NewMock(t, "frontend", "adservice")
+	m.Expect("", "ads for the clothing category", http.StatusOK, &shop.AdResponse{Ads: []*shop.Ad{
+		{RedirectUrl: "/product/66VCHSJNUP", Text: "Tank top for sale. 20% off."},
+	}})
+
+	fe := &frontendServer{client: shop.New(shop.WithServiceURL(shop.AdService, m.URL()))}
+	if _, err := fe.getAd(context.Background(), []string{"clothing"}); err != nil {
+		t.Fatal(err)
+	}
+	m.Write(contractDir)
+}
+
+func TestShippingContract(t *testing.T) {
+	m := contract.NewMock(t, "frontend", "shippingservice")
+	m.Expect("", "a quote for the cart", http.StatusOK, &shop.GetQuoteResponse{
+		CostUsd: &shop.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000},
+	})
+	m.Expect("", "the timeline of a shipment", http.StatusOK, &shop.TrackShipmentResponse{
+		TrackingId: "RS-12345-678901234-S0K6G0",
+		Status:     "delivered",
+		Events: []*shop.ShipmentEvent{
+			{Status: "label_created", Description: "Shipping label created", Time: "2023-09-06T10:00:00Z", Done: true},
+		},
+		EstimatedDelivery: "2023-09-08T15:00:00Z",
+	})
+	currency := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"currency_code":"EUR","units":7,"nanos":650000000}`))
+	}))
+	defer currency.Close()
+
+	fe := &frontendServer{client: shop.New(
+		shop.WithServiceURL(shop.ShippingService, m.URL()),
+		shop.WithServiceURL(shop.CurrencyService, currency.URL),
+	)}
+	items := []*shop.CartItem{{ProductId: "OLJCESPC7Z", Quantity: 2}}
+	ctx := context.Background()
+	l := fe.newLoader(ctx)
+	defer l.close()
+	if _, err := l.shippingQuote(ctx, items, "EUR"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.trackShipment("RS-12345-678901234-S0K6G0")(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m.Write(contractDir)
+}
+
+// TestCartContract records the calls the frontend makes to cartservice,
+// from adding a product to emptying the cart.
+func TestCartContract(t *testing.T) {
+	m := contract.NewMock(t, "frontend", "cartservice")
+	m.Expect("", "adding OLJCESPC7Z to the cart", http.StatusOK, nil)
+	m.Expect("the cart of the user holds OLJCESPC7Z", "the cart of the user", http.StatusOK, &shop.Cart{
+		UserId: "contract-user",
+		Items:  []*shop.CartItem{{ProductId: "OLJCESPC7Z", Quantity: 2}},
+	})
+	m.Expect("the cart of the user holds OLJCESPC7Z", "setting the quantity of OLJCESPC7Z", http.StatusOK, &shop.Cart{
+		UserId: "contract-user",
+		Items:  []*shop.CartItem{{ProductId: "OLJCESPC7Z", Quantity: 5}},
+	})
+	m.Expect("the cart of the user holds OLJCESPC7Z", "removing OLJCESPC7Z from the cart", http.StatusOK, &shop.Cart{
+		Items: []*shop.CartItem{},
+	})
+	m.Expect("", "emptying the cart", http.StatusOK, nil)
+
+	fe := &frontendServer{client: shop.New(shop.WithServiceURL(shop.CartService, m.URL()))}
+	ctx := context.Background()
+	if err := fe.insertCart(ctx, "contract-user", "OLJCESPC7Z", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fe.getCart(ctx, "contract-user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fe.updateCart(ctx, "contract-user", "OLJCESPC7Z", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := fe.removeFromCart(ctx, "contract-user", "OLJCESPC7Z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fe.emptyCart(ctx, "contract-user"); err != nil {
+		t.Fatal(err)
+	}
+	m.Write(contractDir)
+}
+
+// TestCheckoutContract records the request placeOrderHandler sends for a
+// filled-in checkout form, and the lookups of the order history pages.
+func TestCheckoutContract(t *testing.T) {
+	m := contract.NewMock(t, "frontend", "checkoutservice")
+	order := &shop.OrderResult{
+		OrderId:            "e7a8e5b6-4c5d-11ee-be56-0242ac120002",
+		ShippingTrackingId: "RS-12345-678901234-S0K6G0",
+		ShippingCost:       &shop.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000},
+		ShippingAddress:    contractAddress,
+		Items: []*shop.OrderItem{{
+			Item: &shop.CartItem{ProductId: "OLJCESPC7Z", Quantity: 2},
+			Cost: contractMoney,
+		}},
+	}
+	placed := &shop.Order{
+		Order:    order,
+		Total:    &shop.Money{CurrencyCode: "USD", Units: 48, Nanos: 970000000},
+		PlacedAt: "2023-09-06T10:00:00Z",
+	}
+	m.Expect("the cart of the user holds OLJCESPC7Z", "an order for the cart", http.StatusOK, &shop.PlaceOrderResponse{Order: order})
+	m.Expect("the user placed an order", "the orders of the user", http.StatusOK, &shop.ListOrdersResponse{Orders: []*shop.Order{placed}})
+	m.Expect("the user placed an order", "an order of the user", http.StatusOK, placed)
+	m.Expect("the user placed an order", "an order of another user", http.StatusNotFound, &shop.ErrorResponse{Code: "not_found"})
+
+	fe := &frontendServer{client: shop.New(shop.WithServiceURL(shop.CheckoutService, m.URL()))}
+	ctx := context.Background()
+	_, err := fe.client.Checkout.PlaceOrder(ctx, &shop.PlaceOrderRequest{
+		Email: "someone@example.com",
+		CreditCard: &shop.CreditCardInfo{
+			CreditCardNumber:          "4432-8015-6152-0454",
+			CreditCardExpirationMonth: 1,
+			CreditCardExpirationYear:  2030,
+			CreditCardCvv:             672},
+		UserId:       "contract-user",
+		UserCurrency: "USD",
+		Address:      contractAddress,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := fe.newLoader(ctx)
+	defer l.close()
+	if _, err := l.orders("contract-user", "", ordersPageSize)(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.order("contract-user", order.OrderId)(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.order("another-user", order.OrderId)(ctx); !shop.IsNotFound(err) {
+		t.Fatalf("the order of another user: %v, want not found", err)
+	}
+	m.Write(contractDir)
+}
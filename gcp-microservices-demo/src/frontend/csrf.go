@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// csrfField is the form field, and csrfHeader the header, that carry
+	// the CSRF token of the session in the requests that change state.
+	csrfField  = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+	// csrfSessionKey is the session value that keeps the token.
+	csrfSessionKey = "csrf_token"
+)
+
+// ctxKeyCSRFToken is the key of the CSRF token of the session, which
+// executeTemplate adds to the data of every page.
+type ctxKeyCSRFToken struct{}
+
+// safeMethods change no state, and need no CSRF token.
+var safeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// csrfToken returns the CSRF token of the session of r, and gives the
+// session one, for as long as it lasts, if it has none yet.
+func (fe *frontendServer) csrfToken(r *http.Request) (string, error) {
+	s := currentSession(r)
+	if token := s.Values[csrfSessionKey]; token != "" {
+		return token, nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if s.Values == nil {
+		s.Values = make(map[string]string)
+	}
+	s.Values[csrfSessionKey] = base64.RawURLEncoding.EncodeToString(b)
+	if err := fe.sessions.Save(r.Context(), s); err != nil {
+		return "", err
+	}
+	return s.Values[csrfSessionKey], nil
+}
+
+// checkCSRF rejects the requests that change state without the CSRF token
+// of their session, in the csrfField form field or the csrfHeader header:
+// a page of another site can post a form to the shop with the cookies of
+// the shopper, but cannot read the token from the pages of the shop.
+// Requests without a session, such as the signed administration calls,
+// are not checked.
+func (fe *frontendServer) checkCSRF(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if currentSession(r) == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+		token, err := fe.csrfToken(r)
+		if err != nil {
+			renderHTTPError(log, r, w, errors.Wrap(err, "could not save session"), http.StatusServiceUnavailable)
+			return
+		}
+		if !safeMethods[r.Method] {
+			sent := r.Header.Get(csrfHeader)
+			if sent == "" {
+				sent = r.PostFormValue(csrfField)
+			}
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				rejectCSRF(w, r, log, sent == "")
+				return
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyCSRFToken{}, token))
+		next.ServeHTTP(w, r)
+	}
+}
+
+// rejectCSRF answers 403 with a page that asks the shopper to reload the
+// page the form came from, and send it again.
+func rejectCSRF(w http.ResponseWriter, r *http.Request, log logrus.FieldLogger, missing bool) {
+	log.WithField("csrf.missing", missing).Warn("rejected a request without a valid CSRF token")
+	w.WriteHeader(http.StatusForbidden)
+	if err := executeTemplate(r.Context(), w, "csrf_rejected", map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"back":              sameOrigin(r, r.Header.Get("referer")),
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// sameOrigin returns target as a path of the shop if it is one of its
+// pages, a path or an absolute URL of the host of r, and "/" otherwise, so
+// that a redirect to it cannot send the shopper to another site.
+func sameOrigin(r *http.Request, target string) string {
+	u, err := url.Parse(target)
+	if err != nil || target == "" {
+		return "/"
+	}
+	if u.Scheme != "" || u.Host != "" {
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host != r.Host || u.User != nil {
+			return "/"
+		}
+	}
+	// Browsers take "//host" and "/\host" to be other sites.
+	if !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(u.Path, "//") || strings.Contains(u.Path, `\`) {
+		return "/"
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+// csrfShop serves a product page, whose form carries the CSRF token, and
+// takes the posts of the form, through the middleware of the frontend.
+func csrfShop(t *testing.T) http.Handler {
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"OLJCESPC7Z","name":"Sunglasses","price_usd":{"currency_code":"USD","units":19}}`))
+		},
+	})
+	fe.sessions = testSessions()
+	r := mux.NewRouter()
+	r.HandleFunc("/product/{id}", fe.productHandler).Methods(http.MethodGet)
+	r.HandleFunc("/cart", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("added " + r.FormValue("product_id")))
+	}).Methods(http.MethodPost)
+	return fe.ensureSession(&logHandler{log: jsonLogger(new(bytes.Buffer)), next: fe.checkCSRF(r)})
+}
+
+func TestCSRF(t *testing.T) {
+	h := csrfShop(t)
+	serve := func(req *http.Request, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	post := func(form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/cart", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Referer", "/product/OLJCESPC7Z")
+		return serve(req, cookies...)
+	}
+
+	page := serve(httptest.NewRequest(http.MethodGet, "/product/OLJCESPC7Z", nil))
+	if page.Code != http.StatusOK {
+		t.Fatalf("the product page: status %d:\n%s", page.Code, page.Body)
+	}
+	m := csrfInput.FindAllStringSubmatch(page.Body.String(), -1)
+	if len(m) == 0 {
+		t.Fatalf("the forms of the product page carry no CSRF token:\n%s", page.Body)
+	}
+	token := m[0][1]
+	for _, f := range m {
+		if f[1] != token {
+			t.Errorf("the forms of the page carry the tokens %q and %q", token, f[1])
+		}
+	}
+	cookie := page.Result().Cookies()[0]
+
+	other := serve(httptest.NewRequest(http.MethodGet, "/product/OLJCESPC7Z", nil))
+	otherCookie := other.Result().Cookies()[0]
+	if otherToken := csrfInput.FindStringSubmatch(other.Body.String())[1]; otherToken == token {
+		t.Fatal("two sessions share a CSRF token")
+	}
+	if again := serve(httptest.NewRequest(http.MethodGet, "/product/OLJCESPC7Z", nil), cookie); csrfInput.FindStringSubmatch(again.Body.String())[1] != token {
+		t.Error("the token of the session changed between its pages")
+	}
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		cookies []*http.Cookie
+		header  string
+		want    int
+	}{
+		{"form token", url.Values{"product_id": {"OLJCESPC7Z"}, "csrf_token": {token}}, []*http.Cookie{cookie}, "", http.StatusOK},
+		{"header token", url.Values{"product_id": {"OLJCESPC7Z"}}, []*http.Cookie{cookie}, token, http.StatusOK},
+		{"no token", url.Values{"product_id": {"OLJCESPC7Z"}}, []*http.Cookie{cookie}, "", http.StatusForbidden},
+		{"wrong token", url.Values{"product_id": {"OLJCESPC7Z"}, "csrf_token": {token + "x"}}, []*http.Cookie{cookie}, "", http.StatusForbidden},
+		{"token of another session", url.Values{"product_id": {"OLJCESPC7Z"}, "csrf_token": {token}}, []*http.Cookie{otherCookie}, "", http.StatusForbidden},
+		{"no session", url.Values{"product_id": {"OLJCESPC7Z"}, "csrf_token": {token}}, nil, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/cart", strings.NewReader(tt.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Referer", "/product/OLJCESPC7Z")
+		if tt.header != "" {
+			req.Header.Set(csrfHeader, tt.header)
+		}
+		rec := serve(req, tt.cookies...)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		body := rec.Body.String()
+		if tt.want == http.StatusForbidden && (!strings.Contains(body, "This form has expired") || !strings.Contains(body, `href="/product/OLJCESPC7Z"`)) {
+			t.Errorf("%s: not the rejection page:\n%s", tt.name, body)
+		}
+		if tt.want == http.StatusOK && body != "added OLJCESPC7Z" {
+			t.Errorf("%s: the post was not handled: %s", tt.name, body)
+		}
+	}
+	if rec := post(url.Values{"csrf_token": {token}}, cookie); rec.Code != http.StatusOK {
+		t.Errorf("the token was not valid for a second post: status %d", rec.Code)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "http://shop.example/setCurrency", nil)
+	tests := []struct{ target, want string }{
+		{"", "/"},
+		{"/", "/"},
+		{"/?category=kitchen&page=2", "/?category=kitchen&page=2"},
+		{"http://shop.example/product/OLJCESPC7Z", "/product/OLJCESPC7Z"},
+		{"https://shop.example/cart?x=1", "/cart?x=1"},
+		{"https://shop.example", "/"},
+		{"https://evil.example/", "/"},
+		{"//evil.example/path", "/"},
+		{`/\evil.example`, "/"},
+		{"javascript:alert(1)", "/"},
+		{"https://user@shop.example/", "/"},
+		{"product/OLJCESPC7Z", "/"},
+	}
+	for _, tt := range tests {
+		if got := sameOrigin(r, tt.target); got != tt.want {
+			t.Errorf("sameOrigin(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSetCurrencyRedirect(t *testing.T) {
+	fe := &frontendServer{sessions: testSessions()}
+	for referer, want := range map[string]string{
+		"http://example.com/cart":       "/cart",
+		"https://evil.example/phishing": "/",
+		"//evil.example/phishing":       "/",
+		"":                              "/",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/setCurrency", strings.NewReader("currency_code=EUR"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Referer", referer)
+		rec := servePage(fe.setCurrencyHandler, req, "/setCurrency")
+		if got := rec.Header().Get("Location"); got != want {
+			t.Errorf("from %q: redirected to %q, want %q", referer, got, want)
+		}
+	}
+}
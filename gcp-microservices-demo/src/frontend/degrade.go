@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// Optional sections of the pages. A page renders a placeholder for a
+// section whose data could not be fetched in time, rather than failing:
+// only the catalog, currency and cart are critical to the pages.
+const (
+	sectionRecommendations = "recommendations"
+	sectionAd              = "ad"
+	sectionShipping        = "shipping"
+)
+
+// optionalDependency is the function serving an optional section, and the
+// time the section waits for it.
+type optionalDependency struct {
+	svc     shop.Service
+	timeout time.Duration
+}
+
+var optionalSections = map[string]optionalDependency{
+	sectionRecommendations: {shop.RecommendationService, 800 * time.Millisecond},
+	sectionAd:              {shop.AdService, 300 * time.Millisecond},
+	sectionShipping:        {shop.ShippingService, time.Second},
+}
+
+// degraded lists the sections of a page rendered as placeholders. It is
+// passed to the templates as "degraded".
+type degraded map[string]bool
+
+// fetchOptional runs fetch within the timeout of section. When it fails,
+// the section is marked degraded in d, logged and counted, and false is
+// returned.
+func fetchOptional(ctx context.Context, log logrus.FieldLogger, d degraded, section string, fetch func(ctx context.Context) error) bool {
+	dep := optionalSections[section]
+	ctx, cancel := context.WithTimeout(ctx, dep.timeout)
+	defer cancel()
+	err := fetch(ctx)
+	if err == nil {
+		return true
+	}
+	d[section] = true
+	log.WithFields(logrus.Fields{
+		"degraded":   section,
+		"downstream": dep.svc.String(),
+		"error":      err,
+	}).Warn("rendering a placeholder for an optional section")
+	route := "unmatched"
+	if r, ok := ctx.Value(ctxKeyRoute{}).(*string); ok {
+		route = *r
+	}
+	telemetry.Count(ctx, telemetry.FrontendDegraded, 1,
+		telemetry.RouteKey.String(route),
+		telemetry.SectionKey.String(section),
+		telemetry.ServiceKey.String(dep.svc.String()),
+	)
+	return false
+}
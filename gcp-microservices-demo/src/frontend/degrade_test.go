@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry/telemetrytest"
+)
+
+// standIns serves every function with an empty answer, except the paths in
+// handlers.
+func standIns(t *testing.T, handlers map[string]http.HandlerFunc) *frontendServer {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := handlers[r.URL.Path]; ok {
+			h(w, r)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+	return &frontendServer{client: shop.New(shop.WithBaseURL(srv.URL))}
+}
+
+func failing(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+func slow(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-r.Context().Done():
+	case <-time.After(2 * time.Second):
+	}
+	w.Write([]byte("{}"))
+}
+
+func servePage(h http.HandlerFunc, req *http.Request, route string) *httptest.ResponseRecorder {
+	log := logrus.New()
+	log.Out = io.Discard
+	ctx := context.WithValue(req.Context(), ctxKeyLog{}, logrus.FieldLogger(log))
+	ctx = context.WithValue(ctx, ctxKeyRoute{}, &route)
+	ctx = context.WithValue(ctx, ctxKeySessionID{}, "session-1")
+	rec := httptest.NewRecorder()
+	h(rec, req.WithContext(ctx))
+	return rec
+}
+
+func TestProductPageDegraded(t *testing.T) {
+	metrics := telemetrytest.New(t)
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/recommendation": failing,
+		"/ad":             slow,
+	})
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/product/OLJCESPC7Z", nil), map[string]string{"id": "OLJCESPC7Z"})
+	start := time.Now()
+	rec := servePage(fe.productHandler, req, "/product/{id}")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200:\n%s", rec.Code, rec.Body)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("the page took %v, longer than the timeouts of its optional sections", took)
+	}
+	for _, s := range []string{"recommendations-unavailable", "ad-unavailable"} {
+		if !strings.Contains(rec.Body.String(), s) {
+			t.Errorf("the page lacks the %s placeholder", s)
+		}
+	}
+	for section, svc := range map[string]shop.Service{sectionRecommendations: shop.RecommendationService, sectionAd: shop.AdService} {
+		m := metrics.Metric(t, telemetry.FrontendDegraded, telemetry.RouteKey.String("/product/{id}"),
+			telemetry.SectionKey.String(section), telemetry.ServiceKey.String(svc.String()))
+		if got := m.Sum.AsInt64(); got != 1 {
+			t.Errorf("%s{%s} = %d, want 1", telemetry.FrontendDegraded, section, got)
+		}
+	}
+}
+
+func TestCartPageDegraded(t *testing.T) {
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/recommendation": failing,
+		"/shipping":       failing,
+		"/cart": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"items":[{"product_id":"OLJCESPC7Z","quantity":2}]}`))
+		},
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"OLJCESPC7Z","price_usd":{"currency_code":"USD","units":19,"nanos":990000000}}`))
+		},
+		"/currency/batch": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"results":[{"currency_code":"USD","units":19,"nanos":990000000}]}`))
+		},
+	})
+	rec := servePage(fe.viewCartHandler, httptest.NewRequest(http.MethodGet, "/cart", nil), "/cart")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200:\n%s", rec.Code, rec.Body)
+	}
+	for _, s := range []string{"recommendations-unavailable", "calculated at checkout", "(before shipping)", "$39.98"} {
+		if !strings.Contains(rec.Body.String(), s) {
+			t.Errorf("the page lacks %q", s)
+		}
+	}
+}
+
+// TestChooseAdNone checks that a page without ads renders no ad, and no
+// placeholder either.
+func TestChooseAdNone(t *testing.T) {
+	fe := standIns(t, nil)
+	l := fe.newLoader(context.Background())
+	defer l.close()
+	log := logrus.New()
+	log.Out = io.Discard
+	if ad := l.chooseAd(log, nil)(); ad != nil || l.degraded()[sectionAd] {
+		t.Errorf("chooseAd = %v, degraded %v; want no ad", ad, l.degraded())
+	}
+}
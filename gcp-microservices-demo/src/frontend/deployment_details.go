@@ -3,14 +3,15 @@ package main
 import (
 	"net/http"
 	"os"
-	"time"
 
 	"cloud.google.com/go/compute/metadata"
 	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
 )
 
 var deploymentDetailsMap map[string]string
-var log *logrus.Logger
+var log *logging.Logger
 
 func init() {
 	initializeLogger()
@@ -20,17 +21,7 @@ func init() {
 }
 
 func initializeLogger() {
-	log = logrus.New()
-	log.Level = logrus.DebugLevel
-	log.Formatter = &logrus.JSONFormatter{
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "severity",
-			logrus.FieldKeyMsg:   "message",
-		},
-		TimestampFormat: time.RFC3339Nano,
-	}
-	log.Out = os.Stdout
+	log = logging.NewFromEnv()
 }
 
 func loadDeploymentDetails() {
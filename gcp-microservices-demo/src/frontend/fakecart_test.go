@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/contract"
+)
+
+// fakeCart keeps carts in memory, serving /cart and /cart/item as the
+// cartservice function does.
+type fakeCart struct {
+	mu    sync.Mutex
+	carts map[string][]*shop.CartItem
+}
+
+func newFakeCart() *fakeCart {
+	return &fakeCart{carts: make(map[string][]*shop.CartItem)}
+}
+
+// items returns the cart of userID as a map of quantities by product.
+func (c *fakeCart) items(userID string) map[string]int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int32)
+	for _, item := range c.carts[userID] {
+		out[item.ProductId] = item.Quantity
+	}
+	return out
+}
+
+// set sets the quantity of productID in the cart of userID, keeping the
+// order of the products. A quantity of 0 removes the product.
+func (c *fakeCart) set(userID, productID string, quantity int32, add bool) {
+	var items []*shop.CartItem
+	found := false
+	for _, item := range c.carts[userID] {
+		if item.ProductId == productID {
+			found = true
+			if add {
+				quantity += item.Quantity
+			}
+			if quantity <= 0 {
+				continue
+			}
+			item = &shop.CartItem{ProductId: productID, Quantity: quantity}
+		}
+		items = append(items, item)
+	}
+	if !found && quantity > 0 {
+		items = append(items, &shop.CartItem{ProductId: productID, Quantity: quantity})
+	}
+	if len(items) == 0 {
+		delete(c.carts, userID)
+		return
+	}
+	c.carts[userID] = items
+}
+
+func (c *fakeCart) cart(userID string) *shop.Cart {
+	items := c.carts[userID]
+	if len(items) == 0 {
+		return &shop.Cart{Items: []*shop.CartItem{}}
+	}
+	return &shop.Cart{UserId: userID, Items: items}
+}
+
+func (c *fakeCart) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out interface{}
+	switch item := strings.HasSuffix(r.URL.Path, "/item"); {
+	case r.Method == http.MethodGet && !item:
+		out = c.cart(r.URL.Query().Get("user_id"))
+	case r.Method == http.MethodPost && !item:
+		var in shop.AddItemRequest
+		if json.NewDecoder(r.Body).Decode(&in) != nil || in.Item == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.set(in.UserId, in.Item.ProductId, in.Item.Quantity, true)
+	case r.Method == http.MethodDelete && !item:
+		var in shop.EmptyCartRequest
+		if json.NewDecoder(r.Body).Decode(&in) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		delete(c.carts, in.UserId)
+	case r.Method == http.MethodPut && item:
+		var in shop.UpdateItemRequest
+		if json.NewDecoder(r.Body).Decode(&in) != nil || in.Item == nil || in.Item.Quantity < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.set(in.UserId, in.Item.ProductId, in.Item.Quantity, false)
+		out = c.cart(in.UserId)
+	case r.Method == http.MethodDelete && item:
+		var in shop.RemoveItemRequest
+		if json.NewDecoder(r.Body).Decode(&in) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.set(in.UserId, in.ProductId, 0, false)
+		out = c.cart(in.UserId)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if out != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// TestFakeCartHonorsContracts replays the pact of cartservice against the
+// fake, so that the tests using it rely on what cartservice promises.
+// cartservice itself is a Python function, tested by its test.py.
+func TestFakeCartHonorsContracts(t *testing.T) {
+	cart := newFakeCart()
+	contract.Verify(t, contractDir, "cartservice", cart, contract.States{
+		"the cart of the user holds OLJCESPC7Z": func(t *testing.T) {
+			cart.mu.Lock()
+			defer cart.mu.Unlock()
+			cart.carts = map[string][]*shop.CartItem{"contract-user": {{ProductId: "OLJCESPC7Z", Quantity: 2}}}
+		},
+	})
+}
@@ -18,7 +18,7 @@ import (
 	"context"
 	"fmt"
 	"html/template"
-	"math/rand"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -29,9 +29,13 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
 
 	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/money"
-	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/rest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/session"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
 type platformDetails struct {
@@ -41,54 +45,75 @@ type platformDetails struct {
 
 var (
 	isCymbalBrand = "true" == strings.ToLower(os.Getenv("CYMBAL_BRANDING"))
-	templates     = template.Must(template.New("").
+	// In production, error pages show the request ID only: the details go
+	// to the logs.
+	isProduction = os.Getenv("SHOP_MODE") == "production"
+	templates    = template.Must(template.New("").
 			Funcs(template.FuncMap{
 			"renderMoney":        renderMoney,
 			"renderCurrencyLogo": renderCurrencyLogo,
+			"highlight":          highlight,
 		}).ParseGlob("templates/*.html"))
 	plat platformDetails
 )
 
 var validEnvs = []string{"local", "gcp", "azure", "aws", "onprem", "alibaba"}
 
+// homeHandler lists a page of the catalog, narrowed down to a category and
+// a price range and sorted as the query parameters say.
 func (fe *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
 	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
 	log.WithField("currency", currentCurrency(r)).Info("home")
-	currencies, err := fe.getCurrencies()
+	query, err := parseBrowseQuery(r)
+	if err != nil {
+		renderHTTPError(log, r, w, err, http.StatusBadRequest)
+		return
+	}
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadCurrencies, loadPage, loadCart := l.currencies(), l.browse(query.request()), l.cart(sessionID(r))
+	ad := l.chooseAd(log, []string{})
+
+	currencies, err := loadCurrencies(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
 		return
 	}
-	products, err := fe.getProducts()
+	page, err := loadPage(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve products"), http.StatusInternalServerError)
 		return
 	}
-	cart, err := fe.getCart(sessionID(r))
+	products := page.GetProducts()
+	cart, err := loadCart(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
 		return
 	}
 
 	type productView struct {
-		Item  *rest.Product
-		Price *rest.Money
+		Item  *shop.Product
+		Price *shop.Money
+	}
+	prices := make([]*shop.Money, len(products))
+	for i, p := range products {
+		prices[i] = p.GetPriceUsd()
+	}
+	converted, err := l.convertAll(prices, currentCurrency(r))(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to do currency conversion for products"), http.StatusInternalServerError)
+		return
 	}
 	ps := make([]productView, len(products))
 	for i, p := range products {
-		price, err := fe.convertCurrency(p.GetPriceUsd(), currentCurrency(r))
-		if err != nil {
-			renderHTTPError(log, r, w, errors.Wrapf(err, "failed to do currency conversion for product %s", p.GetId()), http.StatusInternalServerError)
-			return
-		}
-		ps[i] = productView{p, price}
+		ps[i] = productView{p, converted[i]}
 	}
 
 	// Set ENV_PLATFORM (default to local if not set; use env var if set; otherwise detect GCP, which overrides env)_
 	var env = os.Getenv("ENV_PLATFORM")
 	// Only override from env variable if set + valid env
 	if env == "" || stringinSlice(validEnvs, env) == false {
-		fmt.Println("env platform is either empty or invalid")
+		log.Warn("env platform is either empty or invalid")
 		env = "local"
 	}
 	// Autodetect GCP
@@ -102,16 +127,27 @@ func (fe *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
 	plat = platformDetails{}
 	plat.setPlatformDetails(strings.ToLower(env))
 
-	if err := templates.ExecuteTemplate(w, "home", map[string]interface{}{
+	chosen := ad()
+	if err := executeTemplate(r.Context(), w, "home", map[string]interface{}{
 		"session_id":        sessionID(r),
 		"request_id":        r.Context().Value(ctxKeyRequestID{}),
 		"user_currency":     currentCurrency(r),
 		"show_currency":     true,
 		"currencies":        currencies,
 		"products":          ps,
+		"category":          query.category,
+		"sort":              query.sort,
+		"min_price_usd":     query.minPrice,
+		"max_price_usd":     query.maxPrice,
+		"categories":        query.categoryLinks(page.GetCategories()),
+		"sorts":             query.sortLinks(),
+		"pages":             query.pageLinks(page.GetTotalSize()),
+		"prev_page":         query.adjacentPage(page.GetTotalSize(), -1),
+		"next_page":         query.adjacentPage(page.GetTotalSize(), 1),
 		"cart_size":         cartSize(cart),
 		"banner_color":      os.Getenv("BANNER_COLOR"), // illustrates canary deployments
-		"ad":                fe.chooseAd(r.Context(), []string{}, log),
+		"ad":                chosen,
+		"degraded":          l.degraded(),
 		"platform_css":      plat.css,
 		"platform_name":     plat.provider,
 		"is_cymbal_brand":   isCymbalBrand,
@@ -153,49 +189,58 @@ func (fe *frontendServer) productHandler(w http.ResponseWriter, r *http.Request)
 	log.WithField("id", id).WithField("currency", currentCurrency(r)).
 		Debug("serving product page")
 
-	p, err := fe.getProduct(id)
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadProduct, loadCurrencies, loadCart := l.product(id), l.currencies(), l.cart(sessionID(r))
+	var recommendations []*shop.Product
+	waitRecommendations := l.optional(log, sectionRecommendations, func(ctx context.Context) (err error) {
+		recommendations, err = l.recommendations(ctx, sessionID(r), []string{id})
+		return err
+	})
+
+	p, err := loadProduct(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve product"), http.StatusInternalServerError)
 		return
 	}
-	currencies, err := fe.getCurrencies()
+	ad := l.chooseAd(log, p.Categories)
+	loadPrice := l.convert(p.GetPriceUsd(), currentCurrency(r))
+
+	currencies, err := loadCurrencies(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
 		return
 	}
 
-	cart, err := fe.getCart(sessionID(r))
+	cart, err := loadCart(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
 		return
 	}
 
-	price, err := fe.convertCurrency(p.GetPriceUsd(), currentCurrency(r))
+	price, err := loadPrice(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "failed to convert currency"), http.StatusInternalServerError)
 		return
 	}
-
-	recommendations, err := fe.getRecommendations(sessionID(r), []string{id})
-	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "failed to get product recommendations"), http.StatusInternalServerError)
-		return
-	}
+	waitRecommendations()
+	chosen := ad()
 
 	product := struct {
-		Item  *rest.Product
-		Price *rest.Money
+		Item  *shop.Product
+		Price *shop.Money
 	}{p, price}
 
-	if err := templates.ExecuteTemplate(w, "product", map[string]interface{}{
+	if err := executeTemplate(r.Context(), w, "product", map[string]interface{}{
 		"session_id":        sessionID(r),
 		"request_id":        r.Context().Value(ctxKeyRequestID{}),
-		"ad":                fe.chooseAd(r.Context(), p.Categories, log),
+		"ad":                chosen,
 		"user_currency":     currentCurrency(r),
 		"show_currency":     true,
 		"currencies":        currencies,
 		"product":           product,
 		"recommendations":   recommendations,
+		"degraded":          l.degraded(),
 		"cart_size":         cartSize(cart),
 		"platform_css":      plat.css,
 		"platform_name":     plat.provider,
@@ -216,16 +261,57 @@ func (fe *frontendServer) addToCartHandler(w http.ResponseWriter, r *http.Reques
 	}
 	log.WithField("product", productID).WithField("quantity", quantity).Debug("adding to cart")
 
-	p, err := fe.getProduct(productID)
+	p, err := fe.getProduct(r.Context(), productID)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve product"), http.StatusInternalServerError)
 		return
 	}
 
-	if err := fe.insertCart(sessionID(r), p.GetId(), int32(quantity)); err != nil {
+	if err := fe.insertCart(r.Context(), sessionID(r), p.GetId(), int32(quantity)); err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "failed to add to cart"), http.StatusInternalServerError)
 		return
 	}
+	telemetry.Count(r.Context(), telemetry.CartAdds, int64(quantity), telemetry.ProductKey.String(p.GetId()))
+	w.Header().Set("location", "/cart")
+	w.WriteHeader(http.StatusFound)
+}
+
+// maxCartQuantity bounds the quantity of a product the cart page sets.
+const maxCartQuantity = 99
+
+// updateCartHandler sets the quantity of a product of the cart. A quantity
+// of 0 removes the product.
+func (fe *frontendServer) updateCartHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	quantity, err := strconv.ParseUint(r.FormValue("quantity"), 10, 32)
+	productID := r.FormValue("product_id")
+	if productID == "" || err != nil || quantity > maxCartQuantity {
+		renderHTTPError(log, r, w, errors.New("invalid form input"), http.StatusBadRequest)
+		return
+	}
+	log.WithField("product", productID).WithField("quantity", quantity).Debug("updating cart")
+
+	if err := fe.updateCart(r.Context(), sessionID(r), productID, int32(quantity)); err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to update cart"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("location", "/cart")
+	w.WriteHeader(http.StatusFound)
+}
+
+func (fe *frontendServer) removeFromCartHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	productID := r.FormValue("product_id")
+	if productID == "" {
+		renderHTTPError(log, r, w, errors.New("invalid form input"), http.StatusBadRequest)
+		return
+	}
+	log.WithField("product", productID).Debug("removing from cart")
+
+	if err := fe.removeFromCart(r.Context(), sessionID(r), productID); err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to remove from cart"), http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("location", "/cart")
 	w.WriteHeader(http.StatusFound)
 }
@@ -234,7 +320,7 @@ func (fe *frontendServer) emptyCartHandler(w http.ResponseWriter, r *http.Reques
 	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
 	log.Debug("emptying cart")
 
-	if err := fe.emptyCart(sessionID(r)); err != nil {
+	if err := fe.emptyCart(r.Context(), sessionID(r)); err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "failed to empty cart"), http.StatusInternalServerError)
 		return
 	}
@@ -245,69 +331,87 @@ func (fe *frontendServer) emptyCartHandler(w http.ResponseWriter, r *http.Reques
 func (fe *frontendServer) viewCartHandler(w http.ResponseWriter, r *http.Request) {
 	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
 	log.Debug("view user cart")
-	currencies, err := fe.getCurrencies()
-	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
-		return
-	}
-	cart, err := fe.getCart(sessionID(r))
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadCurrencies, loadCart := l.currencies(), l.cart(sessionID(r))
+
+	cart, err := loadCart(l.ctx)
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
 		return
 	}
-
-	recommendations, err := fe.getRecommendations(sessionID(r), cartIDs(cart))
-	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "failed to get product recommendations"), http.StatusInternalServerError)
-		return
+	loadProducts := make([]func(context.Context) (*shop.Product, error), len(cart))
+	for i, item := range cart {
+		loadProducts[i] = l.product(item.GetProductId())
 	}
+	var recommendations []*shop.Product
+	waitRecommendations := l.optional(log, sectionRecommendations, func(ctx context.Context) (err error) {
+		recommendations, err = l.recommendations(ctx, sessionID(r), cartIDs(cart))
+		return err
+	})
+	// Without a quote, the total leaves shipping out.
+	var shippingCost *shop.Money
+	waitShipping := l.optional(log, sectionShipping, func(ctx context.Context) (err error) {
+		shippingCost, err = l.shippingQuote(ctx, cart, currentCurrency(r))
+		return err
+	})
 
-	shippingCost, err := fe.getShippingQuote(cart, currentCurrency(r))
+	currencies, err := loadCurrencies(l.ctx)
 	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "failed to get shipping quote"), http.StatusInternalServerError)
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
 		return
 	}
 
 	type cartItemView struct {
-		Item     *rest.Product
+		Item     *shop.Product
 		Quantity int32
-		Price    *rest.Money
+		Price    *shop.Money
 	}
 	items := make([]cartItemView, len(cart))
-	totalPrice := rest.Money{CurrencyCode: currentCurrency(r)}
+	products := make([]*shop.Product, len(cart))
+	prices := make([]*shop.Money, len(cart))
 	for i, item := range cart {
-		p, err := fe.getProduct(item.GetProductId())
+		p, err := loadProducts[i](l.ctx)
 		if err != nil {
 			renderHTTPError(log, r, w, errors.Wrapf(err, "could not retrieve product #%s", item.GetProductId()), http.StatusInternalServerError)
 			return
 		}
-		price, err := fe.convertCurrency(p.GetPriceUsd(), currentCurrency(r))
-		if err != nil {
-			renderHTTPError(log, r, w, errors.Wrapf(err, "could not convert currency for product #%s", item.GetProductId()), http.StatusInternalServerError)
-			return
-		}
-
-		multPrice := money.MultiplySlow(*price, uint32(item.GetQuantity()))
+		products[i] = p
+		prices[i] = p.GetPriceUsd()
+	}
+	converted, err := l.convertAll(prices, currentCurrency(r))(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not convert currency for cart items"), http.StatusInternalServerError)
+		return
+	}
+	totalPrice := shop.Money{CurrencyCode: currentCurrency(r)}
+	for i, item := range cart {
+		multPrice := money.MultiplySlow(*converted[i], uint32(item.GetQuantity()))
 		items[i] = cartItemView{
-			Item:     p,
+			Item:     products[i],
 			Quantity: item.GetQuantity(),
 			Price:    &multPrice}
 		totalPrice = money.Must(money.Sum(totalPrice, multPrice))
 	}
-	totalPrice = money.Must(money.Sum(totalPrice, *shippingCost))
+	waitRecommendations()
+	if waitShipping() {
+		totalPrice = money.Must(money.Sum(totalPrice, *shippingCost))
+	}
 	year := time.Now().Year()
 
-	if err := templates.ExecuteTemplate(w, "cart", map[string]interface{}{
+	if err := executeTemplate(r.Context(), w, "cart", map[string]interface{}{
 		"session_id":        sessionID(r),
 		"request_id":        r.Context().Value(ctxKeyRequestID{}),
 		"user_currency":     currentCurrency(r),
 		"currencies":        currencies,
 		"recommendations":   recommendations,
+		"degraded":          l.degraded(),
 		"cart_size":         cartSize(cart),
 		"shipping_cost":     shippingCost,
 		"show_currency":     true,
 		"total_cost":        totalPrice,
 		"items":             items,
+		"max_quantity":      maxCartQuantity,
 		"expiration_years":  []int{year, year + 1, year + 2, year + 3, year + 4},
 		"platform_css":      plat.css,
 		"platform_name":     plat.provider,
@@ -335,16 +439,16 @@ func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Reque
 		ccCVV, _      = strconv.ParseInt(r.FormValue("credit_card_cvv"), 10, 32)
 	)
 
-	order, err := rest.PlaceOrder(fe.checkoutSvcAddr, &rest.PlaceOrderRequest{
+	order, err := fe.client.Checkout.PlaceOrder(r.Context(), &shop.PlaceOrderRequest{
 		Email: email,
-		CreditCard: &rest.CreditCardInfo{
+		CreditCard: &shop.CreditCardInfo{
 			CreditCardNumber:          ccNumber,
 			CreditCardExpirationMonth: int32(ccMonth),
 			CreditCardExpirationYear:  int32(ccYear),
 			CreditCardCvv:             int32(ccCVV)},
 		UserId:       sessionID(r),
 		UserCurrency: currentCurrency(r),
-		Address: &rest.Address{
+		Address: &shop.Address{
 			StreetAddress: streetAddress,
 			City:          city,
 			State:         state,
@@ -357,8 +461,12 @@ func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Reque
 	}
 	log.WithField("order", order.GetOrder().GetOrderId()).Info("order placed")
 
-	order.GetOrder().GetItems()
-	recommendations, _ := fe.getRecommendations(sessionID(r), nil)
+	deg := make(degraded)
+	var recommendations []*shop.Product
+	fetchOptional(r.Context(), log, deg, sectionRecommendations, func(ctx context.Context) (err error) {
+		recommendations, err = fe.getRecommendations(ctx, sessionID(r), nil)
+		return err
+	})
 
 	totalPaid := *order.GetOrder().GetShippingCost()
 	for _, v := range order.GetOrder().GetItems() {
@@ -366,13 +474,13 @@ func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Reque
 		totalPaid = money.Must(money.Sum(totalPaid, multPrice))
 	}
 
-	currencies, err := fe.getCurrencies()
+	currencies, err := fe.getCurrencies(r.Context())
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
 		return
 	}
 
-	if err := templates.ExecuteTemplate(w, "order", map[string]interface{}{
+	if err := executeTemplate(r.Context(), w, "order", map[string]interface{}{
 		"session_id":        sessionID(r),
 		"request_id":        r.Context().Value(ctxKeyRequestID{}),
 		"user_currency":     currentCurrency(r),
@@ -381,6 +489,7 @@ func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Reque
 		"order":             order.GetOrder(),
 		"total_paid":        &totalPaid,
 		"recommendations":   recommendations,
+		"degraded":          deg,
 		"platform_css":      plat.css,
 		"platform_name":     plat.provider,
 		"is_cymbal_brand":   isCymbalBrand,
@@ -390,13 +499,21 @@ func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// logoutHandler ends the session, and starts a new, empty one in its
+// place: the cart of the old session is left behind with it. The other
+// cookies, such as the currency, are expired. It is a POST, with the CSRF
+// token, so that another site cannot log a shopper out.
 func (fe *frontendServer) logoutHandler(w http.ResponseWriter, r *http.Request) {
 	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
 	log.Debug("logging out")
+	if _, err := fe.sessions.Regenerate(w, r, currentSession(r)); err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not end session"), http.StatusInternalServerError)
+		return
+	}
 	for _, c := range r.Cookies() {
-		c.Expires = time.Now().Add(-time.Hour * 24 * 365)
-		c.MaxAge = -1
-		http.SetCookie(w, c)
+		if c.Name != cookieSessionID {
+			http.SetCookie(w, fe.sessions.Cookie(r, c.Name, "", -1))
+		}
 	}
 	w.Header().Set("Location", "/")
 	w.WriteHeader(http.StatusFound)
@@ -409,41 +526,59 @@ func (fe *frontendServer) setCurrencyHandler(w http.ResponseWriter, r *http.Requ
 		Debug("setting currency")
 
 	if cur != "" {
-		http.SetCookie(w, &http.Cookie{
-			Name:   cookieCurrency,
-			Value:  cur,
-			MaxAge: cookieMaxAge,
-		})
+		http.SetCookie(w, fe.sessions.Cookie(r, cookieCurrency, cur, cookieMaxAge))
 	}
-	referer := r.Header.Get("referer")
-	if referer == "" {
-		referer = "/"
-	}
-	w.Header().Set("Location", referer)
+	w.Header().Set("Location", sameOrigin(r, r.Header.Get("referer")))
 	w.WriteHeader(http.StatusFound)
 }
 
-// chooseAd queries for advertisements available and randomly chooses one, if
-// available. It ignores the error retrieving the ad since it is not critical.
-func (fe *frontendServer) chooseAd(ctx context.Context, ctxKeys []string, log logrus.FieldLogger) *rest.Ad {
-	ads, err := fe.getAd(ctxKeys)
-	if err != nil {
-		log.WithField("error", err).Warn("failed to retrieve ads")
-		return nil
-	}
-	return ads[rand.Intn(len(ads))]
+// downstreamStatuses are the statuses of failed downstream calls that
+// renderHTTPError answers with instead of a 500.
+var downstreamStatuses = map[int]bool{
+	http.StatusNotFound:            true,
+	http.StatusConflict:            true,
+	http.StatusUnprocessableEntity: true,
+	http.StatusServiceUnavailable:  true,
 }
 
 func renderHTTPError(log logrus.FieldLogger, r *http.Request, w http.ResponseWriter, err error, code int) {
-	log.WithField("error", err).Error("request error")
-	errMsg := fmt.Sprintf("%+v", err)
+	// Downstream errors may echo the card, email or address of an order.
+	errMsg := fmt.Sprintf("%+v", logging.RedactError(err))
+
+	// Describe the downstream function that failed, and answer with its
+	// status when it tells more than a generic 500.
+	var failure map[string]interface{}
+	var se *shop.Error
+	if errors.As(err, &se) {
+		if code == http.StatusInternalServerError && downstreamStatuses[se.StatusCode] {
+			code = se.StatusCode
+		}
+		failure = map[string]interface{}{
+			"service":    se.Service.String(),
+			"code":       se.Code(),
+			"message":    logging.Redact(se.Envelope.GetMessage()),
+			"request_id": se.RequestID(),
+			"retryable":  shop.IsRetryable(err),
+		}
+		log = log.WithFields(logrus.Fields{
+			"downstream":            se.Service.String(),
+			"downstream_status":     se.StatusCode,
+			"downstream_request_id": se.RequestID(),
+		})
+	}
+	log.WithField("error", errMsg).Error("request error")
 
 	w.WriteHeader(code)
 
-	if templateErr := templates.ExecuteTemplate(w, "error", map[string]interface{}{
+	if isProduction {
+		errMsg, failure = "", nil
+	}
+	if templateErr := executeTemplate(r.Context(), w, "error", map[string]interface{}{
 		"session_id":        sessionID(r),
 		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"production":        isProduction,
 		"error":             errMsg,
+		"failure":           failure,
 		"status_code":       code,
 		"status":            http.StatusText(code),
 		"deploymentDetails": deploymentDetailsMap,
@@ -452,6 +587,28 @@ func renderHTTPError(log logrus.FieldLogger, r *http.Request, w http.ResponseWri
 	}
 }
 
+// executeTemplate renders the template name with data to w in a span of
+// its own. The CSRF token of the session is added to the data of the
+// pages as csrf_token, for their forms.
+func executeTemplate(ctx context.Context, w io.Writer, name string, data interface{}) error {
+	_, span := telemetry.Tracer().Start(ctx, "template "+name)
+	defer span.End()
+	if m, ok := data.(map[string]interface{}); ok {
+		if token, ok := ctx.Value(ctxKeyCSRFToken{}).(string); ok {
+			m["csrf_token"] = token
+		}
+		if s, ok := ctx.Value(ctxKeySession{}).(*session.Session); ok {
+			m["account_email"] = accountEmail(s)
+		}
+	}
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
 func currentCurrency(r *http.Request) string {
 	c, _ := r.Cookie(cookieCurrency)
 	if c != nil {
@@ -468,7 +625,7 @@ func sessionID(r *http.Request) string {
 	return ""
 }
 
-func cartIDs(c []*rest.CartItem) []string {
+func cartIDs(c []*shop.CartItem) []string {
 	out := make([]string, len(c))
 	for i, v := range c {
 		out[i] = v.GetProductId()
@@ -477,7 +634,7 @@ func cartIDs(c []*rest.CartItem) []string {
 }
 
 // get total # of items in cart
-func cartSize(c []*rest.CartItem) int {
+func cartSize(c []*shop.CartItem) int {
 	cartSize := 0
 	for _, item := range c {
 		cartSize += int(item.GetQuantity())
@@ -485,7 +642,7 @@ func cartSize(c []*rest.CartItem) int {
 	return cartSize
 }
 
-func renderMoney(money rest.Money) string {
+func renderMoney(money shop.Money) string {
 	currencyLogo := renderCurrencyLogo(money.GetCurrencyCode())
 	return fmt.Sprintf("%s%d.%02d", currencyLogo, money.GetUnits(), money.GetNanos()/10000000)
 }
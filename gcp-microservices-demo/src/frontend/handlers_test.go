@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+)
+
+func TestRenderHTTPError(t *testing.T) {
+	log := logrus.New()
+	log.Out = io.Discard
+	tests := []struct {
+		err      error
+		code     int
+		want     int
+		contains []string
+	}{
+		{errors.New("product id not specified"), http.StatusBadRequest, http.StatusBadRequest, nil},
+		{
+			errors.Wrap(&shop.Error{Service: shop.CatalogService, Op: "GetProduct", StatusCode: http.StatusNotFound,
+				Envelope: &shop.ErrorResponse{Code: "not_found", Message: `no product with ID "X"`, RequestId: "req-1"}}, "could not retrieve product"),
+			http.StatusInternalServerError, http.StatusNotFound,
+			[]string{"productcatalogservice", "not_found", "req-1"},
+		},
+		{
+			errors.Wrap(&shop.Error{Service: shop.CheckoutService, Op: "PlaceOrder", StatusCode: http.StatusServiceUnavailable,
+				Envelope: &shop.ErrorResponse{Code: "unavailable", Message: "payment is down", Retryable: true}}, "failed to complete the order"),
+			http.StatusInternalServerError, http.StatusServiceUnavailable,
+			[]string{"payment is down", "try again"},
+		},
+		{
+			errors.Wrap(&shop.Error{Service: shop.CartService, Op: "GetCart", StatusCode: http.StatusBadGateway}, "could not retrieve cart"),
+			http.StatusInternalServerError, http.StatusInternalServerError,
+			[]string{"cartservice"},
+		},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		renderHTTPError(log, httptest.NewRequest(http.MethodGet, "/", nil), rec, tt.err, tt.code)
+		if rec.Code != tt.want {
+			t.Errorf("%v: status %d, want %d", tt.err, rec.Code, tt.want)
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(rec.Body.String(), s) {
+				t.Errorf("%v: page does not mention %q", tt.err, s)
+			}
+		}
+	}
+}
+
+func TestRenderHTTPErrorRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	log := logging.New(logging.Config{Level: logrus.InfoLevel, Out: &buf})
+	err := errors.Wrap(&shop.Error{Service: shop.CheckoutService, Op: "PlaceOrder", StatusCode: http.StatusUnprocessableEntity,
+		Envelope: &shop.ErrorResponse{Code: "invalid_argument", Message: "card 4432-8015-6152-0454 of someone@example.com declined"}}, "failed to complete the order")
+	render := func() string {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/cart/checkout", nil)
+		req = req.WithContext(context.WithValue(req.Context(), ctxKeyRequestID{}, "req-42"))
+		renderHTTPError(log, req, rec, err, http.StatusInternalServerError)
+		return rec.Body.String()
+	}
+
+	page := render()
+	if strings.Contains(page+buf.String(), "4432-8015") || strings.Contains(page+buf.String(), "someone@") {
+		t.Errorf("PII leaked into the page or the logs:\n%s\n%s", page, buf.String())
+	}
+	if !strings.Contains(page, "****0454") || !strings.Contains(page, "failed to complete the order") {
+		t.Errorf("the page lacks the redacted details:\n%s", page)
+	}
+
+	defer func(prod bool) { isProduction = prod }(isProduction)
+	isProduction = true
+	buf.Reset()
+	page = render()
+	if strings.Contains(page, "failed to complete the order") || strings.Contains(page, "invalid_argument") || !strings.Contains(page, "req-42") {
+		t.Errorf("the production page shows more than the request ID:\n%s", page)
+	}
+	if !strings.Contains(buf.String(), "failed to complete the order") {
+		t.Errorf("the error chain is not logged: %s", buf.String())
+	}
+}
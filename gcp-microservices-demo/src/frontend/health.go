@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// Statuses of the readiness report and of its dependencies.
+const (
+	statusReady       = "ready"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+
+	statusOK      = "ok"
+	statusFailing = "failing"
+)
+
+const (
+	defaultProbeTimeout = time.Second
+	defaultProbeTTL     = 5 * time.Second
+	// probeUser is the cart probed, which no shopper has.
+	probeUser = "readiness-probe"
+)
+
+// defaultOptional are the dependencies whose failure degrades the frontend
+// without making it unready: the pages render without them.
+var defaultOptional = []shop.Service{shop.AdService, shop.RecommendationService}
+
+// dependency is a function the frontend calls, and the cheap call probing it.
+type dependency struct {
+	svc      shop.Service
+	optional bool
+	probe    func(ctx context.Context) error
+}
+
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	Optional  bool    `json:"optional,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readinessReport struct {
+	Status       string                      `json:"status"`
+	CheckedAt    time.Time                   `json:"checked_at"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// readiness serves the readiness of the frontend: it probes every
+// dependency concurrently, each within timeout, and caches the report for
+// ttl so that frequent checks do not load the functions. The frontend is
+// unavailable when a required dependency fails, and degraded when only
+// optional ones do.
+type readiness struct {
+	log     logrus.FieldLogger
+	deps    []dependency
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	report *readinessReport
+}
+
+// newReadiness probes the functions fe calls. READINESS_PROBE_TIMEOUT and
+// READINESS_CACHE_TTL override the timeout and ttl, and READINESS_OPTIONAL
+// lists the optional dependencies by function name, as in
+// "adservice,recommendationservice".
+func newReadiness(fe *frontendServer, log logrus.FieldLogger) *readiness {
+	optional := make(map[shop.Service]bool)
+	for _, svc := range defaultOptional {
+		optional[svc] = true
+	}
+	if v, ok := os.LookupEnv("READINESS_OPTIONAL"); ok {
+		optional = make(map[shop.Service]bool)
+		for _, name := range strings.Split(v, ",") {
+			svc, ok := serviceNamed(strings.TrimSpace(name))
+			if !ok {
+				log.Warnf("READINESS_OPTIONAL: unknown service %q", name)
+				continue
+			}
+			optional[svc] = true
+		}
+	}
+	c := fe.client
+	deps := []dependency{
+		{svc: shop.CatalogService, probe: func(ctx context.Context) error {
+			_, err := c.Catalog.ListProducts(ctx)
+			return err
+		}},
+		{svc: shop.CurrencyService, probe: func(ctx context.Context) error {
+			_, err := c.Currency.GetSupportedCurrencies(ctx)
+			return err
+		}},
+		{svc: shop.CartService, probe: func(ctx context.Context) error {
+			_, err := c.Cart.GetCart(ctx, &shop.GetCartRequest{UserId: probeUser})
+			return err
+		}},
+		{svc: shop.ShippingService, probe: func(ctx context.Context) error {
+			_, err := c.Shipping.GetQuote(ctx, &shop.GetQuoteRequest{})
+			return err
+		}},
+		{svc: shop.CheckoutService, probe: func(ctx context.Context) error {
+			return probeReachable(ctx, fe.checkoutSvcAddr)
+		}},
+		{svc: shop.RecommendationService, probe: func(ctx context.Context) error {
+			_, err := c.Recommendations.ListRecommendations(ctx, &shop.ListRecommendationsRequest{UserId: probeUser})
+			return err
+		}},
+		{svc: shop.AdService, probe: func(ctx context.Context) error {
+			_, err := c.Ads.GetAds(ctx, &shop.AdRequest{})
+			return err
+		}},
+	}
+	for i := range deps {
+		deps[i].optional = optional[deps[i].svc]
+	}
+	return &readiness{
+		log:     log,
+		deps:    deps,
+		timeout: durationEnv(log, "READINESS_PROBE_TIMEOUT", defaultProbeTimeout),
+		ttl:     durationEnv(log, "READINESS_CACHE_TTL", defaultProbeTTL),
+	}
+}
+
+func serviceNamed(name string) (shop.Service, bool) {
+	for _, svc := range []shop.Service{shop.CatalogService, shop.CurrencyService, shop.CartService, shop.ShippingService,
+		shop.CheckoutService, shop.RecommendationService, shop.AdService} {
+		if svc.String() == name {
+			return svc, true
+		}
+	}
+	return 0, false
+}
+
+func durationEnv(log logrus.FieldLogger, key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Warnf("%s: %q is not a positive duration, using %v", key, v, def)
+		return def
+	}
+	return d
+}
+
+func intEnv(log logrus.FieldLogger, key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Warnf("%s: %q is not a positive integer, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// probeReachable checks that the function at addr answers, whatever the
+// status below 500: checkout has no operation that is safe to call.
+func probeReachable(ctx context.Context, addr string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 500 {
+		return fmt.Errorf("status %d %s", res.StatusCode, http.StatusText(res.StatusCode))
+	}
+	return nil
+}
+
+func (rd *readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := rd.check()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == statusUnavailable {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// check returns the cached report, or probes the dependencies when it is
+// older than ttl. Concurrent checks wait for the same probes.
+func (rd *readiness) check() *readinessReport {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if rd.report != nil && time.Since(rd.report.CheckedAt) < rd.ttl {
+		return rd.report
+	}
+	report := rd.probe()
+	if rd.report == nil || rd.report.Status != report.Status {
+		rd.log.WithField("dependencies", report.Dependencies).Warnf("readiness is %s", report.Status)
+	}
+	rd.report = report
+	return report
+}
+
+func (rd *readiness) probe() *readinessReport {
+	// The probes outlive a client that gave up, so that the report is
+	// cached for the next check.
+	ctx, cancel := context.WithTimeout(context.Background(), rd.timeout)
+	defer cancel()
+	statuses := make([]dependencyStatus, len(rd.deps))
+	var wg sync.WaitGroup
+	for i, dep := range rd.deps {
+		wg.Add(1)
+		go func(i int, dep dependency) {
+			defer wg.Done()
+			start := time.Now()
+			err := dep.probe(ctx)
+			st := dependencyStatus{Status: statusOK, Optional: dep.optional, LatencyMs: float64(time.Since(start)) / float64(time.Millisecond)}
+			if err != nil {
+				st.Status, st.Error = statusFailing, err.Error()
+			}
+			statuses[i] = st
+		}(i, dep)
+	}
+	wg.Wait()
+
+	report := &readinessReport{Status: statusReady, CheckedAt: time.Now(), Dependencies: make(map[string]dependencyStatus)}
+	for i, dep := range rd.deps {
+		st := statuses[i]
+		switch {
+		case st.Status == statusOK:
+		case dep.optional:
+			st.Status = statusDegraded
+			if report.Status == statusReady {
+				report.Status = statusDegraded
+			}
+		default:
+			report.Status = statusUnavailable
+		}
+		report.Dependencies[dep.svc.String()] = st
+	}
+	return report
+}
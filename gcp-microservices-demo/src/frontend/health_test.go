@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+func testReadiness(deps ...dependency) *readiness {
+	log := logrus.New()
+	log.Out = io.Discard
+	return &readiness{log: log, deps: deps, timeout: 50 * time.Millisecond, ttl: time.Hour}
+}
+
+func probeWith(err error, calls *int32) func(context.Context) error {
+	return func(ctx context.Context) error {
+		atomic.AddInt32(calls, 1)
+		return err
+	}
+}
+
+func readyz(t *testing.T, rd *readiness) (int, readinessReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	rd.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_readyz", nil))
+	var report readinessReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s: %v", rec.Body, err)
+	}
+	return rec.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	var calls int32
+	down := errors.New("down")
+	for _, tt := range []struct {
+		name          string
+		catalog, ads  error
+		code          int
+		status        string
+		catalogStatus string
+		adsStatus     string
+	}{
+		{"all up", nil, nil, http.StatusOK, statusReady, statusOK, statusOK},
+		{"ads down", nil, down, http.StatusOK, statusDegraded, statusOK, statusDegraded},
+		{"catalog down", down, down, http.StatusServiceUnavailable, statusUnavailable, statusFailing, statusDegraded},
+	} {
+		rd := testReadiness(
+			dependency{svc: shop.CatalogService, probe: probeWith(tt.catalog, &calls)},
+			dependency{svc: shop.AdService, optional: true, probe: probeWith(tt.ads, &calls)},
+		)
+		code, report := readyz(t, rd)
+		if code != tt.code || report.Status != tt.status ||
+			report.Dependencies["productcatalogservice"].Status != tt.catalogStatus ||
+			report.Dependencies["adservice"].Status != tt.adsStatus || !report.Dependencies["adservice"].Optional {
+			t.Errorf("%s: %d %+v", tt.name, code, report)
+		}
+		if tt.catalog != nil && report.Dependencies["productcatalogservice"].Error != "down" {
+			t.Errorf("%s: the error of the catalog is not reported: %+v", tt.name, report)
+		}
+	}
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	rd := testReadiness(dependency{svc: shop.CartService, probe: func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Minute):
+			return nil
+		}
+	}})
+	start := time.Now()
+	code, report := readyz(t, rd)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("a hung dependency held the check for %v", took)
+	}
+	if code != http.StatusServiceUnavailable || report.Dependencies["cartservice"].Status != statusFailing {
+		t.Errorf("%d %+v", code, report)
+	}
+}
+
+func TestReadinessCache(t *testing.T) {
+	var calls int32
+	rd := testReadiness(dependency{svc: shop.CatalogService, probe: func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			readyz(t, rd)
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("10 checks within the TTL probed %d times", calls)
+	}
+
+	rd.ttl = 0
+	readyz(t, rd)
+	if calls != 2 {
+		t.Errorf("an expired report was not probed again")
+	}
+}
+
+// TestReadinessProbes checks the probes of the frontend's dependencies
+// against stand-in functions, with the ads failing.
+func TestReadinessProbes(t *testing.T) {
+	t.Setenv("READINESS_OPTIONAL", "adservice, recommendationservice")
+	var paths sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths.Store(r.URL.Path, true)
+		switch r.URL.Path {
+		case "/ad":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/checkout":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+	fe := &frontendServer{checkoutSvcAddr: srv.URL + "/checkout", client: shop.New(shop.WithBaseURL(srv.URL))}
+	log := logrus.New()
+	log.Out = io.Discard
+	code, report := readyz(t, newReadiness(fe, log))
+	if code != http.StatusOK || report.Status != statusDegraded || len(report.Dependencies) != 7 {
+		t.Errorf("%d %+v", code, report)
+	}
+	for name, st := range report.Dependencies {
+		want := statusOK
+		if name == "adservice" {
+			want = statusDegraded
+		}
+		if st.Status != want {
+			t.Errorf("%s: %+v, want %s", name, st, want)
+		}
+	}
+	for _, path := range []string{"/product", "/currency", "/cart", "/shipping", "/checkout", "/recommendation", "/ad"} {
+		if _, ok := paths.Load(path); !ok {
+			t.Errorf("%s was not probed", path)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+const (
+	defaultPageTimeout     = 5 * time.Second
+	defaultPageConcurrency = 8
+)
+
+// loader runs the downstream calls of one page. A call starts as soon as
+// the page asks for it, so that independent calls run concurrently, at most
+// concurrency of them at a time and all within the deadline of the page. A
+// call asked for twice in a page, such as the product of a cart line that
+// is also recommended, runs once.
+//
+// The calls to the functions return futures: the page asks for everything
+// it can before waiting for any of it. Calls depending on others, such as
+// the conversion of the product prices, are made once those are back.
+type loader struct {
+	fe     *frontendServer
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]*pending
+	deg   degraded
+}
+
+// pending is a call started by a loader.
+type pending struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+
+	// ctx is the context the call runs in. It is canceled once every
+	// caller waiting for the call is done, so that a call only an optional
+	// section waits for stops at the timeout of the section and frees its
+	// slot for the rest of the page.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// callers counts the callers still waiting, under the mutex of the
+	// loader; abandoned is set once none is.
+	callers   int
+	abandoned bool
+}
+
+// newLoader returns the loader of a page served within ctx. The calls share
+// the deadline of ctx, or the page timeout of fe when it is sooner. The
+// loader must be closed once the page is rendered.
+func (fe *frontendServer) newLoader(ctx context.Context) *loader {
+	timeout, n := fe.pageTimeout, fe.pageConcurrency
+	if timeout <= 0 {
+		timeout = defaultPageTimeout
+	}
+	if n <= 0 {
+		n = defaultPageConcurrency
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return &loader{
+		fe:     fe,
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, n),
+		calls:  make(map[string]*pending),
+		deg:    make(degraded),
+	}
+}
+
+// close cancels the calls the page no longer waits for.
+func (l *loader) close() { l.cancel() }
+
+// start runs fetch for a caller waiting within ctx, unless a call with the
+// same key was started already, in which case it returns that call. The call
+// runs until every caller waiting for it is done, and at the latest until
+// the deadline of the page.
+func (l *loader) start(ctx context.Context, key string, fetch func(ctx context.Context) (interface{}, error)) *pending {
+	l.mu.Lock()
+	p, ok := l.calls[key]
+	if !ok || p.abandoned {
+		p = &pending{done: make(chan struct{})}
+		p.ctx, p.cancel = context.WithCancel(l.ctx)
+		l.calls[key] = p
+		go l.run(p, fetch)
+	}
+	p.callers++
+	l.mu.Unlock()
+	go l.release(ctx, p)
+	return p
+}
+
+func (l *loader) run(p *pending, fetch func(ctx context.Context) (interface{}, error)) {
+	defer close(p.done)
+	select {
+	case l.sem <- struct{}{}:
+		defer func() { <-l.sem }()
+	case <-p.ctx.Done():
+		p.err = p.ctx.Err()
+		return
+	}
+	p.val, p.err = fetch(p.ctx)
+}
+
+// release cancels p if ctx is done before p and no other caller waits for
+// p any more.
+func (l *loader) release(ctx context.Context, p *pending) {
+	select {
+	case <-p.done:
+		return
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p.callers--; p.callers == 0 {
+		p.abandoned = true
+		p.cancel()
+	}
+}
+
+// wait returns the result of p, or the error of ctx when it is done first.
+func (p *pending) wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-p.done:
+		return p.val, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *loader) currencies() func(ctx context.Context) ([]string, error) {
+	p := l.start(l.ctx, "currencies", func(ctx context.Context) (interface{}, error) {
+		return l.fe.getCurrencies(ctx)
+	})
+	return func(ctx context.Context) ([]string, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.([]string)
+		return out, err
+	}
+}
+
+func (l *loader) products() func(ctx context.Context) ([]*shop.Product, error) {
+	p := l.start(l.ctx, "products", func(ctx context.Context) (interface{}, error) {
+		return l.fe.getProducts(ctx)
+	})
+	return func(ctx context.Context) ([]*shop.Product, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.([]*shop.Product)
+		return out, err
+	}
+}
+
+func (l *loader) product(id string) func(ctx context.Context) (*shop.Product, error) {
+	return l.productWithin(l.ctx, id)
+}
+
+// productWithin looks up the product id for a caller waiting within ctx.
+func (l *loader) productWithin(ctx context.Context, id string) func(ctx context.Context) (*shop.Product, error) {
+	p := l.start(ctx, "product/"+id, func(ctx context.Context) (interface{}, error) {
+		return l.fe.getProduct(ctx, id)
+	})
+	return func(ctx context.Context) (*shop.Product, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.(*shop.Product)
+		return out, err
+	}
+}
+
+func (l *loader) cart(userID string) func(ctx context.Context) ([]*shop.CartItem, error) {
+	p := l.start(l.ctx, "cart/"+userID, func(ctx context.Context) (interface{}, error) {
+		return l.fe.getCart(ctx, userID)
+	})
+	return func(ctx context.Context) ([]*shop.CartItem, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.([]*shop.CartItem)
+		return out, err
+	}
+}
+
+func (l *loader) convert(m *shop.Money, currency string) func(ctx context.Context) (*shop.Money, error) {
+	return l.convertWithin(l.ctx, m, currency)
+}
+
+// convertWithin converts m for a caller waiting within ctx.
+func (l *loader) convertWithin(ctx context.Context, m *shop.Money, currency string) func(ctx context.Context) (*shop.Money, error) {
+	p := l.start(ctx, "convert/"+currency+"/"+moneyKey(m), func(ctx context.Context) (interface{}, error) {
+		return l.fe.convertCurrency(ctx, m, currency)
+	})
+	return func(ctx context.Context) (*shop.Money, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.(*shop.Money)
+		return out, err
+	}
+}
+
+// convertAll converts the amounts in a single call.
+func (l *loader) convertAll(from []*shop.Money, currency string) func(ctx context.Context) ([]*shop.Money, error) {
+	keys := make([]string, len(from))
+	for i, m := range from {
+		keys[i] = moneyKey(m)
+	}
+	p := l.start(l.ctx, "convertAll/"+currency+"/"+strings.Join(keys, ","), func(ctx context.Context) (interface{}, error) {
+		return l.fe.convertCurrencies(ctx, from, currency)
+	})
+	return func(ctx context.Context) ([]*shop.Money, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.([]*shop.Money)
+		return out, err
+	}
+}
+
+func moneyKey(m *shop.Money) string {
+	return fmt.Sprintf("%s:%d.%09d", m.GetCurrencyCode(), m.GetUnits(), m.GetNanos())
+}
+
+// recommendations returns the products recommended to userID, looked up
+// concurrently.
+func (l *loader) recommendations(ctx context.Context, userID string, productIDs []string) ([]*shop.Product, error) {
+	p := l.start(ctx, "recommendations/"+userID+"/"+strings.Join(productIDs, ","), func(ctx context.Context) (interface{}, error) {
+		return l.fe.client.Recommendations.ListRecommendations(ctx, &shop.ListRecommendationsRequest{UserId: userID, ProductIds: productIDs})
+	})
+	v, err := p.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := v.(*shop.ListRecommendationsResponse).GetProductIds()
+	if len(ids) > 4 {
+		ids = ids[:4] // take only first four to fit the UI
+	}
+	waits := make([]func(context.Context) (*shop.Product, error), len(ids))
+	for i, id := range ids {
+		waits[i] = l.productWithin(ctx, id)
+	}
+	out := make([]*shop.Product, len(ids))
+	for i, wait := range waits {
+		if out[i], err = wait(ctx); err != nil {
+			return nil, errors.Wrapf(err, "failed to get recommended product info (#%s)", ids[i])
+		}
+	}
+	return out, nil
+}
+
+// shippingQuote returns the cost of shipping items, converted to currency.
+func (l *loader) shippingQuote(ctx context.Context, items []*shop.CartItem, currency string) (*shop.Money, error) {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = fmt.Sprintf("%s*%d", item.GetProductId(), item.GetQuantity())
+	}
+	p := l.start(ctx, "quote/"+strings.Join(keys, ","), func(ctx context.Context) (interface{}, error) {
+		return l.fe.client.Shipping.GetQuote(ctx, &shop.GetQuoteRequest{Items: items})
+	})
+	v, err := p.wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	localized, err := l.convertWithin(ctx, v.(*shop.GetQuoteResponse).GetCostUsd(), currency)(ctx)
+	return localized, errors.Wrap(err, "failed to convert currency for shipping cost")
+}
+
+// optional starts fetching an optional section of the page, as
+// fetchOptional does, and returns a function waiting for it once, which
+// tells whether the section was fetched.
+func (l *loader) optional(log logrus.FieldLogger, section string, fetch func(ctx context.Context) error) func() bool {
+	done := make(chan bool, 1)
+	go func() {
+		d := make(degraded)
+		ok := fetchOptional(l.ctx, log, d, section, fetch)
+		if !ok {
+			l.mu.Lock()
+			l.deg[section] = true
+			l.mu.Unlock()
+		}
+		done <- ok
+	}()
+	return func() bool { return <-done }
+}
+
+// chooseAd starts fetching the ads for ctxKeys, and returns a function
+// waiting for them once, which chooses one at random. The ad is optional:
+// when it cannot be retrieved, or there is none, the function returns nil.
+func (l *loader) chooseAd(log logrus.FieldLogger, ctxKeys []string) func() *shop.Ad {
+	var ads []*shop.Ad
+	wait := l.optional(log, sectionAd, func(ctx context.Context) error {
+		p := l.start(ctx, "ads/"+strings.Join(ctxKeys, ","), func(ctx context.Context) (interface{}, error) {
+			return l.fe.getAd(ctx, ctxKeys)
+		})
+		v, err := p.wait(ctx)
+		ads, _ = v.([]*shop.Ad)
+		return err
+	})
+	return func() *shop.Ad {
+		if !wait() || len(ads) == 0 {
+			return nil
+		}
+		return ads[rand.Intn(len(ads))]
+	}
+}
+
+// degraded returns the optional sections that could not be fetched. The
+// page calls it once it waited for all of them.
+func (l *loader) degraded() degraded {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d := make(degraded, len(l.deg))
+	for k, v := range l.deg {
+		d[k] = v
+	}
+	return d
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// delayed answers body after d.
+func delayed(d time.Duration, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(d)
+		w.Write([]byte(body))
+	}
+}
+
+// TestPageLatency checks that a page takes about as long as its slowest
+// chain of dependent calls, rather than the sum of its calls.
+func TestPageLatency(t *testing.T) {
+	const d = 100 * time.Millisecond
+	product := `{"id":"OLJCESPC7Z","categories":["clothing"],"price_usd":{"currency_code":"USD","units":19}}`
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/product":        delayed(d, product),
+		"/currency":       delayed(d, `{"currency_code":"USD","units":19}`),
+		"/currency/batch": delayed(d, `{"results":[{"currency_code":"USD","units":19}]}`),
+		"/cart":           delayed(d, `{"items":[{"product_id":"OLJCESPC7Z","quantity":1}]}`),
+		"/recommendation": delayed(d, `{"product_ids":["OLJCESPC7Z"]}`),
+		"/ad":             delayed(d, `{"ads":[{"redirect_url":"/product/OLJCESPC7Z","text":"Ad"}]}`),
+		"/shipping":       delayed(d, `{"cost_usd":{"currency_code":"USD","units":8}}`),
+	})
+	tests := []struct {
+		route string
+		h     http.HandlerFunc
+		req   *http.Request
+		// chain is the longest chain of dependent calls of the page.
+		chain, calls int
+	}{
+		{"/", fe.homeHandler, httptest.NewRequest(http.MethodGet, "/", nil), 2, 5},
+		{"/product/{id}", fe.productHandler, mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/product/OLJCESPC7Z", nil), map[string]string{"id": "OLJCESPC7Z"}), 2, 6},
+		{"/cart", fe.viewCartHandler, httptest.NewRequest(http.MethodGet, "/cart", nil), 3, 7},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		rec := servePage(tt.h, tt.req, tt.route)
+		took := time.Since(start)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d:\n%s", tt.route, rec.Code, rec.Body)
+		}
+		if max := time.Duration(tt.chain)*d + d/2; took > max {
+			t.Errorf("%s took %v, want at most %v (the calls in sequence take %v)", tt.route, took, max, time.Duration(tt.calls)*d)
+		}
+	}
+}
+
+// counting serves every function, counting the calls by path and query and
+// the calls in flight at once.
+type counting struct {
+	mu             sync.Mutex
+	calls          map[string]int
+	inFlight, peak int
+}
+
+func (c *counting) handler(body func(r *http.Request) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		c.calls[r.URL.Path+"?"+r.URL.RawQuery]++
+		if c.inFlight++; c.inFlight > c.peak {
+			c.peak = c.inFlight
+		}
+		c.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(body(r)))
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}
+}
+
+func TestLoaderDedup(t *testing.T) {
+	c := &counting{calls: make(map[string]int)}
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/product": c.handler(func(r *http.Request) string {
+			return fmt.Sprintf(`{"id":%q,"price_usd":{"currency_code":"USD","units":1}}`, r.URL.Query().Get("id"))
+		}),
+		"/cart": c.handler(func(*http.Request) string {
+			return `{"items":[{"product_id":"A","quantity":1},{"product_id":"B","quantity":1}]}`
+		}),
+		// The recommendations include a product of the cart.
+		"/recommendation": c.handler(func(*http.Request) string { return `{"product_ids":["B","C"]}` }),
+		"/currency/batch": c.handler(func(*http.Request) string {
+			return `{"results":[{"currency_code":"USD","units":1},{"currency_code":"USD","units":1}]}`
+		}),
+		"/currency": c.handler(func(*http.Request) string { return `{"currency_code":"USD","units":8}` }),
+	})
+	rec := servePage(fe.viewCartHandler, httptest.NewRequest(http.MethodGet, "/cart", nil), "/cart")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d:\n%s", rec.Code, rec.Body)
+	}
+	for _, id := range []string{"A", "B", "C"} {
+		if n := c.calls["/product?id="+id]; n != 1 {
+			t.Errorf("product %s fetched %d times, want once", id, n)
+		}
+	}
+}
+
+func TestLoaderConcurrency(t *testing.T) {
+	c := &counting{calls: make(map[string]int)}
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/product": c.handler(func(*http.Request) string { return `{}` }),
+	})
+	fe.pageConcurrency = 3
+	l := fe.newLoader(context.Background())
+	defer l.close()
+	var waits []func(context.Context) error
+	for i := 0; i < 12; i++ {
+		load := l.product(fmt.Sprint(i))
+		waits = append(waits, func(ctx context.Context) error { _, err := load(ctx); return err })
+	}
+	for _, wait := range waits {
+		if err := wait(l.ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.peak != 3 {
+		t.Errorf("%d calls in flight at most, want 3", c.peak)
+	}
+}
+
+func TestLoaderDeadline(t *testing.T) {
+	fe := standIns(t, map[string]http.HandlerFunc{"/product": slow})
+	fe.pageTimeout = 100 * time.Millisecond
+	l := fe.newLoader(context.Background())
+	defer l.close()
+	start := time.Now()
+	if _, err := l.product("A")(l.ctx); err == nil {
+		t.Error("a call outliving the page deadline succeeded")
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("the call took %v past the page deadline", took)
+	}
+}
+
+// TestLoaderSectionTimeout checks that a call only an optional section
+// waits for stops at the timeout of the section, freeing its slot, while a
+// call the page also waits for keeps running.
+func TestLoaderSectionTimeout(t *testing.T) {
+	fe := standIns(t, map[string]http.HandlerFunc{
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("id") == "slow" {
+				slow(w, r)
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte(`{"id":"` + r.URL.Query().Get("id") + `"}`))
+		},
+	})
+	fe.pageConcurrency = 1
+	fe.pageTimeout = 5 * time.Second
+	l := fe.newLoader(context.Background())
+	defer l.close()
+
+	section, cancel := context.WithTimeout(l.ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := l.productWithin(section, "slow")(section); err == nil {
+		t.Fatal("a call outliving the section timeout succeeded")
+	}
+	start := time.Now()
+	if _, err := l.product("A")(l.ctx); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("the next call waited %v for the slot of the abandoned one", took)
+	}
+
+	section, cancel = context.WithTimeout(l.ctx, 50*time.Millisecond)
+	defer cancel()
+	shared := l.productWithin(section, "B")
+	page := l.product("B")
+	if _, err := shared(section); err == nil {
+		t.Error("the section waited past its timeout")
+	}
+	if p, err := page(l.ctx); err != nil || p.GetId() != "B" {
+		t.Errorf("the call the page waits for = %+v, %v", p, err)
+	}
+}
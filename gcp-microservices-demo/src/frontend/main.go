@@ -15,15 +15,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
-	"go.opencensus.io/plugin/ochttp"
-	"go.opencensus.io/plugin/ochttp/propagation/b3"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/account"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/session"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fault"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
 const (
@@ -46,7 +51,10 @@ var (
 		"TRY": true}
 )
 
+// ctxKeySessionID is the key of the user ID of the session, which the cart
+// and the orders are kept under, and ctxKeySession that of the session.
 type ctxKeySessionID struct{}
+type ctxKeySession struct{}
 
 type frontendServer struct {
 	productCatalogSvcAddr string
@@ -56,20 +64,21 @@ type frontendServer struct {
 	checkoutSvcAddr       string
 	shippingSvcAddr       string
 	adSvcAddr             string
+
+	client   *shop.Client
+	sessions *session.Manager
+	accounts account.Store
+
+	// pageTimeout and pageConcurrency bound the downstream calls of a page,
+	// see loader.
+	pageTimeout     time.Duration
+	pageConcurrency int
 }
 
 func main() {
-	log := logrus.New()
-	log.Level = logrus.DebugLevel
-	log.Formatter = &logrus.JSONFormatter{
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "severity",
-			logrus.FieldKeyMsg:   "message",
-		},
-		TimestampFormat: time.RFC3339Nano,
+	if _, err := telemetry.Setup(context.Background(), telemetry.FromEnv("frontend")); err != nil {
+		log.Fatal(err)
 	}
-	log.Out = os.Stdout
 
 	srvPort := port
 	if os.Getenv("PORT") != "" {
@@ -84,31 +93,93 @@ func main() {
 	mustMapEnv(&svc.checkoutSvcAddr, "CHECKOUT_SERVICE_ADDR")
 	mustMapEnv(&svc.shippingSvcAddr, "SHIPPING_SERVICE_ADDR")
 	mustMapEnv(&svc.adSvcAddr, "AD_SERVICE_ADDR")
+	svc.pageTimeout = durationEnv(log, "PAGE_TIMEOUT", defaultPageTimeout)
+	svc.pageConcurrency = intEnv(log, "PAGE_CONCURRENCY", defaultPageConcurrency)
+	transportOpts, err := svc.transportOptions(os.Getenv("SHOP_TRANSPORT"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if svc.sessions, err = sessionsFromEnv(log); err != nil {
+		log.Fatal(err)
+	}
+	if svc.accounts, err = accountsFromEnv(); err != nil {
+		log.Fatal(err)
+	}
+	faults, err := fault.FromEnv(fault.Client, log)
+	if err != nil {
+		log.Warnf("fault injection: %v", err)
+	}
+	svc.client = shop.New(append([]shop.Option{
+		shop.WithServiceURL(shop.CatalogService, svc.productCatalogSvcAddr),
+		shop.WithServiceURL(shop.CurrencyService, svc.currencySvcAddr),
+		shop.WithServiceURL(shop.CartService, svc.cartSvcAddr),
+		shop.WithServiceURL(shop.RecommendationService, svc.recommendationSvcAddr),
+		shop.WithServiceURL(shop.CheckoutService, svc.checkoutSvcAddr),
+		shop.WithServiceURL(shop.ShippingService, svc.shippingSvcAddr),
+		shop.WithServiceURL(shop.AdService, svc.adSvcAddr),
+		shop.WithFaults(faults),
+	}, transportOpts...)...)
 
 	r := mux.NewRouter()
+	r.Use(nameRoute)
 	r.HandleFunc("/", svc.homeHandler).Methods(http.MethodGet, http.MethodHead)
 	r.HandleFunc("/product/{id}", svc.productHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/search", svc.searchHandler).Methods(http.MethodGet, http.MethodHead)
 	r.HandleFunc("/cart", svc.viewCartHandler).Methods(http.MethodGet, http.MethodHead)
 	r.HandleFunc("/cart", svc.addToCartHandler).Methods(http.MethodPost)
+	r.HandleFunc("/cart/update", svc.updateCartHandler).Methods(http.MethodPost)
+	r.HandleFunc("/cart/remove", svc.removeFromCartHandler).Methods(http.MethodPost)
 	r.HandleFunc("/cart/empty", svc.emptyCartHandler).Methods(http.MethodPost)
 	r.HandleFunc("/setCurrency", svc.setCurrencyHandler).Methods(http.MethodPost)
-	r.HandleFunc("/logout", svc.logoutHandler).Methods(http.MethodGet)
+	r.HandleFunc("/login", svc.loginHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/login", svc.loginPostHandler).Methods(http.MethodPost)
+	r.HandleFunc("/register", svc.registerHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/register", svc.registerPostHandler).Methods(http.MethodPost)
+	r.HandleFunc("/logout", svc.logoutHandler).Methods(http.MethodPost)
 	r.HandleFunc("/cart/checkout", svc.placeOrderHandler).Methods(http.MethodPost)
+	r.HandleFunc("/orders", svc.ordersHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/orders/{id}", svc.orderHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/track/{id}", svc.trackHandler).Methods(http.MethodGet, http.MethodHead)
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
 	r.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, "User-agent: *\nDisallow: /") })
+	// Liveness only tells that the process serves; readiness probes the
+	// functions.
 	r.HandleFunc("/_healthz", func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, "ok") })
+	r.Handle("/_readyz", newReadiness(svc, log)).Methods(http.MethodGet, http.MethodHead)
+	r.Handle("/metrics", telemetry.MetricsHandler()).Methods(http.MethodGet)
+	r.Handle("/admin/loglevel", log.AdminHandler()).Methods(http.MethodPut)
+	r.Handle("/admin/faults", faults.AdminHandler()).Methods(http.MethodGet, http.MethodPut)
 
 	var handler http.Handler = r
+	handler = svc.checkCSRF(handler)               // reject cross-site posts
 	handler = &logHandler{log: log, next: handler} // add logging
-	handler = ensureSessionID(handler)             // add session ID
-	handler = &ochttp.Handler{                     // add opencensus instrumentation
-		Handler:     handler,
-		Propagation: &b3.HTTPFormat{}}
+	handler = svc.ensureSession(handler)           // add session
+	handler = tracingHandler(handler)              // add opentelemetry instrumentation
 
 	log.Infof("starting server on " + addr + ":" + srvPort)
 	log.Fatal(http.ListenAndServe(addr+":"+srvPort, handler))
 }
 
+// transportOptions selects how the Go functions are reached: over
+// HTTP/JSON (transport "" or "http"), over Connect at their usual addresses
+// ("connect"), or over gRPC at the targets in the *_GRPC_ADDR variables
+// ("grpc"). The other functions are always called over HTTP/JSON.
+func (fe *frontendServer) transportOptions(transport string) ([]shop.Option, error) {
+	addrs := map[shop.Service]string{
+		shop.CatalogService:  fe.productCatalogSvcAddr,
+		shop.ShippingService: fe.shippingSvcAddr,
+		shop.CheckoutService: fe.checkoutSvcAddr,
+		shop.AdService:       fe.adSvcAddr,
+	}
+	if transport == rpc.GRPC {
+		addrs[shop.CatalogService] = os.Getenv("PRODUCT_CATALOG_SERVICE_GRPC_ADDR")
+		addrs[shop.ShippingService] = os.Getenv("SHIPPING_SERVICE_GRPC_ADDR")
+		addrs[shop.CheckoutService] = os.Getenv("CHECKOUT_SERVICE_GRPC_ADDR")
+		addrs[shop.AdService] = os.Getenv("AD_SERVICE_GRPC_ADDR")
+	}
+	return rpc.Options(transport, addrs)
+}
+
 func mustMapEnv(target *string, envKey string) {
 	v := os.Getenv(envKey)
 	if v == "" {
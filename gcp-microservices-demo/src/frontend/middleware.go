@@ -17,17 +17,28 @@ package main
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
 type ctxKeyLog struct{}
 type ctxKeyRequestID struct{}
+type ctxKeyRoute struct{}
 
 type logHandler struct {
-	log  *logrus.Logger
+	log  *logging.Logger
 	next http.Handler
 }
 
@@ -55,51 +66,136 @@ func (r *responseRecorder) WriteHeader(statusCode int) {
 
 func (lh *logHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	requestID, _ := uuid.NewRandom()
-	ctx = context.WithValue(ctx, ctxKeyRequestID{}, requestID.String())
+	requestID := r.Header.Get(shop.RequestIDHeader)
+	if !validRequestID(requestID) {
+		u, _ := uuid.NewRandom()
+		requestID = u.String()
+	}
+	ctx = context.WithValue(ctx, ctxKeyRequestID{}, requestID)
+	// Every call to the functions made while serving r carries its ID, and
+	// the trace context of the span tracingHandler started.
+	ctx = shop.NewContext(ctx, shop.Metadata{RequestID: requestID})
+	w.Header().Set(shop.RequestIDHeader, requestID)
 
 	start := time.Now()
 	rr := &responseRecorder{w: w}
 	log := lh.log.WithFields(logrus.Fields{
-		"http.req.path":   r.URL.Path,
-		"http.req.method": r.Method,
-		"http.req.id":     requestID.String(),
+		"http.req.path":        r.URL.Path,
+		"http.req.method":      r.Method,
+		fission.RequestIDField: requestID,
 	})
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		log = log.WithField(fission.TraceIDField, sc.TraceID().String())
+	}
 	if v, ok := r.Context().Value(ctxKeySessionID{}).(string); ok {
 		log = log.WithField("session", v)
 	}
-	log.Debug("request started")
+	log.Trace("request started")
+	// nameRoute sets the route once mux has matched the request.
+	route := "unmatched"
 	defer func() {
+		took := time.Since(start)
+		recordRequest(ctx, route, r.Method, rr.status, took)
+		// Failures are always logged; the others as sampled for their route.
+		if rr.status < 500 && !lh.log.Sampled(route, requestID) {
+			return
+		}
 		log.WithFields(logrus.Fields{
-			"http.resp.took_ms": int64(time.Since(start) / time.Millisecond),
+			"http.resp.took_ms": int64(took / time.Millisecond),
 			"http.resp.status":  rr.status,
-			"http.resp.bytes":   rr.b}).Debugf("request complete")
+			"http.resp.bytes":   rr.b}).Info("request complete")
 	}()
 
+	ctx = context.WithValue(ctx, ctxKeyRoute{}, &route)
 	ctx = context.WithValue(ctx, ctxKeyLog{}, log)
 	r = r.WithContext(ctx)
 	lh.next.ServeHTTP(rr, r)
 }
 
-func ensureSessionID(next http.Handler) http.HandlerFunc {
+// recordRequest records the rate, errors and duration of a request to route
+// answered with status.
+func recordRequest(ctx context.Context, route, method string, status int, took time.Duration) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	attrs := []attribute.KeyValue{
+		telemetry.RouteKey.String(route),
+		telemetry.MethodKey.String(method),
+		telemetry.StatusKey.Int(status),
+	}
+	telemetry.Count(ctx, telemetry.FrontendRequests, 1, attrs...)
+	if status >= 500 {
+		telemetry.Count(ctx, telemetry.FrontendErrors, 1, attrs...)
+	}
+	telemetry.Duration(ctx, telemetry.FrontendDuration, float64(took)/float64(time.Millisecond), attrs...)
+}
+
+// validRequestID reports whether id, sent by the client, may be adopted as
+// the request ID: it must be short and made of letters, digits, '-' and '_'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// tracingHandler starts a server span for every request, continuing the
+// trace context sent by the client.
+func tracingHandler(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "frontend", otelhttp.WithPropagators(telemetry.Propagator()))
+}
+
+// nameRoute names the span and the metrics of a request after its route,
+// as in "GET /product/{id}".
+func nameRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tmpl := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if t, err := route.GetPathTemplate(); err == nil {
+				tmpl = t
+			}
+		}
+		trace.SpanFromContext(r.Context()).SetName(r.Method + " " + tmpl)
+		if route, ok := r.Context().Value(ctxKeyRoute{}).(*string); ok {
+			*route = tmpl
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionless reports the requests that need no session: probes, metrics,
+// administration and static files, which would otherwise start a session
+// on every call.
+func sessionless(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/_healthz", "/_readyz", "/metrics", "/robots.txt":
+		return true
+	}
+	return strings.HasPrefix(r.URL.Path, "/static/") || strings.HasPrefix(r.URL.Path, "/admin/")
+}
+
+// ensureSession loads the session of the request, or starts one, and adds
+// it and its user ID to the context. Without a session store the request
+// fails, rather than losing the cart.
+func (fe *frontendServer) ensureSession(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var sessionID string
-		c, err := r.Cookie(cookieSessionID)
-		if err == http.ErrNoCookie {
-			u, _ := uuid.NewRandom()
-			sessionID = u.String()
-			http.SetCookie(w, &http.Cookie{
-				Name:   cookieSessionID,
-				Value:  sessionID,
-				MaxAge: cookieMaxAge,
-			})
-		} else if err != nil {
+		if sessionless(r) {
+			next.ServeHTTP(w, r)
 			return
-		} else {
-			sessionID = c.Value
 		}
-		ctx := context.WithValue(r.Context(), ctxKeySessionID{}, sessionID)
-		r = r.WithContext(ctx)
-		next.ServeHTTP(w, r)
+		s, err := fe.sessions.Load(w, r)
+		if err != nil {
+			log.WithField("error", err).Error("could not load session")
+			http.Error(w, "sessions are unavailable, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		ctx := context.WithValue(r.Context(), ctxKeySession{}, s)
+		ctx = context.WithValue(ctx, ctxKeySessionID{}, s.UserID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
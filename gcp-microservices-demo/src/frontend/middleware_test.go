@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry/telemetrytest"
+)
+
+func jsonLogger(buf *bytes.Buffer) *logging.Logger {
+	return logging.New(logging.Config{Level: logrus.DebugLevel, Out: buf})
+}
+
+// logged returns the values of field in the JSON log entries in buf.
+func logged(t *testing.T, buf *bytes.Buffer, field string) map[string]bool {
+	t.Helper()
+	values := make(map[string]bool)
+	sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for sc.Scan() {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			t.Fatalf("log entry %s: %v", sc.Bytes(), err)
+		}
+		if v, ok := entry[field].(string); ok {
+			values[v] = true
+		}
+	}
+	return values
+}
+
+// TestRequestIDPropagation places an order and checks that the frontend,
+// checkout and catalog log it under the same request and trace IDs, in a
+// single trace. Checkout and the catalog are stand-ins built on the
+// fission.Router the Go functions use.
+func TestRequestIDPropagation(t *testing.T) {
+	spans := telemetrytest.New(t)
+	var frontendLog, checkoutLog, catalogLog bytes.Buffer
+
+	downstream := http.NewServeMux()
+	srv := httptest.NewServer(downstream)
+	defer srv.Close()
+	client := shop.New(shop.WithBaseURL(srv.URL))
+
+	downstream.Handle("/checkout", fission.NewRouter(shop.CheckoutService, jsonLogger(&checkoutLog), fission.Route{
+		Name:   "PlaceOrder",
+		Method: http.MethodPost,
+		New:    func() interface{} { return new(shop.PlaceOrderRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			fission.Logger(ctx).Info("placing order")
+			p, err := client.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: "OLJCESPC7Z"})
+			if err != nil {
+				return nil, err
+			}
+			return &shop.PlaceOrderResponse{Order: &shop.OrderResult{
+				OrderId:      "order-1",
+				ShippingCost: &shop.Money{CurrencyCode: "USD"},
+				Items:        []*shop.OrderItem{{Item: &shop.CartItem{ProductId: p.GetId(), Quantity: 1}, Cost: p.GetPriceUsd()}},
+			}}, nil
+		},
+	}))
+	downstream.Handle("/product", fission.NewRouter(shop.CatalogService, jsonLogger(&catalogLog), fission.Route{
+		Name:   "GetProduct",
+		Method: http.MethodGet,
+		Params: []string{"id"},
+		New:    func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return &shop.Product{Id: in.(*shop.GetProductRequest).Id, PriceUsd: &shop.Money{CurrencyCode: "USD", Units: 19}}, nil
+		},
+	}))
+	downstream.HandleFunc("/currency", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"currency_codes":["USD"]}`))
+	})
+	downstream.HandleFunc("/recommendation", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"product_ids":[]}`))
+	})
+
+	fe := &frontendServer{client: client, sessions: testSessions()}
+	r := mux.NewRouter()
+	r.Use(nameRoute)
+	r.HandleFunc("/cart/checkout", fe.placeOrderHandler).Methods(http.MethodPost)
+	h := tracingHandler(fe.ensureSession(&logHandler{log: jsonLogger(&frontendLog), next: r}))
+
+	rec := httptest.NewRecorder()
+	form := url.Values{"email": {"someone@example.com"}, "credit_card_number": {"4432801561520454"}}
+	req := httptest.NewRequest(http.MethodPost, "/cart/checkout", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-B3-TraceId", "463ac35c9f6413ad48485a3953bb6124")
+	req.Header.Set("X-B3-SpanId", "a2fb4a1d1a96d312")
+	req.Header.Set("X-B3-Sampled", "1")
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("placing the order: %d %s", rec.Code, rec.Body)
+	}
+
+	id := rec.Header().Get(shop.RequestIDHeader)
+	if id == "" {
+		t.Fatal("no X-Request-ID in the response")
+	}
+	for name, buf := range map[string]*bytes.Buffer{"frontend": &frontendLog, "checkout": &checkoutLog, "catalog": &catalogLog} {
+		if !logged(t, buf, fission.RequestIDField)[id] {
+			t.Errorf("%s did not log request ID %s:\n%s", name, id, buf)
+		}
+		if !logged(t, buf, fission.TraceIDField)["463ac35c9f6413ad48485a3953bb6124"] {
+			t.Errorf("%s did not log the trace ID:\n%s", name, buf)
+		}
+	}
+	for _, s := range spans.Spans() {
+		if got := s.SpanContext().TraceID().String(); got != "463ac35c9f6413ad48485a3953bb6124" {
+			t.Errorf("span %s in trace %s", s.Name(), got)
+		}
+	}
+	want := `POST /cart/checkout (server)
+  checkoutservice/PlaceOrder (client)
+    checkoutservice/PlaceOrder (server)
+      productcatalogservice/GetProduct (client)
+        productcatalogservice/GetProduct (server)
+  recommendationservice/ListRecommendations (client)
+  currencyservice/GetSupportedCurrencies (client)
+  template order (internal)
+`
+	if got := spans.Tree(); got != want {
+		t.Errorf("spans:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRequestIDFromClient(t *testing.T) {
+	for id, adopted := range map[string]bool{
+		"3f6c1b2a-0d4e-4a4b-9c7d-2f1e0a9b8c7d": true,
+		"req_1":                                true,
+		"":                                     false,
+		"<script>":                             false,
+		strings.Repeat("a", 129):               false,
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(shop.RequestIDHeader, id)
+		var buf bytes.Buffer
+		(&logHandler{log: jsonLogger(&buf), next: http.NotFoundHandler()}).ServeHTTP(rec, req)
+		if got := rec.Header().Get(shop.RequestIDHeader); (got == id) != adopted || got == "" {
+			t.Errorf("X-Request-ID %q answered with %q", id, got)
+		}
+	}
+}
+
+// TestRequestMetrics checks that requests are counted by route and status.
+func TestRequestMetrics(t *testing.T) {
+	rec := telemetrytest.New(t)
+	r := mux.NewRouter()
+	r.Use(nameRoute)
+	r.HandleFunc("/product/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	var buf bytes.Buffer
+	h := &logHandler{log: jsonLogger(&buf), next: r}
+	for _, path := range []string{"/product/OLJCESPC7Z", "/product/66VCHSJNUP", "/product/broken", "/unknown"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	for _, tt := range []struct {
+		metric string
+		route  string
+		status int
+		want   int64
+	}{
+		{telemetry.FrontendRequests, "/product/{id}", http.StatusOK, 2},
+		{telemetry.FrontendRequests, "/product/{id}", http.StatusInternalServerError, 1},
+		{telemetry.FrontendErrors, "/product/{id}", http.StatusInternalServerError, 1},
+		{telemetry.FrontendRequests, "unmatched", http.StatusNotFound, 1},
+	} {
+		m := rec.Metric(t, tt.metric, telemetry.RouteKey.String(tt.route), telemetry.MethodKey.String(http.MethodGet), telemetry.StatusKey.Int(tt.status))
+		if got := m.Sum.AsInt64(); got != tt.want {
+			t.Errorf("%s{%s %d} = %d, want %d", tt.metric, tt.route, tt.status, got, tt.want)
+		}
+	}
+	d := rec.Metric(t, telemetry.FrontendDuration, telemetry.RouteKey.String("/product/{id}"), telemetry.StatusKey.Int(http.StatusOK))
+	if d.Count != 2 {
+		t.Errorf("%s count = %d, want 2", telemetry.FrontendDuration, d.Count)
+	}
+}
+
+// TestAccessLogSampling checks that the access logs of a route sampled out
+// are dropped, but not those of its failures.
+func TestAccessLogSampling(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(nameRoute)
+	r.HandleFunc("/product/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	var buf bytes.Buffer
+	log := logging.New(logging.Config{Level: logrus.InfoLevel, Out: &buf, Sampling: map[string]float64{"/product/{id}": 0}})
+	h := &logHandler{log: log, next: r}
+	for _, path := range []string{"/product/OLJCESPC7Z", "/product/broken", "/unknown"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	paths := logged(t, &buf, "http.req.path")
+	if paths["/product/OLJCESPC7Z"] || !paths["/product/broken"] || !paths["/unknown"] {
+		t.Errorf("logged the requests to %v", paths)
+	}
+}
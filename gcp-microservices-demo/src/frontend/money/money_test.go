@@ -19,18 +19,18 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/rest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
 )
 
-func mmc(u int64, n int32, c string) rest.Money {
-	return rest.Money{Units: u, Nanos: n, CurrencyCode: c}
+func mmc(u int64, n int32, c string) shop.Money {
+	return shop.Money{Units: u, Nanos: n, CurrencyCode: c}
 }
-func mm(u int64, n int32) rest.Money { return mmc(u, n, "") }
+func mm(u int64, n int32) shop.Money { return mmc(u, n, "") }
 
 func TestIsValid(t *testing.T) {
 	tests := []struct {
 		name string
-		in   rest.Money
+		in   shop.Money
 		want bool
 	}{
 		{"valid -/-", mm(-981273891273, -999999999), true},
@@ -54,7 +54,7 @@ func TestIsValid(t *testing.T) {
 func TestIsZero(t *testing.T) {
 	tests := []struct {
 		name string
-		in   rest.Money
+		in   shop.Money
 		want bool
 	}{
 		{"zero", mm(0, 0), true},
@@ -75,7 +75,7 @@ func TestIsZero(t *testing.T) {
 func TestIsPositive(t *testing.T) {
 	tests := []struct {
 		name string
-		in   rest.Money
+		in   shop.Money
 		want bool
 	}{
 		{"zero", mm(0, 0), false},
@@ -96,7 +96,7 @@ func TestIsPositive(t *testing.T) {
 func TestIsNegative(t *testing.T) {
 	tests := []struct {
 		name string
-		in   rest.Money
+		in   shop.Money
 		want bool
 	}{
 		{"zero", mm(0, 0), false},
@@ -116,8 +116,8 @@ func TestIsNegative(t *testing.T) {
 
 func TestAreSameCurrency(t *testing.T) {
 	type args struct {
-		l rest.Money
-		r rest.Money
+		l shop.Money
+		r shop.Money
 	}
 	tests := []struct {
 		name string
@@ -141,8 +141,8 @@ func TestAreSameCurrency(t *testing.T) {
 
 func TestAreEquals(t *testing.T) {
 	type args struct {
-		l rest.Money
-		r rest.Money
+		l shop.Money
+		r shop.Money
 	}
 	tests := []struct {
 		name string
@@ -167,8 +167,8 @@ func TestAreEquals(t *testing.T) {
 func TestNegate(t *testing.T) {
 	tests := []struct {
 		name string
-		in   rest.Money
-		want rest.Money
+		in   shop.Money
+		want shop.Money
 	}{
 		{"zero", mm(0, 0), mm(0, 0)},
 		{"negative", mm(-1, -200), mm(1, 200)},
@@ -203,13 +203,13 @@ func TestMust_panic(t *testing.T) {
 
 func TestSum(t *testing.T) {
 	type args struct {
-		l rest.Money
-		r rest.Money
+		l shop.Money
+		r shop.Money
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    rest.Money
+		want    shop.Money
 		wantErr error
 	}{
 		{"0+0=0", args{mm(0, 0), mm(0, 0)}, mm(0, 0), nil},
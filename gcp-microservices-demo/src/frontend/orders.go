@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/money"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// ordersPageSize is the number of orders listed on each page of /orders.
+const ordersPageSize = 10
+
+// orders returns a page of size orders of userID, newest first, starting
+// at the page token.
+func (l *loader) orders(userID, token string, size int32) func(ctx context.Context) (*shop.ListOrdersResponse, error) {
+	p := l.start(l.ctx, fmt.Sprintf("orders/%s/%s/%d", userID, token, size), func(ctx context.Context) (interface{}, error) {
+		return l.fe.client.Checkout.ListOrders(ctx, &shop.ListOrdersRequest{UserId: userID, PageSize: size, PageToken: token})
+	})
+	return func(ctx context.Context) (*shop.ListOrdersResponse, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.(*shop.ListOrdersResponse)
+		return out, err
+	}
+}
+
+// order returns the order orderID of userID. The orders of other users are
+// not found.
+func (l *loader) order(userID, orderID string) func(ctx context.Context) (*shop.Order, error) {
+	p := l.start(l.ctx, "order/"+userID+"/"+orderID, func(ctx context.Context) (interface{}, error) {
+		return l.fe.client.Checkout.GetOrder(ctx, &shop.GetOrderRequest{UserId: userID, OrderId: orderID})
+	})
+	return func(ctx context.Context) (*shop.Order, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.(*shop.Order)
+		return out, err
+	}
+}
+
+// placedAt formats when an order was placed, or answers "" if checkout did
+// not say.
+func placedAt(o *shop.Order) string {
+	t, err := time.Parse(time.RFC3339, o.GetPlacedAt())
+	if err != nil {
+		return ""
+	}
+	return t.Format("January 2, 2006 15:04 MST")
+}
+
+type orderSummaryView struct {
+	ID       string
+	PlacedAt string
+	Items    int32
+	Total    *shop.Money
+	Tracking string
+}
+
+// ordersHandler lists the orders placed in the session, newest first. The
+// page parameter is the token of the page, as checkoutservice answered it.
+func (fe *frontendServer) ordersHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	token := r.FormValue("page")
+	log.WithField("page", token).Debug("listing orders")
+
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadCurrencies, loadCart := l.currencies(), l.cart(sessionID(r))
+
+	page, err := l.orders(sessionID(r), token, ordersPageSize)(l.ctx)
+	if shop.IsStatus(err, http.StatusBadRequest) {
+		// checkoutservice rejected the page token of the URL.
+		renderHTTPError(log, r, w, errors.Wrap(err, "invalid page"), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve orders"), http.StatusInternalServerError)
+		return
+	}
+	currencies, err := loadCurrencies(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := loadCart(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	orders := make([]orderSummaryView, len(page.GetOrders()))
+	for i, o := range page.GetOrders() {
+		var items int32
+		for _, it := range o.GetOrder().GetItems() {
+			items += it.GetItem().GetQuantity()
+		}
+		orders[i] = orderSummaryView{
+			ID:       o.GetOrder().GetOrderId(),
+			PlacedAt: placedAt(o),
+			Items:    items,
+			Total:    o.GetTotal(),
+			Tracking: o.GetOrder().GetShippingTrackingId(),
+		}
+	}
+
+	if err := executeTemplate(r.Context(), w, "orders", map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":     currentCurrency(r),
+		"show_currency":     false,
+		"currencies":        currencies,
+		"cart_size":         cartSize(cart),
+		"orders":            orders,
+		"first_page":        token == "",
+		"next_page":         page.GetNextPageToken(),
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// orderHandler shows an order placed in the session. Orders of other
+// sessions are not found, as are the orders checkoutservice no longer
+// keeps.
+func (fe *frontendServer) orderHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	id := mux.Vars(r)["id"]
+	log.WithField("order", id).Debug("serving order page")
+
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadCurrencies, loadCart := l.currencies(), l.cart(sessionID(r))
+
+	order, err := l.order(sessionID(r), id)(l.ctx)
+	if shop.IsNotFound(err) {
+		fe.orderNotFound(w, r, log, l, id)
+		return
+	}
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve order"), http.StatusInternalServerError)
+		return
+	}
+	result := order.GetOrder()
+	loadProducts := make([]func(context.Context) (*shop.Product, error), len(result.GetItems()))
+	for i, it := range result.GetItems() {
+		loadProducts[i] = l.product(it.GetItem().GetProductId())
+	}
+
+	currencies, err := loadCurrencies(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := loadCart(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	type orderItemView struct {
+		Item     *shop.Product
+		Quantity int32
+		Price    *shop.Money
+	}
+	items := make([]orderItemView, len(result.GetItems()))
+	for i, it := range result.GetItems() {
+		// The catalog may have dropped a product since the order: show its
+		// ID only.
+		p, err := loadProducts[i](l.ctx)
+		if err != nil {
+			log.WithField("error", err).Warnf("could not retrieve product #%s", it.GetItem().GetProductId())
+			p = &shop.Product{Id: it.GetItem().GetProductId()}
+		}
+		price := money.MultiplySlow(*it.GetCost(), uint32(it.GetItem().GetQuantity()))
+		items[i] = orderItemView{Item: p, Quantity: it.GetItem().GetQuantity(), Price: &price}
+	}
+
+	if err := executeTemplate(r.Context(), w, "order_detail", map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":     currentCurrency(r),
+		"show_currency":     false,
+		"currencies":        currencies,
+		"cart_size":         cartSize(cart),
+		"order":             result,
+		"placed_at":         placedAt(order),
+		"items":             items,
+		"total_paid":        order.GetTotal(),
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// orderNotFound answers 404 with a page pointing to the order history. It
+// does not tell an order of another session from an unknown one.
+func (fe *frontendServer) orderNotFound(w http.ResponseWriter, r *http.Request, log logrus.FieldLogger, l *loader, id string) {
+	log.WithField("order", id).Info("order not found")
+	currencies, err := l.currencies()(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	if err := executeTemplate(r.Context(), w, "order_not_found", map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":     currentCurrency(r),
+		"show_currency":     false,
+		"currencies":        currencies,
+		"order_id":          id,
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
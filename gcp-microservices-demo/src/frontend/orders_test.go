@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// orderStandIns serves the order history of checkoutservice: session-1
+// placed the orders order-1 to order-n, and another session order-x. The
+// shipment of the orders is in transit; shippingservice knows no other.
+func orderStandIns(t *testing.T, n int) *frontendServer {
+	orders := map[string][]*shop.Order{"another-session": {testOrder("order-x")}}
+	for i := n; i > 0; i-- {
+		orders["session-1"] = append(orders["session-1"], testOrder(fmt.Sprintf("order-%d", i)))
+	}
+	return standIns(t, map[string]http.HandlerFunc{
+		"/checkout": func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			w.Header().Set("Content-Type", "application/json")
+			if id := q.Get("order_id"); id != "" {
+				for _, o := range orders[q.Get("user_id")] {
+					if o.GetOrder().GetOrderId() == id {
+						json.NewEncoder(w).Encode(o)
+						return
+					}
+				}
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","message":"no order ` + id + `"}`))
+				return
+			}
+			size, _ := strconv.Atoi(q.Get("page_size"))
+			all := orders[q.Get("user_id")]
+			start := 0
+			if token := q.Get("page_token"); token != "" {
+				start = -1
+				for i, o := range all {
+					if o.GetOrder().GetOrderId() == token {
+						start = i + 1
+					}
+				}
+				if start < 0 {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"code":"bad_request","message":"invalid page_token"}`))
+					return
+				}
+			}
+			resp := &shop.ListOrdersResponse{Orders: []*shop.Order{}}
+			for i := start; i < len(all) && i < start+size; i++ {
+				resp.Orders = append(resp.Orders, all[i])
+			}
+			if start+size < len(all) {
+				resp.NextPageToken = all[start+size-1].GetOrder().GetOrderId()
+			}
+			json.NewEncoder(w).Encode(resp)
+		},
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"` + r.URL.Query().Get("id") + `","name":"Sunglasses","picture":"/static/img/products/sunglasses.jpg"}`))
+		},
+		"/shipping": func(w http.ResponseWriter, r *http.Request) {
+			id := r.URL.Query().Get("tracking_id")
+			w.Header().Set("Content-Type", "application/json")
+			if id != testOrder("").GetOrder().GetShippingTrackingId() {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code":"not_found","message":"no shipment ` + id + `"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(&shop.TrackShipmentResponse{
+				TrackingId: id,
+				Status:     "in_transit",
+				Events: []*shop.ShipmentEvent{
+					{Status: "label_created", Description: "Shipping label created", Time: "2023-09-06T10:00:00Z", Done: true},
+					{Status: "in_transit", Description: "Picked up by the carrier, in transit", Time: "2023-09-06T14:30:00Z", Done: true},
+					{Status: "out_for_delivery", Description: "Out for delivery", Time: "2023-09-08T10:00:00Z"},
+					{Status: "delivered", Description: "Delivered", Time: "2023-09-08T15:00:00Z"},
+				},
+				EstimatedDelivery: "2023-09-08T15:00:00Z",
+			})
+		},
+	})
+}
+
+func testOrder(id string) *shop.Order {
+	return &shop.Order{
+		Order: &shop.OrderResult{
+			OrderId:            id,
+			ShippingTrackingId: "RS-12345-678901234-S0K6G0",
+			ShippingCost:       &shop.Money{CurrencyCode: "EUR", Units: 7, Nanos: 650000000},
+			ShippingAddress:    &shop.Address{StreetAddress: "1600 Amphitheatre Parkway", City: "Mountain View", State: "CA", Country: "United States", ZipCode: 94043},
+			Items: []*shop.OrderItem{{
+				Item: &shop.CartItem{ProductId: "OLJCESPC7Z", Quantity: 2},
+				Cost: &shop.Money{CurrencyCode: "EUR", Units: 17},
+			}},
+		},
+		Total:    &shop.Money{CurrencyCode: "EUR", Units: 41, Nanos: 650000000},
+		PlacedAt: "2023-09-06T10:00:00Z",
+	}
+}
+
+func TestOrdersPages(t *testing.T) {
+	fe := orderStandIns(t, ordersPageSize+2)
+	tests := []struct {
+		path        string
+		want, avoid []string
+	}{
+		{"/orders",
+			[]string{`href="/orders/order-12"`, `href="/orders/order-3"`, `href="/orders?page=order-3" rel="next"`, "€41.65", "2 items", "September 6, 2023"},
+			[]string{`href="/orders/order-2"`, `rel="first"`}},
+		{"/orders?page=order-3",
+			[]string{`href="/orders/order-2"`, `href="/orders/order-1"`, `href="/orders" rel="first"`},
+			[]string{`href="/orders/order-3"`, `rel="next"`}},
+	}
+	for _, tt := range tests {
+		rec := servePage(fe.ordersHandler, httptest.NewRequest(http.MethodGet, tt.path, nil), "/orders")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d:\n%s", tt.path, rec.Code, rec.Body)
+		}
+		page := rec.Body.String()
+		for _, s := range tt.want {
+			if !strings.Contains(page, s) {
+				t.Errorf("%s lacks %s", tt.path, s)
+			}
+		}
+		for _, s := range tt.avoid {
+			if strings.Contains(page, s) {
+				t.Errorf("%s shows %s", tt.path, s)
+			}
+		}
+	}
+
+	for _, token := range []string{"x", "order-x"} {
+		rec := servePage(fe.ordersHandler, httptest.NewRequest(http.MethodGet, "/orders?page="+token, nil), "/orders")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("page %s: status %d, want 400", token, rec.Code)
+		}
+	}
+
+	fe = orderStandIns(t, 0)
+	rec := servePage(fe.ordersHandler, httptest.NewRequest(http.MethodGet, "/orders", nil), "/orders")
+	if !strings.Contains(rec.Body.String(), "You have not placed any orders yet.") {
+		t.Errorf("the page of a session without orders:\n%s", rec.Body)
+	}
+}
+
+func TestOrderPage(t *testing.T) {
+	fe := orderStandIns(t, 1)
+	order := func(id string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/orders/"+id, nil), map[string]string{"id": id})
+		return servePage(fe.orderHandler, req, "/orders/{id}")
+	}
+
+	rec := order("order-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d:\n%s", rec.Code, rec.Body)
+	}
+	for _, s := range []string{"Order #order-1", "Sunglasses", "Quantity: 2", "€34.00", "€7.65", "€41.65", "1600 Amphitheatre Parkway", "Mountain View, CA 94043", "RS-12345-678901234-S0K6G0"} {
+		if !strings.Contains(rec.Body.String(), s) {
+			t.Errorf("the order page lacks %s", s)
+		}
+	}
+
+	for _, id := range []string{"order-x", "unknown"} {
+		rec := order(id)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", id, rec.Code)
+		}
+		if page := rec.Body.String(); !strings.Contains(page, "Order not found") || strings.Contains(page, "RS-12345-678901234-S0K6G0") {
+			t.Errorf("%s: the page is not the not-found page:\n%s", id, page)
+		}
+	}
+}
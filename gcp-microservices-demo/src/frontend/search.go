@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+const (
+	// maxQueryLength bounds the queries sent to the catalog.
+	maxQueryLength = 100
+	// defaultTypeaheadLimit is the number of results of the JSON variant
+	// of /search, unless its limit parameter says otherwise.
+	defaultTypeaheadLimit = 8
+	// searchSuggestions is the number of products suggested when a search
+	// finds nothing.
+	searchSuggestions = 4
+)
+
+// searchQuery returns the query of a search request: the q parameter,
+// trimmed and bounded.
+func searchQuery(r *http.Request) string {
+	q := strings.TrimSpace(r.FormValue("q"))
+	if len(q) > maxQueryLength {
+		q = strings.ToValidUTF8(q[:maxQueryLength], "")
+	}
+	return q
+}
+
+// wantsJSON tells whether a search request asks for the JSON variant, with
+// format=json or an Accept header preferring JSON.
+func wantsJSON(r *http.Request) bool {
+	if r.FormValue("format") == "json" {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.HasPrefix(accept, "application/json")
+}
+
+// searchProducts returns the products matching q, or none when q is empty.
+func (l *loader) searchProducts(q string) func(ctx context.Context) ([]*shop.Product, error) {
+	p := l.start(l.ctx, "search/"+q, func(ctx context.Context) (interface{}, error) {
+		if q == "" {
+			return []*shop.Product(nil), nil
+		}
+		resp, err := l.fe.client.Catalog.SearchProducts(ctx, &shop.SearchProductsRequest{Query: q})
+		return resp.GetResults(), err
+	})
+	return func(ctx context.Context) ([]*shop.Product, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.([]*shop.Product)
+		return out, err
+	}
+}
+
+type searchResult struct {
+	Item  *shop.Product
+	Price *shop.Money
+}
+
+// typeaheadResult is a result of the JSON variant of /search.
+type typeaheadResult struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	URL       string      `json:"url"`
+	Picture   string      `json:"picture"`
+	Price     *shop.Money `json:"price"`
+	PriceText string      `json:"price_text"`
+}
+
+type typeaheadResponse struct {
+	Query   string            `json:"query"`
+	Total   int               `json:"total"`
+	Results []typeaheadResult `json:"results"`
+}
+
+// searchHandler renders the products matching the query, with their
+// prices in the currency of the user. When nothing matches, it suggests
+// products of the catalog instead. The JSON variant answers the first
+// results only, for typeahead.
+func (fe *frontendServer) searchHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	q := searchQuery(r)
+	log.WithField("currency", currentCurrency(r)).Debug("searching products")
+
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadResults := l.searchProducts(q)
+	if wantsJSON(r) {
+		fe.searchJSON(w, r, log, l, q, loadResults)
+		return
+	}
+	loadCurrencies, loadCart := l.currencies(), l.cart(sessionID(r))
+
+	found, err := loadResults(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not search products"), http.StatusInternalServerError)
+		return
+	}
+	results, suggestions := found, []*shop.Product(nil)
+	if len(found) == 0 {
+		products, err := l.products()(l.ctx)
+		if err != nil {
+			renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve products"), http.StatusInternalServerError)
+			return
+		}
+		if len(products) > searchSuggestions {
+			products = products[:searchSuggestions]
+		}
+		results = products
+		suggestions = products
+	}
+	views, err := l.searchResults(results, currentCurrency(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to do currency conversion for products"), http.StatusInternalServerError)
+		return
+	}
+	currencies, err := loadCurrencies(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := loadCart(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":     currentCurrency(r),
+		"show_currency":     true,
+		"currencies":        currencies,
+		"cart_size":         cartSize(cart),
+		"search_query":      q,
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}
+	if suggestions != nil {
+		data["suggestions"] = views
+	} else {
+		data["results"] = views
+	}
+	if err := executeTemplate(r.Context(), w, "search", data); err != nil {
+		log.Println(err)
+	}
+}
+
+func (fe *frontendServer) searchJSON(w http.ResponseWriter, r *http.Request, log logrus.FieldLogger, l *loader, q string,
+	loadResults func(context.Context) ([]*shop.Product, error)) {
+	limit := defaultTypeaheadLimit
+	if v := r.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	found, err := loadResults(l.ctx)
+	if err != nil {
+		log.WithField("error", err).Warn("could not search products")
+		http.Error(w, "could not search products", http.StatusBadGateway)
+		return
+	}
+	resp := typeaheadResponse{Query: q, Total: len(found), Results: []typeaheadResult{}}
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	views, err := l.searchResults(found, currentCurrency(r))
+	if err != nil {
+		log.WithField("error", err).Warn("failed to do currency conversion for products")
+		http.Error(w, "could not convert prices", http.StatusBadGateway)
+		return
+	}
+	for _, v := range views {
+		resp.Results = append(resp.Results, typeaheadResult{
+			ID:        v.Item.GetId(),
+			Name:      v.Item.GetName(),
+			URL:       "/product/" + v.Item.GetId(),
+			Picture:   v.Item.GetPicture(),
+			Price:     v.Price,
+			PriceText: renderMoney(*v.Price),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// searchResults converts the prices of products to currency.
+func (l *loader) searchResults(products []*shop.Product, currency string) ([]searchResult, error) {
+	if len(products) == 0 {
+		return nil, nil
+	}
+	prices := make([]*shop.Money, len(products))
+	for i, p := range products {
+		prices[i] = p.GetPriceUsd()
+	}
+	converted, err := l.convertAll(prices, currency)(l.ctx)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]searchResult, len(products))
+	for i, p := range products {
+		views[i] = searchResult{p, converted[i]}
+	}
+	return views, nil
+}
+
+// highlight escapes text, marking the occurrences of query in it, whatever
+// their case, as the catalog matches them.
+func highlight(text, query string) template.HTML {
+	if query == "" {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	lower, q := strings.ToLower(text), strings.ToLower(query)
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, q)
+		// Lowering may change the length of some characters: only mark
+		// the matches that line up with the text.
+		if i < 0 || len(lower) != len(text) {
+			b.WriteString(template.HTMLEscapeString(text))
+			break
+		}
+		b.WriteString(template.HTMLEscapeString(text[:i]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(text[i : i+len(q)]))
+		b.WriteString("</mark>")
+		text, lower = text[i+len(q):], lower[i+len(q):]
+	}
+	return template.HTML(b.String())
+}
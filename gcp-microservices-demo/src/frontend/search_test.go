@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text, query string
+		want        string
+	}{
+		{"Sunglasses", "", "Sunglasses"},
+		{"Sunglasses", "glass", "Sun<mark>glass</mark>es"},
+		{"Glass jar of glass", "GLASS", "<mark>Glass</mark> jar of <mark>glass</mark>"},
+		{"Salt & Pepper", "salt", "<mark>Salt</mark> &amp; Pepper"},
+		{"<b>Mug</b>", "mug", "&lt;b&gt;<mark>Mug</mark>&lt;/b&gt;"},
+		{"Mug", "<mug>", "Mug"},
+	}
+	for _, tt := range tests {
+		if got := string(highlight(tt.text, tt.query)); got != tt.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+// catalogStandIns serves a catalog of two products, of which the search
+// for "glass" finds one.
+func catalogStandIns(t *testing.T) *frontendServer {
+	return standIns(t, map[string]http.HandlerFunc{
+		"/product": func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("query") {
+			case "glass":
+				w.Write([]byte(`{"results":[{"id":"OLJCESPC7Z","name":"Sunglasses","picture":"/static/img/products/sunglasses.jpg","price_usd":{"currency_code":"USD","units":19,"nanos":990000000}}]}`))
+			case "":
+				w.Write([]byte(`{"products":[{"id":"OLJCESPC7Z","name":"Sunglasses","price_usd":{"currency_code":"USD","units":19,"nanos":990000000}},` +
+					`{"id":"9SIQT8TOJO","name":"Mug","price_usd":{"currency_code":"USD","units":8,"nanos":990000000}}]}`))
+			default:
+				w.Write([]byte(`{}`))
+			}
+		},
+		"/currency/batch": func(w http.ResponseWriter, r *http.Request) {
+			var in struct{ From []json.RawMessage }
+			json.NewDecoder(r.Body).Decode(&in)
+			results := make([]string, len(in.From))
+			for i := range results {
+				results[i] = `{"currency_code":"EUR","units":17,"nanos":500000000}`
+			}
+			w.Write([]byte(`{"results":[` + strings.Join(results, ",") + `]}`))
+		},
+	})
+}
+
+func TestSearchPage(t *testing.T) {
+	fe := catalogStandIns(t)
+	req := httptest.NewRequest(http.MethodGet, "/search?q=+glass+", nil)
+	req.AddCookie(&http.Cookie{Name: cookieCurrency, Value: "EUR"})
+	rec := servePage(fe.searchHandler, req, "/search")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d:\n%s", rec.Code, rec.Body)
+	}
+	page := rec.Body.String()
+	for _, s := range []string{`Results for "glass"`, "Sun<mark>glass</mark>es", "€17.50", `value="glass"`} {
+		if !strings.Contains(page, s) {
+			t.Errorf("the page lacks %q", s)
+		}
+	}
+}
+
+func TestSearchEmpty(t *testing.T) {
+	fe := catalogStandIns(t)
+	for _, q := range []string{"teapot", ""} {
+		rec := servePage(fe.searchHandler, httptest.NewRequest(http.MethodGet, "/search?q="+q, nil), "/search")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status %d:\n%s", q, rec.Code, rec.Body)
+		}
+		page := rec.Body.String()
+		for _, s := range []string{"You may like", `href="/search?q=Mug"`} {
+			if !strings.Contains(page, s) {
+				t.Errorf("%q: the page lacks the suggestion %q", q, s)
+			}
+		}
+		if q != "" && !strings.Contains(page, "No products match") {
+			t.Errorf("%q: the page does not tell that nothing matched", q)
+		}
+	}
+}
+
+func TestSearchJSON(t *testing.T) {
+	fe := catalogStandIns(t)
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/search?format=json&q=glass", nil),
+		func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/search?q=glass", nil)
+			req.Header.Set("Accept", "application/json")
+			return req
+		}(),
+	} {
+		rec := servePage(fe.searchHandler, req, "/search")
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("%s: status %d, %s", req.URL, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		var resp typeaheadResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Query != "glass" || resp.Total != 1 || len(resp.Results) != 1 ||
+			resp.Results[0].URL != "/product/OLJCESPC7Z" || resp.Results[0].PriceText != "€17.50" {
+			t.Errorf("%s: %+v", req.URL, resp)
+		}
+	}
+
+	rec := servePage(fe.searchHandler, httptest.NewRequest(http.MethodGet, "/search?format=json&q=glass&limit=0", nil), "/search")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often a MemoryStore drops its expired sessions.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps sessions in the memory of the process: they are lost
+// when it exits, and not shared between replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	session Session
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry), now: time.Now}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[id]
+	if !ok || !s.now().Before(e.expires) {
+		return nil, ErrNotFound
+	}
+	return e.session.copy(), nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, sess *Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for id, e := range s.sessions {
+			if !now.Before(e.expires) {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastSweep = now
+	}
+	s.sessions[sess.ID] = memoryEntry{session: *sess.copy(), expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// copy returns a copy of s that shares none of its values, so that the
+// callers of a store do not change the sessions it keeps.
+func (s *Session) copy() *Session {
+	c := *s
+	if s.Values != nil {
+		c.Values = make(map[string]string, len(s.Values))
+		for k, v := range s.Values {
+			c.Values[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,51 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisPrefix prefixes the keys of the sessions in Redis, which may be
+// shared with other data.
+const redisPrefix = "session:"
+
+// RedisStore keeps sessions in Redis as JSON, and lets Redis expire them,
+// so that every replica of the frontend shares them.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
+	b, err := s.client.Get(ctx, redisPrefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess := new(Session)
+	if err := json.Unmarshal(b, sess); err != nil {
+		return nil, err
+	}
+	sess.ID = id
+	return sess, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, sess *Session, ttl time.Duration) error {
+	b, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisPrefix+sess.ID, b, ttl).Err()
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, redisPrefix+id).Err()
+}
@@ -0,0 +1,278 @@
+// Package session keeps the sessions of the frontend on the server side.
+// The browser holds only the ID of its session, signed with HMAC-SHA256 so
+// that an ID cannot be made up, in a cookie that scripts cannot read.
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultIdleTimeout and DefaultMaxAge bound the life of a session: it
+	// ends once unused for the idle timeout, and at the latest after the
+	// maximum age.
+	DefaultIdleTimeout = 24 * time.Hour
+	DefaultMaxAge      = 48 * time.Hour
+
+	// touchInterval is how long a session is used before its last use is
+	// saved again, so that every request does not write to the store.
+	touchInterval = time.Minute
+	// minKeyLength is the length of the shortest signing key, that of the
+	// HMAC-SHA256 digest.
+	minKeyLength = sha256.Size
+)
+
+var (
+	// ErrNotFound is answered by a Store for a session it does not keep,
+	// whether it never did or the session expired.
+	ErrNotFound = errors.New("session not found")
+
+	errSignature = errors.New("invalid session cookie")
+)
+
+// Session is the state the frontend keeps for a browser.
+type Session struct {
+	// ID is the secret the browser is known by. It is only sent in the
+	// session cookie.
+	ID string `json:"-"`
+	// UserID is the ID the cart and the orders of the session are kept
+	// under. Unlike ID, it may be shown and logged.
+	UserID   string            `json:"user_id"`
+	Created  time.Time         `json:"created"`
+	LastSeen time.Time         `json:"last_seen"`
+	Values   map[string]string `json:"values,omitempty"`
+}
+
+// Store keeps sessions by ID.
+type Store interface {
+	// Get answers the session id, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Session, error)
+	// Save keeps s for ttl, replacing the session of the same ID.
+	Save(ctx context.Context, s *Session, ttl time.Duration) error
+	// Delete drops the session id, if the store keeps it.
+	Delete(ctx context.Context, id string) error
+}
+
+// ParseKeys parses the comma-separated signing keys of SESSION_KEYS. The
+// first key signs the cookies, and every key is accepted when verifying
+// them, so that a key is rotated by putting the new one first and dropping
+// the old one once the sessions it signed have expired.
+func ParseKeys(s string) ([][]byte, error) {
+	var keys [][]byte
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+		if len(k) < minKeyLength {
+			return nil, errors.New("session keys must be at least 32 bytes long")
+		}
+		keys = append(keys, []byte(k))
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no session key")
+	}
+	return keys, nil
+}
+
+// RandomKey returns a signing key for a single replica that does not need
+// its sessions to outlive it.
+func RandomKey() []byte {
+	b := make([]byte, minKeyLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return []byte(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// Manager loads the session of each request from its store, and sets the
+// session cookie.
+type Manager struct {
+	Store Store
+	// Keys sign the session cookies, see ParseKeys.
+	Keys [][]byte
+	// CookieName is the name of the session cookie.
+	CookieName  string
+	IdleTimeout time.Duration
+	MaxAge      time.Duration
+	// SecureCookies marks every cookie Secure. Without it, the cookies of
+	// requests that came over TLS, as told by the request or its
+	// X-Forwarded-Proto header, are marked Secure.
+	SecureCookies bool
+
+	// clock is time.Now, but for tests.
+	clock func() time.Time
+}
+
+// NewManager returns a Manager of the sessions in store, with the default
+// expiry.
+func NewManager(store Store, cookieName string, keys [][]byte) *Manager {
+	return &Manager{
+		Store:       store,
+		Keys:        keys,
+		CookieName:  cookieName,
+		IdleTimeout: DefaultIdleTimeout,
+		MaxAge:      DefaultMaxAge,
+	}
+}
+
+// Load returns the session of r, and starts a new one when r has none, or
+// one that is forged, unknown or expired.
+func (m *Manager) Load(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	now := m.now()
+	if c, err := r.Cookie(m.CookieName); err == nil {
+		id, current, err := m.verify(c.Value)
+		if err == nil {
+			s, err := m.Store.Get(r.Context(), id)
+			switch {
+			case err == nil && !m.expired(s, now):
+				s.ID = id
+				if now.Sub(s.LastSeen) >= touchInterval {
+					s.LastSeen = now
+					if err := m.Save(r.Context(), s); err != nil {
+						return nil, err
+					}
+				}
+				if !current {
+					http.SetCookie(w, m.sessionCookie(r, s))
+				}
+				return s, nil
+			case err == nil:
+				if err := m.Store.Delete(r.Context(), id); err != nil {
+					return nil, err
+				}
+			case !errors.Is(err, ErrNotFound):
+				return nil, err
+			}
+		}
+	}
+	return m.start(w, r)
+}
+
+// Save keeps the changes to s until it expires.
+func (m *Manager) Save(ctx context.Context, s *Session) error {
+	return m.Store.Save(ctx, s, m.ttl(s, m.now()))
+}
+
+// Regenerate ends s, if not nil, and starts a new, empty session in its
+// place, under another ID and another user ID.
+func (m *Manager) Regenerate(w http.ResponseWriter, r *http.Request, s *Session) (*Session, error) {
+	if s != nil {
+		if err := m.Store.Delete(r.Context(), s.ID); err != nil {
+			return nil, err
+		}
+	}
+	return m.start(w, r)
+}
+
+// Renew moves s, with its user ID and values, to a new ID and ends the old
+// one, so that an ID learnt before a login does not carry it. The session
+// starts again from now.
+func (m *Manager) Renew(w http.ResponseWriter, r *http.Request, s *Session) error {
+	old := s.ID
+	now := m.now()
+	s.ID, s.Created, s.LastSeen = newID(), now, now
+	if err := m.Save(r.Context(), s); err != nil {
+		return err
+	}
+	if err := m.Store.Delete(r.Context(), old); err != nil {
+		return err
+	}
+	http.SetCookie(w, m.sessionCookie(r, s))
+	return nil
+}
+
+// Cookie returns a cookie of the frontend with the attributes of the
+// session cookie: HttpOnly, SameSite=Lax, and Secure over TLS.
+func (m *Manager) Cookie(r *http.Request, name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   m.SecureCookies || r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (m *Manager) now() time.Time {
+	if m.clock == nil {
+		return time.Now()
+	}
+	return m.clock()
+}
+
+func (m *Manager) start(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	now := m.now()
+	s := &Session{ID: newID(), UserID: uuid.New().String(), Created: now, LastSeen: now}
+	if err := m.Save(r.Context(), s); err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, m.sessionCookie(r, s))
+	return s, nil
+}
+
+func (m *Manager) sessionCookie(r *http.Request, s *Session) *http.Cookie {
+	return m.Cookie(r, m.CookieName, m.sign(s.ID), int(s.Created.Add(m.MaxAge).Sub(m.now())/time.Second))
+}
+
+func (m *Manager) expired(s *Session, now time.Time) bool {
+	return now.Sub(s.LastSeen) >= m.IdleTimeout || now.Sub(s.Created) >= m.MaxAge
+}
+
+// ttl is how long s is kept from now: until it is idle for too long, or
+// reaches its maximum age.
+func (m *Manager) ttl(s *Session, now time.Time) time.Duration {
+	ttl := s.LastSeen.Add(m.IdleTimeout).Sub(now)
+	if end := s.Created.Add(m.MaxAge).Sub(now); end < ttl {
+		ttl = end
+	}
+	return ttl
+}
+
+// sign returns the cookie value of the session id: the ID and its MAC by
+// the first key.
+func (m *Manager) sign(id string) string {
+	return id + "." + mac(m.Keys[0], id)
+}
+
+// verify returns the session ID of a cookie value, and whether the first
+// key signed it.
+func (m *Manager) verify(value string) (id string, current bool, err error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", false, errSignature
+	}
+	id, sig := value[:i], value[i+1:]
+	for n, k := range m.Keys {
+		if hmac.Equal([]byte(sig), []byte(mac(k, id))) {
+			return id, n == 0, nil
+		}
+	}
+	return "", false, errSignature
+}
+
+func mac(key []byte, id string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// newID returns a random session ID of 256 bits.
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,207 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	oldKey = []byte("an old key of at least thirty-two bytes")
+	newKey = []byte("a new key of at least thirty-two bytes!")
+)
+
+// clock is a time that tests move forward.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+// stores returns a memory and a Redis store, which both tell the time of
+// c.
+func stores(t *testing.T, c *clock) map[string]Store {
+	mem := NewMemoryStore()
+	mem.now = c.now
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return map[string]Store{"memory": mem, "redis": &fastForward{NewRedisStore(client), mr, c}}
+}
+
+// fastForward moves the clock of miniredis to that of the test before each
+// call, so that keys expire as they would in Redis.
+type fastForward struct {
+	*RedisStore
+	mr    *miniredis.Miniredis
+	clock *clock
+}
+
+func (s *fastForward) Get(ctx context.Context, id string) (*Session, error) {
+	s.mr.SetTime(s.clock.t)
+	s.mr.FastForward(0)
+	return s.RedisStore.Get(ctx, id)
+}
+
+// load serves a request with the cookies given, and answers its session
+// and the cookie set, if any.
+func load(t *testing.T, m *Manager, cookies ...*http.Cookie) (*Session, *http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	s, err := m.Load(rec, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set *http.Cookie
+	if cs := rec.Result().Cookies(); len(cs) > 0 {
+		set = cs[0]
+	}
+	return s, set
+}
+
+func TestExpiry(t *testing.T) {
+	c := &clock{time.Date(2023, 9, 6, 10, 0, 0, 0, time.UTC)}
+	for name, store := range stores(t, c) {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(store, "sid", [][]byte{newKey})
+			m.IdleTimeout, m.MaxAge = time.Hour, 3*time.Hour
+			m.clock = c.now
+			start := c.t
+
+			s, cookie := load(t, m)
+			if cookie == nil || cookie.MaxAge != 3*60*60 {
+				t.Fatalf("cookie %v, want one for 3 hours", cookie)
+			}
+			user := s.UserID
+			for _, d := range []time.Duration{50 * time.Minute, 100 * time.Minute, 150 * time.Minute} {
+				c.t = start.Add(d)
+				if s, _ := load(t, m, cookie); s.UserID != user {
+					t.Fatalf("after %v: the session was lost", d)
+				}
+			}
+			c.t = start.Add(170 * time.Minute)
+			if s, _ := load(t, m, cookie); s.UserID != user {
+				t.Fatal("a session used within the idle timeout was lost")
+			}
+			c.t = start.Add(3 * time.Hour)
+			if s, _ := load(t, m, cookie); s.UserID == user {
+				t.Error("the session outlived its maximum age")
+			}
+
+			start = c.t
+			s, cookie = load(t, m)
+			c.t = start.Add(time.Hour)
+			if s2, _ := load(t, m, cookie); s2.UserID == s.UserID {
+				t.Error("the session outlived its idle timeout")
+			}
+		})
+	}
+}
+
+func TestValues(t *testing.T) {
+	c := &clock{time.Date(2023, 9, 6, 10, 0, 0, 0, time.UTC)}
+	for name, store := range stores(t, c) {
+		t.Run(name, func(t *testing.T) {
+			m := NewManager(store, "sid", [][]byte{newKey})
+			m.clock = c.now
+			s, cookie := load(t, m)
+			s.Values = map[string]string{"k": "v"}
+			if err := m.Save(context.Background(), s); err != nil {
+				t.Fatal(err)
+			}
+			s.Values["k"] = "changed, unsaved"
+			if got, _ := load(t, m, cookie); got.Values["k"] != "v" || got.ID != s.ID {
+				t.Errorf("loaded %+v, want the saved values of %s", got, s.ID)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	store := NewMemoryStore()
+	old := NewManager(store, "sid", [][]byte{oldKey})
+	s, oldCookie := load(t, old)
+
+	rotated := NewManager(store, "sid", [][]byte{newKey, oldKey})
+	got, resigned := load(t, rotated, oldCookie)
+	if got.UserID != s.UserID {
+		t.Fatal("the session signed with the old key was lost")
+	}
+	if resigned == nil || resigned.Value == oldCookie.Value {
+		t.Fatalf("the cookie was not signed again with the new key: %v", resigned)
+	}
+	if _, set := load(t, rotated, resigned); set != nil {
+		t.Errorf("a cookie of the new key was set again: %v", set)
+	}
+
+	retired := NewManager(store, "sid", [][]byte{newKey})
+	if got, _ := load(t, retired, oldCookie); got.UserID == s.UserID {
+		t.Error("a cookie of a retired key is accepted")
+	}
+	if got, _ := load(t, retired, resigned); got.UserID != s.UserID {
+		t.Error("the re-signed cookie is not accepted")
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	m := NewManager(NewMemoryStore(), "sid", [][]byte{newKey})
+	s, cookie := load(t, m)
+	rec := httptest.NewRecorder()
+	fresh, err := m.Regenerate(rec, httptest.NewRequest(http.MethodGet, "/logout", nil), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.ID == s.ID || fresh.UserID == s.UserID {
+		t.Errorf("regenerated %+v, want new IDs", fresh)
+	}
+	if got, _ := load(t, m, cookie); got.UserID == s.UserID {
+		t.Error("the ended session is still loaded")
+	}
+	if got, _ := load(t, m, rec.Result().Cookies()[0]); got.UserID != fresh.UserID {
+		t.Error("the new session is not loaded")
+	}
+}
+
+func TestRenew(t *testing.T) {
+	m := NewManager(NewMemoryStore(), "sid", [][]byte{newKey})
+	s, cookie := load(t, m)
+	old := s.ID
+	s.Values = map[string]string{"k": "v"}
+	rec := httptest.NewRecorder()
+	if err := m.Renew(rec, httptest.NewRequest(http.MethodPost, "/login", nil), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID == old {
+		t.Fatal("the session kept its ID")
+	}
+	if got, _ := load(t, m, cookie); got.ID == old || got.UserID == s.UserID {
+		t.Error("the old ID still loads the session")
+	}
+	got, _ := load(t, m, rec.Result().Cookies()[0])
+	if got.ID != s.ID || got.UserID != s.UserID || got.Values["k"] != "v" {
+		t.Errorf("loaded %+v, want the renewed %+v", got, s)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(string(newKey) + ", " + string(oldKey))
+	if err != nil || len(keys) != 2 || string(keys[0]) != string(newKey) {
+		t.Errorf("ParseKeys = %q, %v", keys, err)
+	}
+	for _, s := range []string{"", " , ", "short," + string(newKey)} {
+		if _, err := ParseKeys(s); err == nil {
+			t.Errorf("ParseKeys(%q) succeeded", s)
+		}
+	}
+	if k := RandomKey(); len(k) < minKeyLength || strings.Contains(string(k), ",") {
+		t.Errorf("RandomKey = %q", k)
+	}
+}
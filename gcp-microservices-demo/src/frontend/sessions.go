@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/session"
+)
+
+// sessionsFromEnv sets up the sessions as SESSION_STORE ("memory", the
+// default, or "redis" at SESSION_REDIS_ADDR), SESSION_KEYS,
+// SESSION_IDLE_TIMEOUT, SESSION_MAX_AGE and SECURE_COOKIES say. Without
+// SESSION_KEYS, a random key signs the cookies, so that the sessions do not
+// survive a restart and replicas do not share them.
+func sessionsFromEnv(log logrus.FieldLogger) (*session.Manager, error) {
+	var store session.Store
+	switch kind := os.Getenv("SESSION_STORE"); kind {
+	case "", "memory":
+		store = session.NewMemoryStore()
+	case "redis":
+		addr := os.Getenv("SESSION_REDIS_ADDR")
+		if addr == "" {
+			return nil, errors.New("SESSION_STORE is redis but SESSION_REDIS_ADDR is not set")
+		}
+		store = session.NewRedisStore(redis.NewClient(&redis.Options{Addr: addr}))
+	default:
+		return nil, errors.Errorf("unknown SESSION_STORE %q, want memory or redis", kind)
+	}
+
+	var keys [][]byte
+	if v := os.Getenv("SESSION_KEYS"); v != "" {
+		var err error
+		if keys, err = session.ParseKeys(v); err != nil {
+			return nil, errors.Wrap(err, "SESSION_KEYS")
+		}
+	} else {
+		log.Warn("SESSION_KEYS is not set: signing the session cookies with a random key")
+		keys = [][]byte{session.RandomKey()}
+	}
+
+	m := session.NewManager(store, cookieSessionID, keys)
+	m.IdleTimeout = durationEnv(log, "SESSION_IDLE_TIMEOUT", session.DefaultIdleTimeout)
+	m.MaxAge = durationEnv(log, "SESSION_MAX_AGE", session.DefaultMaxAge)
+	m.SecureCookies = os.Getenv("SECURE_COOKIES") == "true"
+	return m, nil
+}
+
+// currentSession returns the session of r, as ensureSession loaded it.
+func currentSession(r *http.Request) *session.Session {
+	s, _ := r.Context().Value(ctxKeySession{}).(*session.Session)
+	return s
+}
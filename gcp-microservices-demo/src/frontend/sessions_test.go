@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/frontend/session"
+)
+
+// testSessions keeps the sessions of a test in memory.
+func testSessions() *session.Manager {
+	return session.NewManager(session.NewMemoryStore(), cookieSessionID, [][]byte{session.RandomKey()})
+}
+
+// TestSessionCookies checks that a forged session cookie starts a new
+// session rather than naming the cart of another user, and that logging
+// out does the same for a valid one.
+func TestSessionCookies(t *testing.T) {
+	fe := &frontendServer{sessions: testSessions()}
+	log := logrus.New()
+	log.Out = io.Discard
+	var userID string
+	h := fe.ensureSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = sessionID(r)
+		if r.URL.Path == "/logout" {
+			fe.logoutHandler(w, r.WithContext(context.WithValue(r.Context(), ctxKeyLog{}, logrus.FieldLogger(log))))
+		}
+	}))
+	get := func(path string, cookies ...*http.Cookie) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Result()
+	}
+
+	first := get("/")
+	if len(first.Cookies()) != 1 {
+		t.Fatalf("cookies %v, want the session cookie", first.Cookies())
+	}
+	c := first.Cookies()[0]
+	if !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Path != "/" {
+		t.Errorf("the session cookie %v is not HttpOnly, SameSite=Lax on /", c)
+	}
+	if strings.Contains(c.Value, userID) {
+		t.Errorf("the session cookie %q holds the user ID %s", c.Value, userID)
+	}
+	owner := userID
+
+	if res := get("/", c); userID != owner || len(res.Cookies()) != 0 {
+		t.Errorf("the session was not kept: user %s, cookies %v", userID, res.Cookies())
+	}
+	for _, forged := range []string{owner, c.Value[:strings.LastIndexByte(c.Value, '.')] + ".forged", "x" + c.Value} {
+		if get("/", &http.Cookie{Name: cookieSessionID, Value: forged}); userID == owner {
+			t.Errorf("the cookie %q gives the session of %s", forged, owner)
+		}
+	}
+
+	res := get("/logout", c, &http.Cookie{Name: cookieCurrency, Value: "EUR"})
+	if userID != owner {
+		t.Fatalf("logout ran in the session of %s, want %s", userID, owner)
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, c := range res.Cookies() {
+		cookies[c.Name] = c
+	}
+	if c := cookies[cookieCurrency]; c == nil || c.MaxAge >= 0 {
+		t.Errorf("the currency cookie was not expired: %v", c)
+	}
+	renewed := cookies[cookieSessionID]
+	if renewed == nil || renewed.Value == c.Value {
+		t.Fatalf("logout did not start a new session: %v", renewed)
+	}
+	if get("/", renewed); userID == owner {
+		t.Error("the new session is that of the old user")
+	}
+	if get("/", c); userID == owner {
+		t.Error("the session still works after logout")
+	}
+}
+
+func TestProbesStartNoSession(t *testing.T) {
+	fe := &frontendServer{sessions: testSessions()}
+	h := fe.ensureSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, path := range []string{"/_healthz", "/_readyz", "/metrics", "/static/styles/styles.css"} {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if c := rec.Result().Cookies(); len(c) != 0 {
+			t.Errorf("%s started a session: %v", path, c)
+		}
+	}
+}
+
+func TestSecureCookies(t *testing.T) {
+	m := testSessions()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if m.Cookie(req, cookieCurrency, "EUR", 60).Secure {
+		t.Error("a cookie over plain HTTP is Secure")
+	}
+	req.Header.Set("X-Forwarded-Proto", "https")
+	if !m.Cookie(req, cookieCurrency, "EUR", 60).Secure {
+		t.Error("a cookie behind a TLS proxy is not Secure")
+	}
+	m.SecureCookies = true
+	if !m.Cookie(httptest.NewRequest(http.MethodGet, "/", nil), cookieCurrency, "EUR", 60).Secure {
+		t.Error("SECURE_COOKIES does not make every cookie Secure")
+	}
+}
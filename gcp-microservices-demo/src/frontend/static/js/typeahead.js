@@ -0,0 +1,37 @@
+// Suggests the products matching the search box as the user types, from
+// the JSON variant of /search. The search form works without it.
+(function () {
+  var input = document.querySelector('input[data-typeahead]');
+  if (!input || !window.fetch) {
+    return;
+  }
+  var list = document.getElementById(input.getAttribute('list'));
+  var timer, last;
+  input.addEventListener('input', function () {
+    clearTimeout(timer);
+    timer = setTimeout(function () {
+      var q = input.value.trim();
+      if (q.length < 2 || q === last) {
+        return;
+      }
+      last = q;
+      fetch(input.dataset.typeahead + '&q=' + encodeURIComponent(q), {
+        headers: { 'Accept': 'application/json' },
+        credentials: 'same-origin'
+      }).then(function (res) {
+        return res.ok ? res.json() : { results: [] };
+      }).then(function (data) {
+        if (data.query !== input.value.trim()) {
+          return;
+        }
+        list.innerHTML = '';
+        data.results.forEach(function (r) {
+          var option = document.createElement('option');
+          option.value = r.name;
+          option.label = r.name + ' ' + r.price_text;
+          list.appendChild(option);
+        });
+      }).catch(function () {});
+    }, 150);
+  });
+})();
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// trackShipment returns the timeline of the shipment trackingID.
+func (l *loader) trackShipment(trackingID string) func(ctx context.Context) (*shop.TrackShipmentResponse, error) {
+	p := l.start(l.ctx, "track/"+trackingID, func(ctx context.Context) (interface{}, error) {
+		return l.fe.client.Shipping.TrackShipment(ctx, &shop.TrackShipmentRequest{TrackingId: trackingID})
+	})
+	return func(ctx context.Context) (*shop.TrackShipmentResponse, error) {
+		v, err := p.wait(ctx)
+		out, _ := v.(*shop.TrackShipmentResponse)
+		return out, err
+	}
+}
+
+type shipmentEventView struct {
+	Description string
+	Time        string
+	Done        bool
+}
+
+// formatEventTime formats the RFC 3339 time of a shipment event, or answers
+// "" if shippingservice did not say.
+func formatEventTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return ""
+	}
+	return t.Format("Mon, January 2 15:04 MST")
+}
+
+// trackHandler renders the timeline of a shipment. The tracking ID tells
+// shippingservice when the shipment was created, so any shipment can be
+// tracked from its ID, as with a carrier.
+func (fe *frontendServer) trackHandler(w http.ResponseWriter, r *http.Request) {
+	log := r.Context().Value(ctxKeyLog{}).(logrus.FieldLogger)
+	id := mux.Vars(r)["id"]
+	log.WithField("tracking", id).Debug("serving tracking page")
+
+	l := fe.newLoader(r.Context())
+	defer l.close()
+	loadCurrencies, loadCart := l.currencies(), l.cart(sessionID(r))
+
+	tracking, err := l.trackShipment(id)(l.ctx)
+	if shop.IsNotFound(err) {
+		fe.shipmentNotFound(w, r, log, l, id)
+		return
+	}
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not track shipment"), http.StatusInternalServerError)
+		return
+	}
+	currencies, err := loadCurrencies(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := loadCart(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]shipmentEventView, len(tracking.GetEvents()))
+	status := ""
+	for i, e := range tracking.GetEvents() {
+		events[i] = shipmentEventView{Description: e.GetDescription(), Time: formatEventTime(e.GetTime()), Done: e.GetDone()}
+		if e.GetStatus() == tracking.GetStatus() {
+			status = e.GetDescription()
+		}
+	}
+
+	if err := executeTemplate(r.Context(), w, "track", map[string]interface{}{
+		"session_id":         sessionID(r),
+		"request_id":         r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":      currentCurrency(r),
+		"show_currency":      false,
+		"currencies":         currencies,
+		"cart_size":          cartSize(cart),
+		"tracking_id":        id,
+		"status":             status,
+		"delivered":          tracking.GetStatus() == "delivered",
+		"events":             events,
+		"estimated_delivery": formatEventTime(tracking.GetEstimatedDelivery()),
+		"platform_css":       plat.css,
+		"platform_name":      plat.provider,
+		"is_cymbal_brand":    isCymbalBrand,
+		"deploymentDetails":  deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// shipmentNotFound answers 404 with a page pointing to the order history.
+func (fe *frontendServer) shipmentNotFound(w http.ResponseWriter, r *http.Request, log logrus.FieldLogger, l *loader, id string) {
+	log.WithField("tracking", id).Info("shipment not found")
+	currencies, err := l.currencies()(l.ctx)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	if err := executeTemplate(r.Context(), w, "shipment_not_found", map[string]interface{}{
+		"session_id":        sessionID(r),
+		"request_id":        r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":     currentCurrency(r),
+		"show_currency":     false,
+		"currencies":        currencies,
+		"tracking_id":       id,
+		"platform_css":      plat.css,
+		"platform_name":     plat.provider,
+		"is_cymbal_brand":   isCymbalBrand,
+		"deploymentDetails": deploymentDetailsMap,
+	}); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+func TestTrackPage(t *testing.T) {
+	// The shipment is tracked from its ID alone, whether or not the session
+	// has orders.
+	fe := orderStandIns(t, 0)
+	order := testOrder("order-1").GetOrder()
+	track := func(id string) *httptest.ResponseRecorder {
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/track/"+id, nil), map[string]string{"id": id})
+		return servePage(fe.trackHandler, req, "/track/{id}")
+	}
+
+	rec := track(order.GetShippingTrackingId())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d:\n%s", rec.Code, rec.Body)
+	}
+	page := rec.Body.String()
+	for _, s := range []string{
+		"Shipment #RS-12345-678901234-S0K6G0", "Picked up by the carrier, in transit, expected by Fri, September 8 15:00 UTC",
+		`shipment-event-done`, "Wed, September 6 10:00 UTC", "Expected Fri, September 8 10:00 UTC",
+		`href="/orders" role="button"`,
+	} {
+		if !strings.Contains(page, s) {
+			t.Errorf("the tracking page lacks %s", s)
+		}
+	}
+
+	rec = track("QT-54321-876543210")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Shipment not found") {
+		t.Errorf("an unknown shipment: status %d:\n%s", rec.Code, rec.Body)
+	}
+}
+
+// TestTrackingLinks checks that the order pages link to the tracking page.
+func TestTrackingLinks(t *testing.T) {
+	fe := orderStandIns(t, 1)
+	link := `href="/track/RS-12345-678901234-S0K6G0"`
+	rec := servePage(fe.ordersHandler, httptest.NewRequest(http.MethodGet, "/orders", nil), "/orders")
+	if !strings.Contains(rec.Body.String(), link) {
+		t.Errorf("the order history lacks %s", link)
+	}
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/orders/order-1", nil), map[string]string{"id": "order-1"})
+	rec = servePage(fe.orderHandler, req, "/orders/{id}")
+	if !strings.Contains(rec.Body.String(), link) {
+		t.Errorf("the order page lacks %s", link)
+	}
+	var b strings.Builder
+	if err := templates.ExecuteTemplate(&b, "order", map[string]interface{}{
+		"order":      testOrder("order-1").GetOrder(),
+		"total_paid": &shop.Money{CurrencyCode: "EUR", Units: 41},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), link) {
+		t.Errorf("the order confirmation lacks %s", link)
+	}
+}
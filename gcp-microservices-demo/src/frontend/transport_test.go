@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
+)
+
+var (
+	fakeAds = &shop.AdResponse{Ads: []*shop.Ad{{RedirectUrl: "/product/66VCHSJNUP", Text: "Tank top for sale. 20% off."}}}
+
+	fakeAdService = rpc.NewService(shop.AdService, rpc.Method{
+		Name: "GetAds",
+		New:  func() interface{} { return new(shop.AdRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return fakeAds, nil
+		},
+	})
+)
+
+func fakeAdHandler(w http.ResponseWriter, r *http.Request) {
+	if fakeAdService.ServeConnect(w, r) {
+		return
+	}
+	w.Write([]byte(`{"ads":[{"redirect_url":"/product/66VCHSJNUP","text":"Tank top for sale. 20% off."}]}`))
+}
+
+// TestTransports fetches ads over HTTP/JSON, gRPC and Connect.
+func TestTransports(t *testing.T) {
+	opts := rpctest.Options(t, shop.AdService, http.HandlerFunc(fakeAdHandler), fakeAdService)
+	for _, transport := range rpc.Transports {
+		fe := &frontendServer{client: shop.New(opts[transport])}
+		ads, err := fe.getAd(context.Background(), []string{"clothing"})
+		if err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if len(ads) != 1 || ads[0].GetText() != fakeAds.Ads[0].Text {
+			t.Errorf("%s: getAd = %+v", transport, ads)
+		}
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	fe := &frontendServer{
+		productCatalogSvcAddr: "http://productcatalogservice",
+		shippingSvcAddr:       "http://shippingservice",
+		checkoutSvcAddr:       "http://checkoutservice",
+		adSvcAddr:             "http://adservice",
+	}
+	if opts, err := fe.transportOptions(rpc.Connect); err != nil || len(opts) != 4 {
+		t.Errorf("connect: %d options, %v", len(opts), err)
+	}
+	if _, err := fe.transportOptions(rpc.GRPC); err == nil {
+		t.Error("grpc without *_GRPC_ADDR must fail")
+	}
+	if _, err := fe.transportOptions("carrier-pigeon"); err == nil {
+		t.Error("unknown transports must fail")
+	}
+}
@@ -15,6 +15,7 @@
 # limitations under the License.
 
 import random
+import re
 from locust import HttpUser, TaskSet, between
 
 products = [
@@ -28,13 +29,23 @@ products = [
     'LS4PSXUNUM',
     'OLJCESPC7Z']
 
+# The forms of the frontend carry the CSRF token of the session, which
+# every post must send back.
+csrf_token = re.compile(r'name="csrf_token" value="([^"]+)"')
+
 def index(l):
-    l.client.get("/")
+    page = l.client.get("/")
+    m = csrf_token.search(page.text)
+    if m:
+        l.csrf_token = m.group(1)
+
+def form(l, fields):
+    return dict(fields, csrf_token=getattr(l, 'csrf_token', ''))
 
 def setCurrency(l):
     currencies = ['EUR', 'USD', 'JPY', 'CAD']
     l.client.post("/setCurrency",
-        {'currency_code': random.choice(currencies)})
+        form(l, {'currency_code': random.choice(currencies)}))
 
 def browseProduct(l):
     l.client.get("/product/" + random.choice(products))
@@ -45,13 +56,13 @@ def viewCart(l):
 def addToCart(l):
     product = random.choice(products)
     l.client.get("/product/" + product)
-    l.client.post("/cart", {
+    l.client.post("/cart", form(l, {
         'product_id': product,
-        'quantity': random.choice([1,2,3,4,5,10])})
+        'quantity': random.choice([1,2,3,4,5,10])}))
 
 def checkout(l):
     addToCart(l)
-    l.client.post("/cart/checkout", {
+    l.client.post("/cart/checkout", form(l, {
         'email': 'someone@example.com',
         'street_address': '1600 Amphitheatre Parkway',
         'zip_code': '94043',
@@ -62,7 +73,7 @@ def checkout(l):
         'credit_card_expiration_month': '1',
         'credit_card_expiration_year': '2039',
         'credit_card_cvv': '672',
-    })
+    }))
 
 class UserBehavior(TaskSet):
 
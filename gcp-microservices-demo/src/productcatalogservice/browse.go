@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultPageSize and maxPageSize bound the pages BrowseProducts answers.
+	defaultPageSize = 12
+	maxPageSize     = 100
+	// maxPriceUsd bounds the price range, so that it still fits an int64 in
+	// billionths of a dollar.
+	maxPriceUsd = 1000000000
+)
+
+// nanosUsd is the price of p in billionths of a US dollar, for comparisons.
+func nanosUsd(p *Product) int64 {
+	if p.PriceUsd == nil {
+		return 0
+	}
+	return p.PriceUsd.Units*1e9 + int64(p.PriceUsd.Nanos)
+}
+
+// hasCategory reports whether p is in category.
+func hasCategory(p *Product, category string) bool {
+	for _, c := range p.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// categories returns the categories of products, sorted and without
+// duplicates.
+func categories(products []*Product) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, p := range products {
+		for _, c := range p.Categories {
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// BrowseProducts answers a page of the products of a category within a
+// price range, in the requested order. Pages past the last one are empty.
+// The categories answered are those of the whole catalog, whatever the
+// filters, so that a shopper can always move to another one.
+func BrowseProducts(req *BrowseProductsRequest) (*BrowseProductsResponse, error) {
+	catalog := parseCatalog()
+	var ps []*Product
+	for _, p := range catalog {
+		price := nanosUsd(p)
+		switch {
+		case req.Category != "" && !hasCategory(p, req.Category),
+			price < req.MinPriceUsd*1e9,
+			req.MaxPriceUsd > 0 && price > req.MaxPriceUsd*1e9:
+			continue
+		}
+		ps = append(ps, p)
+	}
+
+	var less func(a, b *Product) bool
+	switch strings.TrimPrefix(req.Sort, "-") {
+	case "price":
+		less = func(a, b *Product) bool { return nanosUsd(a) < nanosUsd(b) }
+	case "name":
+		less = func(a, b *Product) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	}
+	if less != nil {
+		desc := strings.HasPrefix(req.Sort, "-")
+		sort.SliceStable(ps, func(i, j int) bool {
+			if desc {
+				return less(ps[j], ps[i])
+			}
+			return less(ps[i], ps[j])
+		})
+	}
+
+	size := int(req.PageSize)
+	if size == 0 {
+		size = defaultPageSize
+	}
+	page := int(req.Page)
+	if page == 0 {
+		page = 1
+	}
+	resp := &BrowseProductsResponse{TotalSize: int32(len(ps)), Categories: categories(catalog)}
+	if start := (page - 1) * size; start < len(ps) {
+		end := start + size
+		if end > len(ps) {
+			end = len(ps)
+		}
+		resp.Products = ps[start:end]
+	}
+	return resp, nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+)
+
+// serveGRPC serves the routes of the function over gRPC on GRPC_ADDR in
+// the background if it is set.
+func serveGRPC() {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		return
+	}
+	go func() {
+		log.Infof("serving gRPC on %s", addr)
+		if err := rpc.ListenAndServe(addr, router.RPC()); err != nil {
+			log.Errorf("gRPC server failed: %v", err)
+		}
+	}()
+}
@@ -1,5 +1,10 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Product struct {
 	Id          string `json:"id,omitempty"`
 	Name        string `json:"name,omitempty"`
@@ -42,3 +47,43 @@ type SearchProductsRequest struct {
 type SearchProductsResponse struct {
 	Results []*Product `json:"results,omitempty"`
 }
+
+type BrowseProductsRequest struct {
+	Category    string `json:"category,omitempty"`
+	MinPriceUsd int64  `json:"min_price_usd,omitempty"`
+	MaxPriceUsd int64  `json:"max_price_usd,omitempty"`
+	Sort        string `json:"sort,omitempty"`
+	Page        int32  `json:"page,omitempty"`
+	PageSize    int32  `json:"page_size,omitempty"`
+}
+
+type BrowseProductsResponse struct {
+	Products []*Product `json:"products,omitempty"`
+	// The number of products matching the filters, on every page.
+	TotalSize int32 `json:"total_size,omitempty"`
+	// Every category of the catalog, sorted, for navigation.
+	Categories []string `json:"categories,omitempty"`
+}
+
+// Validate reports an unknown sort order, a price range out of bounds or
+// inverted and pages out of bounds.
+func (m *BrowseProductsRequest) Validate() error {
+	switch m.Sort {
+	case "", "price", "-price", "name", "-name":
+	default:
+		return fmt.Errorf("unknown sort %q, want price, -price, name or -name", m.Sort)
+	}
+	if m.MinPriceUsd < 0 || m.MinPriceUsd > maxPriceUsd || m.MaxPriceUsd < 0 || m.MaxPriceUsd > maxPriceUsd {
+		return fmt.Errorf("prices must be between 0 and %d", maxPriceUsd)
+	}
+	if m.MaxPriceUsd > 0 && m.MaxPriceUsd < m.MinPriceUsd {
+		return errors.New("max_price_usd must not be below min_price_usd")
+	}
+	if m.Page < 0 {
+		return errors.New("page must not be negative")
+	}
+	if m.PageSize < 0 || m.PageSize > maxPageSize {
+		return fmt.Errorf("page_size must be between 0 and %d", maxPageSize)
+	}
+	return nil
+}
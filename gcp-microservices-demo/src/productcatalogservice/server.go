@@ -15,121 +15,76 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
-	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
 var (
-	cat ListProductsResponse
-	log *logrus.Logger
+	cat    ListProductsResponse
+	log    *logging.Logger
+	router *fission.Router
 )
 
 func init() {
-	log = logrus.New()
-	log.Formatter = &logrus.JSONFormatter{
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "severity",
-			logrus.FieldKeyMsg:   "message",
-		},
-		TimestampFormat: time.RFC3339Nano,
+	log = logging.NewFromEnv()
+	if _, err := telemetry.Setup(context.Background(), telemetry.FromEnv(shop.CatalogService.String())); err != nil {
+		log.Warnf("could not set up telemetry: %v", err)
 	}
-	log.Out = os.Stdout
 	err := getCatalogData(&cat)
 	if err != nil {
 		log.Warnf("could not parse product catalog")
 	}
+	router = fission.NewRouter(shop.CatalogService, log,
+		fission.Route{
+			Name:   "ListProducts",
+			Method: http.MethodGet,
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return ListProducts()
+			},
+		},
+		fission.Route{
+			Name:   "GetProduct",
+			Method: http.MethodGet,
+			Params: []string{"id"},
+			New:    func() interface{} { return new(GetProductRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return GetProduct(in.(*GetProductRequest))
+			},
+		},
+		fission.Route{
+			Name:   "SearchProducts",
+			Method: http.MethodGet,
+			Params: []string{"query"},
+			New:    func() interface{} { return new(SearchProductsRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return SearchProducts(in.(*SearchProductsRequest))
+			},
+		},
+		fission.Route{
+			Name:   "BrowseProducts",
+			Method: http.MethodGet,
+			// Every filter is optional; a request without any is
+			// ListProducts.
+			Optional: []string{"category", "min_price_usd", "max_price_usd", "sort", "page", "page_size"},
+			New:      func() interface{} { return new(BrowseProductsRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return BrowseProducts(in.(*BrowseProductsRequest))
+			},
+		},
+	)
+	serveGRPC()
 }
 
 // Handler is the entry point for this fission function
 func Handler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		log.Errorf("methods other than GET are not supported")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	v := r.URL.Query()
-	if len(v) == 0 { //empty
-		result, err := ListProducts()
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(result)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else if v.Has("id") && !v.Has("query") {
-		if len(v["id"]) > 1 {
-			log.Errorf("could not get more than one product")
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		result, err := GetProduct(&GetProductRequest{Id: v.Get("id")})
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(result)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else if !v.Has("id") && v.Has("query") {
-		if len(v["query"]) > 1 {
-			log.Errorf("could not search more than one product")
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		result, err := SearchProducts(&SearchProductsRequest{Query: v.Get("query")})
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(result)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else {
-		log.Errorf("parameters of method GET are incorrect")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
+	router.ServeHTTP(w, r)
 }
 
 func getCatalogData(catalog *ListProductsResponse) error {
@@ -163,7 +118,7 @@ func GetProduct(req *GetProductRequest) (*Product, error) {
 		}
 	}
 	if found == nil {
-		return nil, fmt.Errorf("no product with ID %s", req.Id)
+		return nil, fission.NotFound("no product with ID %s", req.Id)
 	}
 	return found, nil
 }
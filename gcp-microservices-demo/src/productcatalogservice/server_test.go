@@ -15,9 +15,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/contract"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/openapi"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
 )
 
 func TestServer(t *testing.T) {
@@ -46,3 +60,162 @@ func TestServer(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestBrowseProducts(t *testing.T) {
+	names := func(ps []*Product) []string {
+		out := []string{}
+		for _, p := range ps {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		req   BrowseProductsRequest
+		want  []string
+		total int32
+	}{
+		{BrowseProductsRequest{Category: "kitchen", Sort: "price"}, []string{"Bamboo Glass Jar", "Mug", "Salt & Pepper Shakers"}, 3},
+		{BrowseProductsRequest{Category: "kitchen", Sort: "-name"}, []string{"Salt & Pepper Shakers", "Mug", "Bamboo Glass Jar"}, 3},
+		{BrowseProductsRequest{Category: "kitchen", Sort: "price", Page: 2, PageSize: 2}, []string{"Salt & Pepper Shakers"}, 3},
+		{BrowseProductsRequest{Category: "kitchen", Page: 3, PageSize: 2}, []string{}, 3},
+		{BrowseProductsRequest{MinPriceUsd: 20, MaxPriceUsd: 100, Sort: "-price"}, []string{"Loafers", "Hairdryer"}, 2},
+		{BrowseProductsRequest{MaxPriceUsd: 19, Sort: "name", PageSize: 3}, []string{"Bamboo Glass Jar", "Candle Holder", "Mug"}, 5},
+		{BrowseProductsRequest{Sort: "-price", PageSize: 3}, []string{"Watch", "Loafers", "Hairdryer"}, 9},
+		{BrowseProductsRequest{Category: "garden"}, []string{}, 0},
+	}
+	for _, tt := range tests {
+		res, err := BrowseProducts(&tt.req)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.req, err)
+		}
+		if diff := cmp.Diff(tt.want, names(res.Products)); diff != "" {
+			t.Errorf("%+v: products (-want +got):\n%s", tt.req, diff)
+		}
+		if res.TotalSize != tt.total {
+			t.Errorf("%+v: total_size %d, want %d", tt.req, res.TotalSize, tt.total)
+		}
+		want := []string{"accessories", "beauty", "clothing", "decor", "footwear", "hair", "home", "kitchen", "tops"}
+		if diff := cmp.Diff(want, res.Categories); diff != "" {
+			t.Errorf("%+v: categories (-want +got):\n%s", tt.req, diff)
+		}
+	}
+
+	for _, q := range []string{
+		"sort=rating&category=&min_price_usd=0&max_price_usd=0&page=0&page_size=0",
+		"sort=&category=&min_price_usd=50&max_price_usd=20&page=0&page_size=0",
+		"sort=&category=&min_price_usd=10000000000&max_price_usd=0&page=0&page_size=0",
+		"sort=&category=&min_price_usd=0&max_price_usd=10000000000&page=0&page_size=0",
+		"sort=&category=&min_price_usd=0&max_price_usd=0&page=-1&page_size=0",
+		"sort=&category=&min_price_usd=0&max_price_usd=0&page=0&page_size=101",
+	} {
+		rec := httptest.NewRecorder()
+		Handler(rec, httptest.NewRequest(http.MethodGet, "/?"+q, nil))
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422", q, rec.Code)
+		}
+	}
+
+	// Every filter is optional: a single one browses the catalog.
+	for _, tt := range []struct {
+		query    string
+		products int
+		total    int32
+	}{
+		{"category=kitchen", 3, 3},
+		{"sort=price", 9, 9},
+		{"max_price_usd=19", 5, 5},
+		{"page=2&page_size=4", 4, 9},
+	} {
+		rec := httptest.NewRecorder()
+		Handler(rec, httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want 200: %s", tt.query, rec.Code, rec.Body)
+			continue
+		}
+		var res BrowseProductsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Products) != tt.products || res.TotalSize != tt.total || len(res.Categories) == 0 {
+			t.Errorf("%s: %d products of %d in %v, want %d of %d", tt.query, len(res.Products), res.TotalSize, res.Categories, tt.products, tt.total)
+		}
+	}
+}
+
+func TestConformsToSpec(t *testing.T) {
+	spec, err := openapi.Load("../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{Product{}, Money{}, ListProductsResponse{}, GetProductRequest{}, SearchProductsRequest{}, SearchProductsResponse{}, BrowseProductsRequest{}, BrowseProductsResponse{}} {
+		for _, err := range spec.CheckType(reflect.TypeOf(v).Name(), v) {
+			t.Error(err)
+		}
+	}
+	for _, err := range spec.CheckHandler("productcatalogservice", http.HandlerFunc(Handler)) {
+		t.Error(err)
+	}
+}
+
+// TestHonorsContracts replays the pacts recorded by the consumers of this
+// function.
+func TestHonorsContracts(t *testing.T) {
+	contract.Verify(t, "../../contracts", "productcatalogservice", http.HandlerFunc(Handler), nil)
+}
+
+// TestTransports calls the catalog over HTTP/JSON, gRPC and Connect.
+func TestTransports(t *testing.T) {
+	clients := rpctest.Clients(t, shop.CatalogService, http.HandlerFunc(Handler), router.RPC())
+	for _, transport := range rpc.Transports {
+		c := clients[transport]
+		t.Run(transport, func(t *testing.T) {
+			ctx := context.Background()
+			list, err := c.Catalog.ListProducts(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := len(list.GetProducts()), len(parseCatalog()); got != want {
+				t.Errorf("ListProducts returned %d products, want %d", got, want)
+			}
+			p, err := c.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: "OLJCESPC7Z"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.GetName() != parseCatalog()[0].Name {
+				t.Errorf("GetProduct = %+v", p)
+			}
+			if _, err := c.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: "missing"}); err == nil {
+				t.Error("GetProduct of an unknown ID succeeded")
+			}
+			res, err := c.Catalog.SearchProducts(ctx, &shop.SearchProductsRequest{Query: "sunglasses"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.GetResults()) != 1 {
+				t.Errorf("SearchProducts = %+v", res)
+			}
+			page, err := c.Catalog.BrowseProducts(ctx, &shop.BrowseProductsRequest{Category: "kitchen", Sort: "price", Page: 1, PageSize: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.GetProducts()) != 2 || page.GetTotalSize() != 3 {
+				t.Errorf("BrowseProducts = %+v", page)
+			}
+		})
+	}
+}
+
+// TestRequestIDInLogs checks that the request ID sent by checkout or the
+// frontend is logged with the request.
+func TestRequestIDInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log.Out = &buf
+	defer func() { log.Out = os.Stdout }()
+
+	r := httptest.NewRequest(http.MethodGet, "/?id=OLJCESPC7Z", nil)
+	r.Header.Set(shop.RequestIDHeader, "req-1")
+	Handler(httptest.NewRecorder(), r)
+	if !strings.Contains(buf.String(), `"http.req.id":"req-1"`) {
+		t.Errorf("request ID not logged:\n%s", buf.String())
+	}
+}
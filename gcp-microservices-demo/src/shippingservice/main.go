@@ -15,109 +15,64 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
 )
 
-var log *logrus.Logger
+var (
+	log    *logging.Logger
+	router *fission.Router
+)
 
 func init() {
-	log = logrus.New()
-	log.Level = logrus.DebugLevel
-	log.Formatter = &logrus.JSONFormatter{
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "severity",
-			logrus.FieldKeyMsg:   "message",
-		},
-		TimestampFormat: time.RFC3339Nano,
+	log = logging.NewFromEnv()
+	if _, err := telemetry.Setup(context.Background(), telemetry.FromEnv(shop.ShippingService.String())); err != nil {
+		log.Warnf("could not set up telemetry: %v", err)
 	}
-	log.Out = os.Stdout
+	router = fission.NewRouter(shop.ShippingService, log,
+		fission.Route{
+			Name:   "GetQuote",
+			Method: http.MethodPost,
+			New:    func() interface{} { return new(GetQuoteRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return GetQuote(ctx, in.(*GetQuoteRequest))
+			},
+		},
+		fission.Route{
+			Name:   "ShipOrder",
+			Method: http.MethodPut,
+			New:    func() interface{} { return new(ShipOrderRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return ShipOrder(ctx, in.(*ShipOrderRequest))
+			},
+		},
+		fission.Route{
+			Name:   "TrackShipment",
+			Method: http.MethodGet,
+			Params: []string{"tracking_id"},
+			New:    func() interface{} { return new(TrackShipmentRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return TrackShipment(ctx, in.(*TrackShipmentRequest))
+			},
+		},
+	)
+	serveGRPC()
 }
 
 // Handler is the entry point for this fission function
 func Handler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "POST" {
-		raw_req, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		req := new(GetQuoteRequest)
-		err = json.Unmarshal(raw_req, req)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		res, err := GetQuote(req)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(res)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else if r.Method == "PUT" {
-		raw_req, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		req := new(ShipOrderRequest)
-		err = json.Unmarshal(raw_req, req)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		res, err := ShipOrder(req)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		body, err := json.Marshal(res)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-		w.Header().Set("content-type", "application/json")
-		_, err = w.Write(body)
-		if err != nil {
-			log.Error(err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
-	} else {
-		log.Errorf("methods other than POST and PUT are not supported")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
+	router.ServeHTTP(w, r)
 }
 
 // GetQuote produces a shipping quote (cost) in USD.
-func GetQuote(in *GetQuoteRequest) (*GetQuoteResponse, error) {
+func GetQuote(ctx context.Context, in *GetQuoteRequest) (*GetQuoteResponse, error) {
+	log := fission.Logger(ctx)
 	log.Info("[GetQuote] received request")
 	defer log.Info("[GetQuote] completed request")
 
@@ -136,15 +91,31 @@ func GetQuote(in *GetQuoteRequest) (*GetQuoteResponse, error) {
 
 // ShipOrder mocks that the requested items will be shipped.
 // It supplies a tracking ID for notional lookup of shipment delivery status.
-func ShipOrder(in *ShipOrderRequest) (*ShipOrderResponse, error) {
+func ShipOrder(ctx context.Context, in *ShipOrderRequest) (*ShipOrderResponse, error) {
+	log := fission.Logger(ctx)
 	log.Info("[ShipOrder] received request")
 	defer log.Info("[ShipOrder] completed request")
 	// 1. Create a Tracking ID
 	baseAddress := fmt.Sprintf("%s, %s, %s", in.Address.StreetAddress, in.Address.City, in.Address.State)
-	id := CreateTrackingId(baseAddress)
+	id := stampTrackingID(CreateTrackingId(baseAddress), now())
 
 	// 2. Generate a response.
 	return &ShipOrderResponse{
 		TrackingId: id,
 	}, nil
 }
+
+// TrackShipment answers the simulated timeline of a shipment, which starts
+// at the creation time carried by the tracking ID. IDs that ShipOrder cannot
+// have made are not found.
+func TrackShipment(ctx context.Context, in *TrackShipmentRequest) (*TrackShipmentResponse, error) {
+	log := fission.Logger(ctx)
+	log.Info("[TrackShipment] received request")
+	defer log.Info("[TrackShipment] completed request")
+
+	created, ok := trackingCreated(in.TrackingId)
+	if !ok {
+		return nil, fission.NotFound("no shipment %s", in.TrackingId)
+	}
+	return track(in.TrackingId, created, now()), nil
+}
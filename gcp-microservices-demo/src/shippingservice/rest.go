@@ -1,8 +1,10 @@
 package main
 
+import "errors"
+
 type GetQuoteRequest struct {
-	Address *Address    ` json:"address,omitempty"`
-	Items   []*CartItem ` json:"items,omitempty"`
+	Address *Address    `json:"address,omitempty"`
+	Items   []*CartItem `json:"items,omitempty"`
 }
 
 type Address struct {
@@ -67,3 +69,37 @@ func (m *Money) GetNanos() int32 {
 	}
 	return 0
 }
+
+// Validate reports a request that has nowhere to ship to.
+func (m *ShipOrderRequest) Validate() error {
+	if m.Address == nil {
+		return errors.New("address is required")
+	}
+	return nil
+}
+
+type TrackShipmentRequest struct {
+	TrackingId string `json:"tracking_id,omitempty"`
+}
+
+type ShipmentEvent struct {
+	Status      string `json:"status,omitempty"`
+	Description string `json:"description,omitempty"`
+	Time        string `json:"time,omitempty"`
+	Done        bool   `json:"done,omitempty"`
+}
+
+type TrackShipmentResponse struct {
+	TrackingId        string           `json:"tracking_id,omitempty"`
+	Status            string           `json:"status,omitempty"`
+	Events            []*ShipmentEvent `json:"events,omitempty"`
+	EstimatedDelivery string           `json:"estimated_delivery,omitempty"`
+}
+
+// Validate reports a lookup that lacks the tracking ID.
+func (m *TrackShipmentRequest) Validate() error {
+	if m.TrackingId == "" {
+		return errors.New("tracking_id is required")
+	}
+	return nil
+}
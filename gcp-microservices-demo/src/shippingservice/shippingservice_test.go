@@ -15,7 +15,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/contract"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/openapi"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
 )
 
 // TestGetQuote is a basic check on the GetQuote RPC service.
@@ -40,7 +54,7 @@ func TestGetQuote(t *testing.T) {
 		},
 	}
 
-	res, err := GetQuote(req)
+	res, err := GetQuote(context.Background(), req)
 	if err != nil {
 		t.Errorf("TestGetQuote (%v) failed", err)
 	}
@@ -71,12 +85,136 @@ func TestShipOrder(t *testing.T) {
 		},
 	}
 
-	res, err := ShipOrder(req)
+	res, err := ShipOrder(context.Background(), req)
 	if err != nil {
 		t.Errorf("TestShipOrder (%v) failed", err)
 	}
-	// @todo improve quality of this test to check for a pattern such as '[A-Z]{2}-\d+-\d+'.
-	if len(res.TrackingId) != 18 {
-		t.Errorf("TestShipOrder: Tracking ID is malformed - has %d characters, %d expected", len(res.TrackingId), 18)
+	if !regexp.MustCompile(`^[A-Z]{2}-\d{5}-\d{9}-[0-9A-Z]+$`).MatchString(res.TrackingId) {
+		t.Errorf("TestShipOrder: Tracking ID %s is malformed", res.TrackingId)
+	}
+	if created, ok := trackingCreated(res.TrackingId); !ok || time.Since(created) > time.Minute {
+		t.Errorf("TestShipOrder: Tracking ID %s carries the creation time %v", res.TrackingId, created)
+	}
+}
+
+func TestConformsToSpec(t *testing.T) {
+	spec, err := openapi.Load("../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{GetQuoteRequest{}, GetQuoteResponse{}, ShipOrderRequest{}, ShipOrderResponse{}, TrackShipmentRequest{}, TrackShipmentResponse{}, ShipmentEvent{}, Address{}, CartItem{}, Money{}} {
+		for _, err := range spec.CheckType(reflect.TypeOf(v).Name(), v) {
+			t.Error(err)
+		}
+	}
+	for _, err := range spec.CheckHandler("shippingservice", http.HandlerFunc(Handler)) {
+		t.Error(err)
+	}
+}
+
+// TestHonorsContracts replays the pacts recorded by the consumers of this
+// function.
+func TestHonorsContracts(t *testing.T) {
+	contract.Verify(t, "../../contracts", "shippingservice", http.HandlerFunc(Handler), nil)
+}
+
+// TestTransports calls shipping over HTTP/JSON, gRPC and Connect.
+func TestTransports(t *testing.T) {
+	clients := rpctest.Clients(t, shop.ShippingService, http.HandlerFunc(Handler), router.RPC())
+	address := &shop.Address{StreetAddress: "Muffin Man", City: "London", Country: "England"}
+	items := []*shop.CartItem{{ProductId: "23", Quantity: 1}}
+	for _, transport := range rpc.Transports {
+		c := clients[transport]
+		t.Run(transport, func(t *testing.T) {
+			ctx := context.Background()
+			quote, err := c.Shipping.GetQuote(ctx, &shop.GetQuoteRequest{Address: address, Items: items})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cost := quote.GetCostUsd(); cost.GetUnits() != 8 || cost.GetNanos() != 990000000 {
+				t.Errorf("GetQuote = %+v", cost)
+			}
+			order, err := c.Shipping.ShipOrder(ctx, &shop.ShipOrderRequest{Address: address, Items: items})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order.GetTrackingId() == "" {
+				t.Error("ShipOrder returned no tracking ID")
+			}
+			tracking, err := c.Shipping.TrackShipment(ctx, &shop.TrackShipmentRequest{TrackingId: order.GetTrackingId()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tracking.GetStatus() != StatusLabelCreated || len(tracking.GetEvents()) != 4 {
+				t.Errorf("TrackShipment = %+v", tracking)
+			}
+		})
+	}
+}
+
+// TestTrackShipment follows a shipment along its timeline, which depends on
+// nothing but the tracking ID and the creation time it carries.
+func TestTrackShipment(t *testing.T) {
+	created := time.Date(2023, 9, 6, 10, 0, 0, 0, time.UTC)
+	events := timeline("RS-12345-678901234", created)
+	for i := 1; i < len(events); i++ {
+		if !events[i].at.After(events[i-1].at) {
+			t.Fatalf("%s is not after %s", events[i].status, events[i-1].status)
+		}
+	}
+	if !reflect.DeepEqual(events, timeline("RS-12345-678901234", created)) {
+		t.Error("the timeline of a shipment changes between lookups")
+	}
+	if reflect.DeepEqual(events, timeline("QT-54321-876543210", created)) {
+		t.Error("two shipments follow the same timeline")
+	}
+
+	for i, want := range []string{StatusLabelCreated, StatusInTransit, StatusOutForDelivery, StatusDelivered} {
+		resp := track("RS-12345-678901234", created, events[i].at)
+		if resp.Status != want {
+			t.Errorf("at %v: status %s, want %s", events[i].at, resp.Status, want)
+		}
+		for j, e := range resp.Events {
+			if e.Done != (j <= i) {
+				t.Errorf("at %s: %s done = %v", want, e.Status, e.Done)
+			}
+		}
+		if resp.EstimatedDelivery != events[3].at.Format(time.RFC3339) {
+			t.Errorf("estimated delivery %s, want %v", resp.EstimatedDelivery, events[3].at)
+		}
+	}
+	if resp := track("RS-12345-678901234", created, created.Add(-time.Minute)); resp.Status != "" {
+		t.Errorf("before the label is created: status %s", resp.Status)
+	}
+
+	defer func(prev func() time.Time) { now = prev }(now)
+	now = func() time.Time { return events[2].at.Add(time.Minute) }
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"tracking_id=" + stampTrackingID("RS-12345-678901234", created), http.StatusOK},
+		{"tracking_id=not-an-id", http.StatusNotFound},
+		{"tracking_id=RS-12345-678901234", http.StatusNotFound},
+		{"tracking_id=RS-12345-678901234-" + strings.Repeat("Z", 20), http.StatusNotFound},
+		{"tracking_id=RS-12345-678901234&created_at=2023-09-06T10:00:00Z", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		Handler(rec, httptest.NewRequest(http.MethodGet, "/shipping?"+tt.query, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.query, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var resp TrackShipmentResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != StatusOutForDelivery || resp.Events[0].Time != "2023-09-06T10:00:00Z" {
+			t.Errorf("%s: status %s, want %s", tt.query, resp.Status, StatusOutForDelivery)
+		}
 	}
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of a shipment, in the order they happen.
+const (
+	StatusLabelCreated   = "label_created"
+	StatusInTransit      = "in_transit"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusDelivered      = "delivered"
+)
+
+// trackingIDPattern matches the IDs ShipOrder makes: one of CreateTrackingId
+// followed by the time the shipment was created, in seconds since the epoch
+// in base 36.
+var trackingIDPattern = regexp.MustCompile(`^[A-Z]{2}-\d+-\d+-([0-9A-Z]+)$`)
+
+// now is the clock timelines are read against, replaced by tests.
+var now = time.Now
+
+// stampTrackingID appends the creation time of a shipment to its ID, so that
+// the shipment can be tracked from the ID alone.
+func stampTrackingID(id string, created time.Time) string {
+	return id + "-" + strings.ToUpper(strconv.FormatInt(created.Unix(), 36))
+}
+
+// trackingCreated returns the creation time a tracking ID carries, and false
+// for an ID that ShipOrder cannot have made.
+func trackingCreated(trackingID string) (time.Time, bool) {
+	m := trackingIDPattern.FindStringSubmatch(trackingID)
+	if m == nil {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(m[1], 36, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0).UTC(), true
+}
+
+type milestone struct {
+	status      string
+	description string
+	at          time.Time
+}
+
+// timeline simulates the journey of the shipment trackingID created at
+// created. The delays between the milestones are drawn from a hash of the
+// tracking ID, so a shipment always follows the same timeline: in transit
+// 2 to 8 hours after the label is created, out for delivery 1 to 3 days
+// after, and delivered 2 to 10 hours later.
+func timeline(trackingID string, created time.Time) []milestone {
+	h := fnv.New64a()
+	h.Write([]byte(trackingID))
+	sum := h.Sum64()
+
+	inTransit := created.Add(2*time.Hour + time.Duration(sum%360)*time.Minute)
+	outForDelivery := created.Add(24*time.Hour + time.Duration((sum>>16)%48)*time.Hour)
+	delivered := outForDelivery.Add(2*time.Hour + time.Duration((sum>>32)%480)*time.Minute)
+	return []milestone{
+		{StatusLabelCreated, "Shipping label created", created},
+		{StatusInTransit, "Picked up by the carrier, in transit", inTransit},
+		{StatusOutForDelivery, "Out for delivery", outForDelivery},
+		{StatusDelivered, "Delivered", delivered},
+	}
+}
+
+// track answers the timeline of a shipment as of at: the milestones that
+// happened are done, and the status is the last of them.
+func track(trackingID string, created, at time.Time) *TrackShipmentResponse {
+	resp := &TrackShipmentResponse{TrackingId: trackingID}
+	for _, m := range timeline(trackingID, created) {
+		done := !at.Before(m.at)
+		if done {
+			resp.Status = m.status
+		}
+		resp.Events = append(resp.Events, &ShipmentEvent{
+			Status:      m.status,
+			Description: m.description,
+			Time:        m.at.UTC().Format(time.RFC3339),
+			Done:        done,
+		})
+		resp.EstimatedDelivery = m.at.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
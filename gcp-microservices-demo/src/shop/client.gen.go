@@ -0,0 +1,261 @@
+// Code generated by shopgen from docs/openapi.yaml. DO NOT EDIT.
+
+package shop
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GetCart calls GET /cart on cartservice.
+func (cc *CartClient) GetCart(ctx context.Context, in *GetCartRequest) (*Cart, error) {
+	out := new(Cart)
+	v := url.Values{}
+	v.Add("user_id", in.UserId)
+	err := cc.c.do(ctx, call{svc: CartService, op: "GetCart", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AddItem calls POST /cart on cartservice.
+func (cc *CartClient) AddItem(ctx context.Context, in *AddItemRequest) error {
+	return cc.c.do(ctx, call{svc: CartService, op: "AddItem", method: http.MethodPost, in: in})
+}
+
+// EmptyCart calls DELETE /cart on cartservice.
+func (cc *CartClient) EmptyCart(ctx context.Context, in *EmptyCartRequest) error {
+	return cc.c.do(ctx, call{svc: CartService, op: "EmptyCart", method: http.MethodDelete, in: in, idempotent: true})
+}
+
+// UpdateItem calls PUT /cart/item on cartservice.
+func (cc *CartClient) UpdateItem(ctx context.Context, in *UpdateItemRequest) (*Cart, error) {
+	out := new(Cart)
+	err := cc.c.do(ctx, call{svc: CartService, op: "UpdateItem", method: http.MethodPut, suffix: "/item", in: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RemoveItem calls DELETE /cart/item on cartservice.
+func (cc *CartClient) RemoveItem(ctx context.Context, in *RemoveItemRequest) (*Cart, error) {
+	out := new(Cart)
+	err := cc.c.do(ctx, call{svc: CartService, op: "RemoveItem", method: http.MethodDelete, suffix: "/item", in: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRecommendations calls GET /recommendation on recommendationservice.
+func (rc *RecommendationsClient) ListRecommendations(ctx context.Context, in *ListRecommendationsRequest) (*ListRecommendationsResponse, error) {
+	out := new(ListRecommendationsResponse)
+	v := url.Values{}
+	v.Add("user_id", in.UserId)
+	if len(in.ProductIds) > 0 {
+		v.Add("product_ids", strings.Join(in.ProductIds, ","))
+	}
+	err := rc.c.do(ctx, call{svc: RecommendationService, op: "ListRecommendations", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListProducts calls GET /product on productcatalogservice.
+func (cc *CatalogClient) ListProducts(ctx context.Context) (*ListProductsResponse, error) {
+	out := new(ListProductsResponse)
+	err := cc.c.do(ctx, call{svc: CatalogService, op: "ListProducts", method: http.MethodGet, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProduct calls GET /product on productcatalogservice.
+func (cc *CatalogClient) GetProduct(ctx context.Context, in *GetProductRequest) (*Product, error) {
+	out := new(Product)
+	v := url.Values{}
+	v.Add("id", in.Id)
+	err := cc.c.do(ctx, call{svc: CatalogService, op: "GetProduct", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SearchProducts calls GET /product on productcatalogservice.
+func (cc *CatalogClient) SearchProducts(ctx context.Context, in *SearchProductsRequest) (*SearchProductsResponse, error) {
+	out := new(SearchProductsResponse)
+	v := url.Values{}
+	v.Add("query", in.Query)
+	err := cc.c.do(ctx, call{svc: CatalogService, op: "SearchProducts", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BrowseProducts calls GET /product on productcatalogservice.
+func (cc *CatalogClient) BrowseProducts(ctx context.Context, in *BrowseProductsRequest) (*BrowseProductsResponse, error) {
+	out := new(BrowseProductsResponse)
+	v := url.Values{}
+	if in.Category != "" {
+		v.Add("category", in.Category)
+	}
+	if in.MinPriceUsd != 0 {
+		v.Add("min_price_usd", fmt.Sprint(in.MinPriceUsd))
+	}
+	if in.MaxPriceUsd != 0 {
+		v.Add("max_price_usd", fmt.Sprint(in.MaxPriceUsd))
+	}
+	if in.Sort != "" {
+		v.Add("sort", in.Sort)
+	}
+	if in.Page != 0 {
+		v.Add("page", fmt.Sprint(in.Page))
+	}
+	if in.PageSize != 0 {
+		v.Add("page_size", fmt.Sprint(in.PageSize))
+	}
+	err := cc.c.do(ctx, call{svc: CatalogService, op: "BrowseProducts", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetQuote calls POST /shipping on shippingservice.
+func (sc *ShippingClient) GetQuote(ctx context.Context, in *GetQuoteRequest) (*GetQuoteResponse, error) {
+	out := new(GetQuoteResponse)
+	err := sc.c.do(ctx, call{svc: ShippingService, op: "GetQuote", method: http.MethodPost, in: in, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShipOrder calls PUT /shipping on shippingservice.
+func (sc *ShippingClient) ShipOrder(ctx context.Context, in *ShipOrderRequest) (*ShipOrderResponse, error) {
+	out := new(ShipOrderResponse)
+	err := sc.c.do(ctx, call{svc: ShippingService, op: "ShipOrder", method: http.MethodPut, in: in, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TrackShipment calls GET /shipping on shippingservice.
+func (sc *ShippingClient) TrackShipment(ctx context.Context, in *TrackShipmentRequest) (*TrackShipmentResponse, error) {
+	out := new(TrackShipmentResponse)
+	v := url.Values{}
+	v.Add("tracking_id", in.TrackingId)
+	err := sc.c.do(ctx, call{svc: ShippingService, op: "TrackShipment", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSupportedCurrencies calls GET /currency on currencyservice.
+func (cc *CurrencyClient) GetSupportedCurrencies(ctx context.Context) (*GetSupportedCurrenciesResponse, error) {
+	out := new(GetSupportedCurrenciesResponse)
+	err := cc.c.do(ctx, call{svc: CurrencyService, op: "GetSupportedCurrencies", method: http.MethodGet, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Convert calls POST /currency on currencyservice.
+func (cc *CurrencyClient) Convert(ctx context.Context, in *CurrencyConversionRequest) (*Money, error) {
+	out := new(Money)
+	err := cc.c.do(ctx, call{svc: CurrencyService, op: "Convert", method: http.MethodPost, in: in, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// convertBatch calls POST /currency/batch on currencyservice.
+func (cc *CurrencyClient) convertBatch(ctx context.Context, in *CurrencyConversionBatchRequest) (*CurrencyConversionBatchResponse, error) {
+	out := new(CurrencyConversionBatchResponse)
+	err := cc.c.do(ctx, call{svc: CurrencyService, op: "ConvertBatch", method: http.MethodPost, suffix: "/batch", in: in, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Charge calls POST /payment on paymentservice.
+func (pc *PaymentClient) Charge(ctx context.Context, in *ChargeRequest) (*ChargeResponse, error) {
+	out := new(ChargeResponse)
+	err := pc.c.do(ctx, call{svc: PaymentService, op: "Charge", method: http.MethodPost, in: in, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SendOrderConfirmation calls POST /email on emailservice.
+func (ec *EmailClient) SendOrderConfirmation(ctx context.Context, in *SendOrderConfirmationRequest) error {
+	return ec.c.do(ctx, call{svc: EmailService, op: "SendOrderConfirmation", method: http.MethodPost, in: in})
+}
+
+// PlaceOrder calls POST /checkout on checkoutservice.
+func (cc *CheckoutClient) PlaceOrder(ctx context.Context, in *PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	out := new(PlaceOrderResponse)
+	err := cc.c.do(ctx, call{svc: CheckoutService, op: "PlaceOrder", method: http.MethodPost, in: in, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListOrders calls GET /checkout on checkoutservice.
+func (cc *CheckoutClient) ListOrders(ctx context.Context, in *ListOrdersRequest) (*ListOrdersResponse, error) {
+	out := new(ListOrdersResponse)
+	v := url.Values{}
+	v.Add("user_id", in.UserId)
+	if in.PageSize != 0 {
+		v.Add("page_size", fmt.Sprint(in.PageSize))
+	}
+	if in.PageToken != "" {
+		v.Add("page_token", in.PageToken)
+	}
+	err := cc.c.do(ctx, call{svc: CheckoutService, op: "ListOrders", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOrder calls GET /checkout on checkoutservice.
+func (cc *CheckoutClient) GetOrder(ctx context.Context, in *GetOrderRequest) (*Order, error) {
+	out := new(Order)
+	v := url.Values{}
+	v.Add("user_id", in.UserId)
+	v.Add("order_id", in.OrderId)
+	err := cc.c.do(ctx, call{svc: CheckoutService, op: "GetOrder", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAds calls GET /ad on adservice.
+func (ac *AdsClient) GetAds(ctx context.Context, in *AdRequest) (*AdResponse, error) {
+	out := new(AdResponse)
+	v := url.Values{}
+	v.Add("context_keys", strings.Join(in.ContextKeys, ","))
+	err := ac.c.do(ctx, call{svc: AdService, op: "GetAds", method: http.MethodGet, query: v, req: in, idempotent: true, out: out})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,316 @@
+// Package shop is a Go client for the HTTP/JSON API exposed by the
+// gcp-microservices-demo functions behind the Fission router.
+//
+// The message types and client stubs are generated from docs/openapi.yaml.
+package shop
+
+//go:generate go run ./internal/shopgen -spec ../../docs/openapi.yaml -types types.gen.go -client client.gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fault"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// Version is the version of this SDK. It is sent in the User-Agent header.
+const Version = "v0.1.0"
+
+// DefaultBaseURL is the in-cluster address of the Fission router.
+const DefaultBaseURL = "http://router.fission.svc.cluster.local"
+
+// Service identifies one of the shop functions.
+type Service int
+
+const (
+	CatalogService Service = iota
+	CartService
+	CurrencyService
+	ShippingService
+	PaymentService
+	EmailService
+	CheckoutService
+	AdService
+	RecommendationService
+)
+
+var servicePaths = map[Service]string{
+	CatalogService:        "/product",
+	CartService:           "/cart",
+	CurrencyService:       "/currency",
+	ShippingService:       "/shipping",
+	PaymentService:        "/payment",
+	EmailService:          "/email",
+	CheckoutService:       "/checkout",
+	AdService:             "/ad",
+	RecommendationService: "/recommendation",
+}
+
+var serviceNames = map[Service]string{
+	CatalogService:        "productcatalogservice",
+	CartService:           "cartservice",
+	CurrencyService:       "currencyservice",
+	ShippingService:       "shippingservice",
+	PaymentService:        "paymentservice",
+	EmailService:          "emailservice",
+	CheckoutService:       "checkoutservice",
+	AdService:             "adservice",
+	RecommendationService: "recommendationservice",
+}
+
+func (s Service) String() string {
+	if n, ok := serviceNames[s]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// Client talks to every shop function. The zero value is not usable; create
+// clients with New.
+type Client struct {
+	Catalog         *CatalogClient
+	Cart            *CartClient
+	Currency        *CurrencyClient
+	Shipping        *ShippingClient
+	Payment         *PaymentClient
+	Email           *EmailClient
+	Checkout        *CheckoutClient
+	Ads             *AdsClient
+	Recommendations *RecommendationsClient
+
+	baseURL    string
+	addrs      map[Service]string
+	invokers   map[Service]Invoker
+	httpClient *http.Client
+	auth       AuthFunc
+	trace      []TraceFunc
+	maxRetries int
+	backoff    time.Duration
+	faults     *fault.Injector
+}
+
+// New returns a Client configured by opts.
+func New(opts ...Option) *Client {
+	c := &Client{
+		baseURL:    DefaultBaseURL,
+		addrs:      make(map[Service]string),
+		invokers:   make(map[Service]Invoker),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.Catalog = &CatalogClient{c: c}
+	c.Cart = &CartClient{c: c}
+	c.Currency = &CurrencyClient{c: c}
+	c.Shipping = &ShippingClient{c: c}
+	c.Payment = &PaymentClient{c: c}
+	c.Email = &EmailClient{c: c}
+	c.Checkout = &CheckoutClient{c: c}
+	c.Ads = &AdsClient{c: c}
+	c.Recommendations = &RecommendationsClient{c: c}
+	return c
+}
+
+// CatalogClient calls productcatalogservice.
+type CatalogClient struct{ c *Client }
+
+// CartClient calls cartservice.
+type CartClient struct{ c *Client }
+
+// ShippingClient calls shippingservice.
+type ShippingClient struct{ c *Client }
+
+// PaymentClient calls paymentservice.
+type PaymentClient struct{ c *Client }
+
+// EmailClient calls emailservice.
+type EmailClient struct{ c *Client }
+
+// CheckoutClient calls checkoutservice.
+type CheckoutClient struct{ c *Client }
+
+// AdsClient calls adservice.
+type AdsClient struct{ c *Client }
+
+// RecommendationsClient calls recommendationservice.
+type RecommendationsClient struct{ c *Client }
+
+// Addr returns the address requests for svc are sent to.
+func (c *Client) Addr(svc Service) string {
+	if addr, ok := c.addrs[svc]; ok {
+		return addr
+	}
+	return strings.TrimSuffix(c.baseURL, "/") + servicePaths[svc]
+}
+
+// call describes one request to a shop function.
+type call struct {
+	svc    Service
+	op     string
+	method string
+	// suffix is appended to the service address, e.g. "/batch".
+	suffix string
+	query  url.Values
+	// req is the request message of an operation that sends it as query
+	// parameters rather than as the body in.
+	req interface{}
+	in  interface{}
+	out interface{}
+	// idempotent marks an operation that may be safely repeated, and so
+	// retried.
+	idempotent bool
+}
+
+func (c *Client) do(ctx context.Context, cl call) (err error) {
+	ctx, span := telemetry.StartClient(ctx, cl.svc.String(), cl.op)
+	defer func() {
+		status := http.StatusOK
+		var e *Error
+		if errors.As(err, &e) {
+			status = e.StatusCode
+		} else if err != nil {
+			status = 0
+		}
+		span.End(ctx, status, err)
+	}()
+
+	if inv, ok := c.invokers[cl.svc]; ok {
+		return c.retry(ctx, cl, func() error { return c.invoke(ctx, inv, cl) })
+	}
+
+	var payload []byte
+	if cl.in != nil {
+		var err error
+		if payload, err = json.Marshal(cl.in); err != nil {
+			return &Error{Service: cl.svc, Op: cl.op, Err: err}
+		}
+	}
+	target := c.Addr(cl.svc) + cl.suffix
+	if len(cl.query) > 0 {
+		target += "?" + cl.query.Encode()
+	}
+
+	return c.retry(ctx, cl, func() error {
+		body, err := c.send(ctx, cl, target, payload)
+		if err != nil {
+			return err
+		}
+		if cl.out == nil || len(body) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(body, cl.out); err != nil {
+			return &Error{Service: cl.svc, Op: cl.op, Err: err}
+		}
+		return nil
+	})
+}
+
+// retry runs attempt until it succeeds, the error is not retryable or the
+// retries configured by WithRetries are used up.
+func (c *Client) retry(ctx context.Context, cl call, attempt func() error) error {
+	for n := 0; ; n++ {
+		err := attempt()
+		if err == nil || n >= c.maxRetries || !cl.idempotent || !IsRetryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return &Error{Service: cl.svc, Op: cl.op, Err: ctx.Err()}
+		case <-time.After(c.backoff * time.Duration(n+1)):
+		}
+	}
+}
+
+func (c *Client) invoke(ctx context.Context, inv Invoker, cl call) error {
+	in := cl.req
+	if in == nil {
+		in = cl.in
+	}
+	err := inv.Invoke(ctx, cl.svc, cl.op, in, cl.out)
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		e.Service, e.Op = cl.svc, cl.op
+		return e
+	}
+	return &Error{Service: cl.svc, Op: cl.op, Err: err}
+}
+
+func (c *Client) send(ctx context.Context, cl call, target string, payload []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, cl.method, target, reqBody)
+	if err != nil {
+		return nil, &Error{Service: cl.svc, Op: cl.op, Err: err}
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "shop-go/"+Version)
+	if md, ok := FromContext(ctx); ok {
+		md.Inject(req.Header)
+	}
+	telemetry.Inject(ctx, req.Header)
+	if c.auth != nil {
+		c.auth(req)
+	}
+	for _, t := range c.trace {
+		t(ctx, req)
+	}
+
+	rule, faulted := c.faults.Match(cl.svc.String(), cl.op, cl.method, req.Header)
+	if faulted {
+		if err, done := c.injectFault(ctx, cl, req, rule); done {
+			return nil, err
+		}
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &Error{Service: cl.svc, Op: cl.op, Err: err}
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, &Error{Service: cl.svc, Op: cl.op, StatusCode: res.StatusCode, Err: err}
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &Error{Service: cl.svc, Op: cl.op, StatusCode: res.StatusCode, Body: body, Envelope: decodeEnvelope(body)}
+	}
+	if faulted && rule.Malformed {
+		body = fault.Malform(body)
+	}
+	return body, nil
+}
+
+// injectFault injects the fault of rule into the call of req, and returns
+// the error it fails with and true when the call is not to be sent.
+func (c *Client) injectFault(ctx context.Context, cl call, req *http.Request, rule fault.Rule) (error, bool) {
+	c.faults.Record(ctx, nil, rule, cl.svc.String(), cl.op)
+	if err := fault.Sleep(ctx, rule); err != nil {
+		return &Error{Service: cl.svc, Op: cl.op, Err: err}, true
+	}
+	switch {
+	case rule.Status != 0:
+		retryable := rule.Status == http.StatusServiceUnavailable || rule.Status == http.StatusTooManyRequests || rule.Status == http.StatusGatewayTimeout
+		env := &ErrorResponse{Code: InjectedFaultCode, Message: fault.Message, Retryable: retryable}
+		body, _ := json.Marshal(env)
+		return &Error{Service: cl.svc, Op: cl.op, StatusCode: rule.Status, Body: body, Envelope: env}, true
+	case rule.Drop:
+		return &Error{Service: cl.svc, Op: cl.op, Err: &url.Error{Op: req.Method, URL: req.URL.String(), Err: fault.ErrDropped}}, true
+	}
+	return nil, false
+}
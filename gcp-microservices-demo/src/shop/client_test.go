@@ -0,0 +1,190 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fault"
+)
+
+func TestClientRoutesAndHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/product" || r.URL.Query().Get("id") != "OLJCESPC7Z" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.Header.Get("X-Test-Trace"); got != "span-1" {
+			t.Errorf("X-Test-Trace = %q", got)
+		}
+		if got := r.Header.Get("User-Agent"); !strings.HasSuffix(got, Version) {
+			t.Errorf("User-Agent = %q", got)
+		}
+		w.Write([]byte(`{"id":"OLJCESPC7Z","name":"Sunglasses"}`))
+	}))
+	defer srv.Close()
+
+	type traceKey struct{}
+	c := New(
+		WithBaseURL(srv.URL+"/"),
+		WithAuth(BearerToken("secret")),
+		WithTracing(func(ctx context.Context, r *http.Request) {
+			r.Header.Set("X-Test-Trace", ctx.Value(traceKey{}).(string))
+		}),
+	)
+	ctx := context.WithValue(context.Background(), traceKey{}, "span-1")
+	p, err := c.Catalog.GetProduct(ctx, &GetProductRequest{Id: "OLJCESPC7Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.GetName() != "Sunglasses" {
+		t.Errorf("got product %+v", p)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"currency_codes":["EUR","USD"]}`))
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CurrencyService, srv.URL), WithRetries(2, 0))
+	res, err := c.Currency.GetSupportedCurrencies(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.GetCurrencyCodes()) != 2 || calls != 3 {
+		t.Errorf("got %v after %d calls", res.GetCurrencyCodes(), calls)
+	}
+
+	// Non-idempotent requests are never retried.
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.Currency.Convert(context.Background(), &CurrencyConversionRequest{ToCode: "EUR"})
+	if !IsStatus(err, http.StatusServiceUnavailable) || calls != 1 {
+		t.Errorf("got err %v after %d calls", err, calls)
+	}
+
+	// Neither is ShipOrder, a PUT that ships the order again when repeated,
+	// while setting the quantity of a cart item is.
+	c = New(WithServiceURL(ShippingService, srv.URL), WithServiceURL(CartService, srv.URL), WithRetries(2, 0))
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.Shipping.ShipOrder(context.Background(), &ShipOrderRequest{})
+	if !IsStatus(err, http.StatusServiceUnavailable) || calls != 1 {
+		t.Errorf("ShipOrder: got err %v after %d calls", err, calls)
+	}
+	if _, err := c.Cart.UpdateItem(context.Background(), &UpdateItemRequest{}); err != nil || calls != 3 {
+		t.Errorf("UpdateItem: got err %v after %d calls", err, calls)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("no such product"))
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CatalogService, srv.URL))
+	_, err := c.Catalog.GetProduct(context.Background(), &GetProductRequest{Id: "missing"})
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want not found", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Service != CatalogService || e.Op != "GetProduct" || string(e.Body) != "no such product" {
+		t.Errorf("got %+v", e)
+	}
+	if IsRetryable(err) {
+		t.Error("404 should not be retryable")
+	}
+}
+
+func TestClientErrorEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"code":"unavailable","message":"cart is down","request_id":"req-1","retryable":false}`))
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CatalogService, srv.URL), WithRetries(0, 0))
+	_, err := c.Catalog.GetProduct(context.Background(), &GetProductRequest{Id: "OLJCESPC7Z"})
+	if !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrUnavailable", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Code() != "unavailable" || e.RequestID() != "req-1" {
+		t.Fatalf("got %+v", e)
+	}
+	if want := "productcatalogservice GetProduct: cart is down (unavailable, status 503)"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+	if IsRetryable(err) {
+		t.Error("the envelope says the error is not retryable")
+	}
+}
+
+func TestNilSafeGetters(t *testing.T) {
+	var cart *Cart
+	if cart.GetItems() != nil || cart.GetUserId() != "" {
+		t.Error("nil Cart getters should return zero values")
+	}
+	var order *PlaceOrderResponse
+	if order.GetOrder().GetShippingCost().GetUnits() != 0 {
+		t.Error("nil chain should return zero units")
+	}
+}
+
+func TestClientFaults(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"id":"OLJCESPC7Z","name":"Sunglasses"}`))
+	}))
+	defer srv.Close()
+
+	log := logrus.New()
+	log.Out = io.Discard
+	inj := fault.New(fault.Client, log,
+		fault.Rule{Route: "GetProduct", Header: map[string]string{RequestIDHeader: "down"}, Status: http.StatusServiceUnavailable, Percent: 100},
+		fault.Rule{Route: "GetProduct", Header: map[string]string{RequestIDHeader: "drop"}, Drop: true, Percent: 100},
+		fault.Rule{Route: "GetProduct", Header: map[string]string{RequestIDHeader: "garbled"}, Malformed: true, Percent: 100},
+		fault.Rule{Side: fault.Server, Status: http.StatusInternalServerError, Percent: 100},
+	)
+	c := New(WithServiceURL(CatalogService, srv.URL), WithFaults(inj))
+	get := func(requestID string) error {
+		ctx := NewContext(context.Background(), Metadata{RequestID: requestID})
+		_, err := c.Catalog.GetProduct(ctx, &GetProductRequest{Id: "OLJCESPC7Z"})
+		return err
+	}
+
+	var e *Error
+	if err := get("down"); !IsStatus(err, http.StatusServiceUnavailable) || !IsRetryable(err) || !errors.As(err, &e) || e.Code() != InjectedFaultCode {
+		t.Errorf("status fault: %v", err)
+	}
+	if err := get("drop"); !errors.Is(err, fault.ErrDropped) || !IsRetryable(err) {
+		t.Errorf("drop fault: %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("faulted calls were sent %d times", calls)
+	}
+	if err := get("garbled"); err == nil || calls != 1 {
+		t.Errorf("malformed fault: %v after %d calls", err, calls)
+	}
+	if err := get("fine"); err != nil {
+		t.Errorf("a server-side rule faulted the client: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Command coldstart reports the cold-start percentiles of the Go functions
+// from their logs: the time each function takes to initialize, and to serve
+// the first request of a process.
+//
+//	kubectl logs -n fission-function -l functionName=productcatalogservice --tail=-1 | go run ./cmd/coldstart
+//	go run ./cmd/coldstart checkout.log shipping.log
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: coldstart [log file ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var in []io.Reader
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "coldstart:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = append(in, f)
+	}
+	if len(in) == 0 {
+		in = append(in, os.Stdin)
+	}
+
+	r := newReport()
+	if err := r.read(io.MultiReader(in...)); err != nil {
+		fmt.Fprintln(os.Stderr, "coldstart:", err)
+		os.Exit(1)
+	}
+	if err := r.write(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "coldstart:", err)
+		os.Exit(1)
+	}
+}
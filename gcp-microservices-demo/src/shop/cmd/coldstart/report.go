@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fission"
+)
+
+// percentiles are the percentiles reported for each function.
+var percentiles = []float64{50, 90, 99}
+
+// report collects the cold-start timings logged by each function.
+type report struct {
+	init         map[string][]float64
+	firstRequest map[string][]float64
+}
+
+func newReport() *report {
+	return &report{init: make(map[string][]float64), firstRequest: make(map[string][]float64)}
+}
+
+// read adds the "cold start" entries of the JSON logs in r. Lines that are
+// not JSON, such as a prefix added by a log collector before the entry,
+// are skipped up to the first '{'.
+func (rp *report) read(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		i := bytes.IndexByte(line, '{')
+		if i < 0 {
+			continue
+		}
+		var entry map[string]interface{}
+		if json.Unmarshal(line[i:], &entry) != nil {
+			continue
+		}
+		// The functions map the message to "message"; logrus defaults to
+		// "msg".
+		if entry["message"] != fission.ColdStartMessage && entry["msg"] != fission.ColdStartMessage {
+			continue
+		}
+		svc, _ := entry[fission.ServiceField].(string)
+		if svc == "" {
+			continue
+		}
+		if ms, ok := entry[fission.InitField].(float64); ok {
+			rp.init[svc] = append(rp.init[svc], ms)
+		}
+		if ms, ok := entry[fission.FirstRequestField].(float64); ok {
+			rp.firstRequest[svc] = append(rp.firstRequest[svc], ms)
+		}
+	}
+	return sc.Err()
+}
+
+// write prints a table of the percentiles of each function, in ms.
+func (rp *report) write(w io.Writer) error {
+	var services []string
+	for svc := range rp.firstRequest {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprint(tw, "function\tcold starts\t")
+	for _, name := range []string{"init", "first request"} {
+		for _, p := range percentiles {
+			fmt.Fprintf(tw, "%s p%g\t", name, p)
+		}
+		fmt.Fprintf(tw, "%s max\t", name)
+	}
+	fmt.Fprintln(tw)
+	for _, svc := range services {
+		fmt.Fprintf(tw, "%s\t%d\t", svc, len(rp.firstRequest[svc]))
+		for _, samples := range [][]float64{rp.init[svc], rp.firstRequest[svc]} {
+			sort.Float64s(samples)
+			for _, p := range percentiles {
+				fmt.Fprintf(tw, "%.1f\t", percentile(samples, p))
+			}
+			fmt.Fprintf(tw, "%.1f\t", percentile(samples, 100))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// percentile returns the nearest-rank p-th percentile of the sorted
+// samples, or 0 without samples.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
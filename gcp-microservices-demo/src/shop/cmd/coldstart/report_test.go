@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	samples := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for p, want := range map[float64]float64{50: 5, 90: 9, 99: 10, 100: 10, 0: 1} {
+		if got := percentile(samples, p); got != want {
+			t.Errorf("p%g = %g, want %g", p, got, want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("p50 of no samples = %g", got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	logs := `{"message":"cold start","service":"productcatalogservice","init_ms":40,"first_request_ms":120,"cold":true}
+{"message":"request complete","cold":true,"http.resp.status":200}
+not a log entry
+[productcatalogservice-7d9c] {"message":"cold start","service":"productcatalogservice","init_ms":60,"first_request_ms":300,"cold":true}
+{"msg":"cold start","service":"adservice","init_ms":5,"first_request_ms":15,"cold":true}
+{"message":"cold start","service":"productcatalogservice","init_ms":50,"first_request_ms":200,"cold":true}
+`
+	r := newReport()
+	if err := r.read(strings.NewReader(logs)); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := r.write(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("report:\n%s", &out)
+	}
+	for i, want := range [][]string{
+		{"adservice", "1", "5.0", "5.0", "5.0", "5.0", "15.0", "15.0", "15.0", "15.0"},
+		{"productcatalogservice", "3", "50.0", "60.0", "60.0", "60.0", "200.0", "300.0", "300.0", "300.0"},
+	} {
+		if got := strings.Fields(lines[i+1]); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("row %d = %q, want %q", i+1, got, want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package contract implements consumer-driven contract tests for the shop
+// functions.
+//
+// A consumer test points its client at a Mock, which answers with canned
+// responses and records every request the client actually sends. The
+// recorded interactions are written to a pact file named
+// "<consumer>-<provider>.json". The provider test then replays every pact
+// file addressed to it against its Handler with Verify, so a request shape
+// or response field a consumer relies on cannot silently break.
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Pact is the set of interactions one consumer relies on from one provider.
+type Pact struct {
+	Consumer     string         `json:"consumer"`
+	Provider     string         `json:"provider"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is one request a consumer sends and the response it expects.
+type Interaction struct {
+	Description string `json:"description"`
+	// ProviderState names the state the provider must be in before the
+	// request is replayed, e.g. "cart of user-1 holds OLJCESPC7Z".
+	ProviderState string   `json:"providerState,omitempty"`
+	Request       Request  `json:"request"`
+	Response      Response `json:"response"`
+}
+
+// Request is the recorded request of an interaction.
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is the response a consumer expects. The provider's body matches
+// when it has every field of Body with a value of the same JSON type; arrays
+// must hold at least one element and each element must match the first
+// element of the expected array.
+type Response struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// FileName returns the name of the pact file between consumer and provider.
+func FileName(consumer, provider string) string {
+	return consumer + "-" + provider + ".json"
+}
+
+// Write stores p in dir, replacing any earlier pact between the same
+// consumer and provider.
+func Write(dir string, p *Pact) error {
+	sort.SliceStable(p.Interactions, func(i, j int) bool {
+		return p.Interactions[i].Description < p.Interactions[j].Description
+	})
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName(p.Consumer, p.Provider)), append(b, '\n'), 0644)
+}
+
+// Load reads every pact in dir whose provider is provider.
+func Load(dir, provider string) ([]*Pact, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*-"+provider+".json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var pacts []*Pact
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p := new(Pact)
+		if err := json.Unmarshal(b, p); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if p.Provider != provider {
+			continue
+		}
+		pacts = append(pacts, p)
+	}
+	return pacts, nil
+}
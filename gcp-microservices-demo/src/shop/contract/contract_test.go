@@ -0,0 +1,81 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+func TestMockRecordsAndVerifyReplays(t *testing.T) {
+	dir := t.TempDir()
+	m := NewMock(t, "consumer", "adservice")
+	m.Expect("", "ads for clothing", http.StatusOK, &shop.AdResponse{Ads: []*shop.Ad{{RedirectUrl: "/product/1", Text: "ad"}}})
+
+	c := shop.New(shop.WithServiceURL(shop.AdService, m.URL()))
+	if _, err := c.Ads.GetAds(context.Background(), &shop.AdRequest{ContextKeys: []string{"clothing"}}); err != nil {
+		t.Fatal(err)
+	}
+	m.Write(dir)
+
+	pacts, err := Load(dir, "adservice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pacts) != 1 || len(pacts[0].Interactions) != 1 {
+		t.Fatalf("got pacts %+v", pacts)
+	}
+	req := pacts[0].Interactions[0].Request
+	if req.Method != http.MethodGet || req.Path != "/" || req.Query != "context_keys=clothing" {
+		t.Errorf("recorded request %+v", req)
+	}
+
+	var seen string
+	provider := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Query().Get("context_keys")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ads":[{"redirect_url":"/product/2","text":"other"},{"redirect_url":"/product/3","text":"more"}]}`))
+	})
+	Verify(t, dir, "adservice", provider, nil)
+	if seen != "clothing" {
+		t.Errorf("provider saw context_keys %q", seen)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		want, got string
+		errs      int
+	}{
+		{`{"a":"x","b":[{"c":1}]}`, `{"a":"y","b":[{"c":2,"d":true}],"e":null}`, 0},
+		{`{"a":"x"}`, `{}`, 1},
+		{`{"a":"x"}`, `{"a":1}`, 1},
+		{`{"b":[{"c":1}]}`, `{"b":[]}`, 1},
+		{`{"b":[{"c":1}]}`, `{"b":[{"c":1},{"c":"2"},{}]}`, 2},
+		{`{"b":[]}`, `{"b":[1]}`, 0},
+	}
+	for _, tt := range tests {
+		var want, got interface{}
+		if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(tt.got), &got); err != nil {
+			t.Fatal(err)
+		}
+		if errs := Match("body", want, got); len(errs) != tt.errs {
+			t.Errorf("Match(%s, %s) = %v, want %d errors", tt.want, tt.got, errs, tt.errs)
+		}
+	}
+}
+
+func TestReplayStatus(t *testing.T) {
+	it := &Interaction{Description: "x", Request: Request{Method: http.MethodGet, Path: "/"}, Response: Response{Status: http.StatusOK}}
+	errs := Replay(it, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	if len(errs) != 1 {
+		t.Errorf("Replay = %v, want a status error", errs)
+	}
+}
@@ -0,0 +1,113 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Mock is a fake provider for consumer tests. Each request it receives is
+// answered with the next expected response and recorded as an interaction.
+type Mock struct {
+	t    testing.TB
+	srv  *httptest.Server
+	pact Pact
+
+	mu      sync.Mutex
+	pending []*Interaction
+}
+
+// NewMock starts a Mock for the pact between consumer and provider. It is
+// shut down when the test finishes.
+func NewMock(t testing.TB, consumer, provider string) *Mock {
+	m := &Mock{t: t, pact: Pact{Consumer: consumer, Provider: provider}}
+	m.srv = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+// URL returns the address to point the consumer's client at.
+func (m *Mock) URL() string {
+	return m.srv.URL
+}
+
+// Expect queues the response for the next request the consumer sends. The
+// request itself is taken from what the consumer actually sends. body is
+// encoded as JSON unless it is nil.
+func (m *Mock) Expect(state, description string, status int, body interface{}) {
+	m.t.Helper()
+	var raw json.RawMessage
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			m.t.Fatalf("%s: %v", description, err)
+		}
+		raw = b
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, &Interaction{
+		Description:   description,
+		ProviderState: state,
+		Response:      Response{Status: status, Body: raw},
+	})
+}
+
+func (m *Mock) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	if len(m.pending) == 0 {
+		m.mu.Unlock()
+		m.t.Errorf("%s: unexpected request %s %s", m.pact.Provider, r.Method, r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	it := m.pending[0]
+	m.pending = m.pending[1:]
+	m.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		m.t.Errorf("%s: %v", it.Description, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	it.Request = Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery}
+	if len(body) > 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, body); err != nil {
+			m.t.Errorf("%s: request body is not JSON: %v", it.Description, err)
+		}
+		it.Request.Body = buf.Bytes()
+	}
+
+	m.mu.Lock()
+	m.pact.Interactions = append(m.pact.Interactions, it)
+	m.mu.Unlock()
+
+	if it.Response.Body != nil {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(it.Response.Status)
+	w.Write(it.Response.Body)
+}
+
+// Write stores the recorded interactions as a pact file in dir. Nothing is
+// written if the test has failed or an expected request was never sent.
+func (m *Mock) Write(dir string) {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, it := range m.pending {
+		m.t.Errorf("%s: expected request %q was never sent", m.pact.Provider, it.Description)
+	}
+	if m.t.Failed() {
+		return
+	}
+	if err := Write(dir, &m.pact); err != nil {
+		m.t.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// States sets up the provider states named by interactions. Each function
+// runs before the interactions that name its state are replayed.
+type States map[string]func(t *testing.T)
+
+// Verify replays every interaction of the pacts in dir addressed to provider
+// against h, one subtest per interaction.
+func Verify(t *testing.T, dir, provider string, h http.Handler, states States) {
+	t.Helper()
+	pacts, err := Load(dir, provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pacts) == 0 {
+		t.Fatalf("no pacts for %s in %s", provider, dir)
+	}
+	for _, p := range pacts {
+		for _, it := range p.Interactions {
+			it := it
+			t.Run(p.Consumer+"/"+it.Description, func(t *testing.T) {
+				if it.ProviderState != "" {
+					setup, ok := states[it.ProviderState]
+					if !ok {
+						t.Fatalf("unknown provider state %q", it.ProviderState)
+					}
+					setup(t)
+				}
+				for _, err := range Replay(it, h) {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+// Replay sends the request of it to h and reports how the response diverges
+// from the expected one.
+func Replay(it *Interaction, h http.Handler) []error {
+	target := it.Request.Path
+	if it.Request.Query != "" {
+		target += "?" + it.Request.Query
+	}
+	var body io.Reader
+	if len(it.Request.Body) > 0 {
+		body = bytes.NewReader(it.Request.Body)
+	}
+	req := httptest.NewRequest(it.Request.Method, target, body)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	res := rec.Result()
+	if res.StatusCode != it.Response.Status {
+		return []error{fmt.Errorf("status %d, want %d: %s", res.StatusCode, it.Response.Status, rec.Body.Bytes())}
+	}
+	if len(it.Response.Body) == 0 {
+		return nil
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.Contains(ct, "application/json") {
+		return []error{fmt.Errorf("content type %q is not application/json", ct)}
+	}
+	var want, got interface{}
+	if err := json.Unmarshal(it.Response.Body, &want); err != nil {
+		return []error{fmt.Errorf("expected body: %v", err)}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		return []error{fmt.Errorf("response body: %v", err)}
+	}
+	return Match("body", want, got)
+}
+
+// Match reports where the decoded JSON value got does not satisfy want, using
+// the rules documented on Response.
+func Match(path string, want, got interface{}) []error {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: got %s, want object", path, kind(got))}
+		}
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var errs []error
+		for _, k := range keys {
+			v, ok := g[k]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: missing field %q", path, k))
+				continue
+			}
+			errs = append(errs, Match(path+"."+k, w[k], v)...)
+		}
+		return errs
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: got %s, want array", path, kind(got))}
+		}
+		if len(w) == 0 {
+			return nil
+		}
+		if len(g) == 0 {
+			return []error{fmt.Errorf("%s: got an empty array", path)}
+		}
+		var errs []error
+		for i, v := range g {
+			errs = append(errs, Match(fmt.Sprintf("%s[%d]", path, i), w[0], v)...)
+		}
+		return errs
+	}
+	if kind(want) != kind(got) {
+		return []error{fmt.Errorf("%s: got %s, want %s", path, kind(got), kind(want))}
+	}
+	return nil
+}
+
+func kind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,85 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// maxParallelConversions bounds the single Convert calls the batch fallback
+// has in flight at once.
+const maxParallelConversions = 8
+
+// CurrencyClient calls currencyservice.
+type CurrencyClient struct {
+	c *Client
+	// batchUnsupported is set once the router has answered the batch route
+	// as missing, so later calls go straight to the fallback.
+	batchUnsupported int32
+}
+
+// ConvertBatch converts every amount in the request to the target currency.
+// When the currency service does not support the batch route it falls back
+// to one Convert call per amount, at most maxParallelConversions at a time.
+func (cc *CurrencyClient) ConvertBatch(ctx context.Context, in *CurrencyConversionBatchRequest) (*CurrencyConversionBatchResponse, error) {
+	if len(in.From) == 0 {
+		return &CurrencyConversionBatchResponse{Results: []*Money{}}, nil
+	}
+	if atomic.LoadInt32(&cc.batchUnsupported) == 0 {
+		out, err := cc.convertBatch(ctx, in)
+		switch {
+		case err == nil && len(out.Results) == len(in.From):
+			return out, nil
+		case err == nil:
+			return nil, &Error{Service: CurrencyService, Op: "ConvertBatch", Err: errBatchLength(len(out.Results), len(in.From))}
+		case !batchRouteMissing(err):
+			return nil, err
+		}
+		atomic.StoreInt32(&cc.batchUnsupported, 1)
+	}
+	return cc.convertEach(ctx, in)
+}
+
+// convertEach converts the amounts with parallel single Convert calls.
+func (cc *CurrencyClient) convertEach(ctx context.Context, in *CurrencyConversionBatchRequest) (*CurrencyConversionBatchResponse, error) {
+	results := make([]*Money, len(in.From))
+	errs := make([]error, len(in.From))
+	sem := make(chan struct{}, maxParallelConversions)
+	var wg sync.WaitGroup
+	for i, from := range in.From {
+		wg.Add(1)
+		go func(i int, from *Money) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = cc.Convert(ctx, &CurrencyConversionRequest{
+				From:   from,
+				ToCode: in.ToCode,
+			})
+		}(i, from)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &CurrencyConversionBatchResponse{Results: results}, nil
+}
+
+// batchRouteMissing reports whether err says the batch route is not deployed:
+// a 404, 405 or 501 without an error envelope. One with an envelope is an
+// error of the batch conversion itself, such as an unknown currency.
+func batchRouteMissing(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) || e.Envelope != nil {
+		return false
+	}
+	switch e.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
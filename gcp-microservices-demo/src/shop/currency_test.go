@@ -0,0 +1,147 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConvertBatch(t *testing.T) {
+	var batchCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		atomic.AddInt32(&batchCalls, 1)
+		req := new(CurrencyConversionBatchRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Error(err)
+			return
+		}
+		out := &CurrencyConversionBatchResponse{}
+		for _, m := range req.From {
+			out.Results = append(out.Results, &Money{CurrencyCode: req.ToCode, Units: m.Units * 2})
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CurrencyService, srv.URL))
+	res, err := c.Currency.ConvertBatch(context.Background(), &CurrencyConversionBatchRequest{
+		From:   []*Money{{CurrencyCode: "USD", Units: 1}, {CurrencyCode: "USD", Units: 3}},
+		ToCode: "EUR",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.GetResults(); len(got) != 2 || got[0].Units != 2 || got[1].Units != 6 || got[1].CurrencyCode != "EUR" {
+		t.Errorf("unexpected results %+v", got)
+	}
+	if batchCalls != 1 {
+		t.Errorf("got %d batch calls, want 1", batchCalls)
+	}
+}
+
+func TestConvertBatchFallback(t *testing.T) {
+	var batchCalls, singleCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/batch" {
+			atomic.AddInt32(&batchCalls, 1)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&singleCalls, 1)
+		req := new(CurrencyConversionRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Error(err)
+			return
+		}
+		json.NewEncoder(w).Encode(&Money{CurrencyCode: req.ToCode, Units: req.From.Units + 1})
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CurrencyService, srv.URL))
+	in := &CurrencyConversionBatchRequest{
+		From:   []*Money{{CurrencyCode: "USD", Units: 1}, {CurrencyCode: "USD", Units: 2}, {CurrencyCode: "USD", Units: 3}},
+		ToCode: "JPY",
+	}
+	for i := 0; i < 2; i++ {
+		res, err := c.Currency.ConvertBatch(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j, m := range res.GetResults() {
+			if m.Units != in.From[j].Units+1 || m.CurrencyCode != "JPY" {
+				t.Errorf("result %d: got %+v", j, m)
+			}
+		}
+	}
+	if batchCalls != 1 {
+		t.Errorf("got %d batch calls, want the unsupported route to be probed once", batchCalls)
+	}
+	if singleCalls != 6 {
+		t.Errorf("got %d single conversions, want 6", singleCalls)
+	}
+}
+
+func TestConvertBatchError(t *testing.T) {
+	var batchCalls, singleCalls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch" {
+			atomic.AddInt32(&singleCalls, 1)
+			return
+		}
+		atomic.AddInt32(&batchCalls, 1)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":"not_found","message":"unknown currency XXX"}`))
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CurrencyService, srv.URL))
+	in := &CurrencyConversionBatchRequest{From: []*Money{{CurrencyCode: "XXX", Units: 1}}, ToCode: "EUR"}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Currency.ConvertBatch(context.Background(), in); !IsNotFound(err) {
+			t.Errorf("call %d: err = %v, want the 404 of the batch call", i, err)
+		}
+	}
+	if batchCalls != 2 || singleCalls != 0 {
+		t.Errorf("got %d batch calls and %d single conversions, want batching kept on", batchCalls, singleCalls)
+	}
+}
+
+func TestConvertBatchFallbackBound(t *testing.T) {
+	var inFlight, most int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/batch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&most)
+			if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		json.NewEncoder(w).Encode(&Money{CurrencyCode: "EUR", Units: 1})
+	}))
+	defer srv.Close()
+
+	c := New(WithServiceURL(CurrencyService, srv.URL))
+	in := &CurrencyConversionBatchRequest{ToCode: "EUR"}
+	for i := 0; i < 3*maxParallelConversions; i++ {
+		in.From = append(in.From, &Money{CurrencyCode: "USD", Units: int64(i)})
+	}
+	if _, err := c.Currency.ConvertBatch(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if most > maxParallelConversions {
+		t.Errorf("%d conversions in flight at once, want at most %d", most, maxParallelConversions)
+	}
+}
@@ -0,0 +1,121 @@
+package shop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Sentinel errors matched by errors.Is against an *Error with the
+// corresponding status.
+var (
+	ErrBadRequest    = errors.New("bad request")
+	ErrNotFound      = errors.New("not found")
+	ErrConflict      = errors.New("conflict")
+	ErrUnprocessable = errors.New("unprocessable")
+	ErrInternal      = errors.New("internal error")
+	ErrUnavailable   = errors.New("unavailable")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrConflict,
+	http.StatusUnprocessableEntity: ErrUnprocessable,
+	http.StatusInternalServerError: ErrInternal,
+	http.StatusServiceUnavailable:  ErrUnavailable,
+}
+
+// InjectedFaultCode is the envelope code of the errors a Client configured
+// WithFaults answers calls with, instead of sending them.
+const InjectedFaultCode = "injected_fault"
+
+// Error is returned by every Client method that fails. StatusCode is zero
+// when no response was received.
+type Error struct {
+	Service    Service
+	Op         string
+	StatusCode int
+	// Body holds the response body of a non-2xx response.
+	Body []byte
+	// Envelope is the decoded body when it is an ErrorResponse, as answered
+	// by the Go functions.
+	Envelope *ErrorResponse
+	// Err is the underlying transport or decoding error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s: %v", e.Service, e.Op, e.Err)
+	}
+	if e.Envelope != nil {
+		return fmt.Sprintf("%s %s: %s (%s, status %d)", e.Service, e.Op, e.Envelope.Message, e.Envelope.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: unexpected status %d %s", e.Service, e.Op, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is the sentinel error for the status of e.
+func (e *Error) Is(target error) bool {
+	return e.StatusCode != 0 && statusErrors[e.StatusCode] == target
+}
+
+// Code returns the error code of the envelope, or "" without one.
+func (e *Error) Code() string {
+	return e.Envelope.GetCode()
+}
+
+// RequestID returns the ID the failed function logged the request under, or
+// "" when it did not answer with an envelope.
+func (e *Error) RequestID() string {
+	return e.Envelope.GetRequestId()
+}
+
+// decodeEnvelope returns the ErrorResponse in body, or nil if body is not
+// one.
+func decodeEnvelope(body []byte) *ErrorResponse {
+	env := new(ErrorResponse)
+	if json.Unmarshal(body, env) != nil || env.Code == "" {
+		return nil
+	}
+	return env
+}
+
+// IsStatus reports whether err is an *Error for a response with status code.
+func IsStatus(err error, code int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == code
+}
+
+// IsNotFound reports whether err is an *Error for a 404 response.
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+// IsRetryable reports whether the request that failed with err may succeed
+// if sent again: transport failures, responses whose envelope says so, and
+// 429 and 5xx responses without an envelope.
+func IsRetryable(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	if e.Envelope != nil {
+		return e.Envelope.Retryable
+	}
+	if e.StatusCode == 0 {
+		var ue *url.Error
+		return errors.As(e.Err, &ue) &&
+			!errors.Is(e.Err, context.Canceled) && !errors.Is(e.Err, context.DeadlineExceeded)
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func errBatchLength(got, want int) error {
+	return fmt.Errorf("batch conversion returned %d results for %d amounts", got, want)
+}
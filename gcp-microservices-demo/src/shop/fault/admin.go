@@ -0,0 +1,114 @@
+package fault
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AdminSuffix ends the path of the route listing and replacing the rules of
+// a Go function, as in GET or PUT /product/admin/faults. The frontend
+// serves the rules of its clients on /admin/faults.
+const AdminSuffix = "/admin/faults"
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256, keyed with
+// FAULT_ADMIN_KEY, of the Change put on the admin route, or of the query of
+// a GET of it.
+const SignatureHeader = "X-Fault-Signature"
+
+// maxValidity bounds how far in the future a request to the admin route may
+// expire, which bounds how long a captured request can be replayed.
+const maxValidity = 10 * time.Minute
+
+// Change is the body of a PUT of rules.
+type Change struct {
+	Rules []Rule `json:"rules"`
+	// Expires is the Unix time after which the request is rejected.
+	Expires int64 `json:"expires"`
+}
+
+// Sign returns the body of change and its signature with key.
+func Sign(key []byte, change Change) (body []byte, signature string) {
+	body, _ = json.Marshal(change)
+	return body, sign(key, body)
+}
+
+// SignList returns the query of a GET of the rules that expires at
+// expires, and its signature with key.
+func SignList(key []byte, expires int64) (query, signature string) {
+	query = "expires=" + strconv.FormatInt(expires, 10)
+	return query, sign(key, []byte(query))
+}
+
+func sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AdminHandler answers a GET with the rules of i, and replaces them with the
+// Rules of a PUT of a Change. Both must be signed with the admin key, and
+// expire within maxValidity. A nil Injector, or one without an admin key,
+// answers 404.
+func (i *Injector) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if i == nil || len(i.adminKey) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		var signed []byte
+		switch r.Method {
+		case http.MethodGet:
+			signed = []byte(r.URL.RawQuery)
+		case http.MethodPut:
+			body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			signed = body
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		got, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+		want, _ := hex.DecodeString(sign(i.adminKey, signed))
+		if err != nil || !hmac.Equal(got, want) {
+			i.log.WithField("remote_addr", r.RemoteAddr).Warn("rejected a fault admin request with a bad signature")
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+
+		var change Change
+		if r.Method == http.MethodGet {
+			change.Expires, err = strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		} else {
+			err = json.Unmarshal(signed, &change)
+		}
+		if err != nil {
+			http.Error(w, "malformed request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if expires := time.Unix(change.Expires, 0); time.Now().After(expires) || time.Until(expires) > maxValidity {
+			http.Error(w, fmt.Sprintf("the request must expire within %v", maxValidity), http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodPut {
+			rules, err := Parse(signed)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			i.SetRules(rules)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Rules{Rules: i.Rules()})
+	})
+}
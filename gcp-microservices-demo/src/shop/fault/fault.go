@@ -0,0 +1,287 @@
+// Package fault injects faults into the Go functions and the calls the
+// frontend makes to them, for chaos experiments: latency, errors with a
+// chosen status, dropped connections and malformed JSON.
+//
+// Faults are described by Rules, read from the file in FAULT_RULES and
+// replaced at runtime on the admin route of an Injector. A fission.Router
+// injects them into the requests it serves, and a shop.Client configured
+// WithFaults into the calls it sends.
+package fault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// Sides a Rule applies to.
+const (
+	Server = "server"
+	Client = "client"
+)
+
+// Kinds of faults, as logged in the fault field and recorded in the
+// FaultKey attribute of telemetry.FaultsInjected.
+const (
+	Latency   = "latency"
+	Status    = "error"
+	Drop      = "drop"
+	Malformed = "malformed"
+)
+
+// Field is the log field naming the fault injected into a request.
+const Field = "fault"
+
+// Message is the message of the errors injected.
+const Message = "injected fault"
+
+// ErrDropped is the error of a call whose connection a fault dropped.
+var ErrDropped = errors.New(Message + ": connection dropped")
+
+// Duration is a time.Duration written as in "250ms" in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("durations are strings such as \"250ms\": %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Rule injects a fault into the requests it matches. Empty conditions
+// match every request.
+type Rule struct {
+	// Name identifies the rule in the logs.
+	Name string `json:"name,omitempty"`
+	// Side restricts the rule to the Server or Client side.
+	Side string `json:"side,omitempty"`
+	// Service is the name of a function, as in "productcatalogservice".
+	Service string `json:"service,omitempty"`
+	// Route is an operation name, as in "GetProduct".
+	Route  string `json:"route,omitempty"`
+	Method string `json:"method,omitempty"`
+	// Header lists headers the request must carry with these values. On
+	// the client side, the headers are those of the outgoing call.
+	Header map[string]string `json:"header,omitempty"`
+	// Percent is the share of the matching requests faulted, above 0 and
+	// at most 100. It is required, so that a rule left without one faults
+	// nothing rather than everything.
+	Percent float64 `json:"percent,omitempty"`
+
+	// Delay delays the request. It may be combined with one of the faults
+	// below.
+	Delay Duration `json:"delay,omitempty"`
+	// Status answers with an error envelope of this status.
+	Status int `json:"status,omitempty"`
+	// Drop closes the connection without answering.
+	Drop bool `json:"drop,omitempty"`
+	// Malformed truncates the JSON of the response.
+	Malformed bool `json:"malformed,omitempty"`
+}
+
+// Kind returns the kind of fault the rule injects.
+func (r Rule) Kind() string {
+	switch {
+	case r.Drop:
+		return Drop
+	case r.Status != 0:
+		return Status
+	case r.Malformed:
+		return Malformed
+	}
+	return Latency
+}
+
+func (r Rule) validate() error {
+	faults := 0
+	for _, set := range []bool{r.Drop, r.Status != 0, r.Malformed} {
+		if set {
+			faults++
+		}
+	}
+	switch {
+	case faults > 1:
+		return fmt.Errorf("rule %q injects more than one of status, drop and malformed", r.Name)
+	case faults == 0 && r.Delay <= 0:
+		return fmt.Errorf("rule %q injects no fault", r.Name)
+	case r.Status != 0 && (r.Status < 400 || r.Status > 599):
+		return fmt.Errorf("rule %q: status %d is not an error", r.Name, r.Status)
+	case r.Percent <= 0 || r.Percent > 100:
+		return fmt.Errorf("rule %q: percent must be above 0 and at most 100", r.Name)
+	case r.Side != "" && r.Side != Server && r.Side != Client:
+		return fmt.Errorf("rule %q: side must be %q or %q", r.Name, Server, Client)
+	}
+	return nil
+}
+
+func (r Rule) matches(side, service, route, method string, header http.Header) bool {
+	if r.Side != "" && r.Side != side ||
+		r.Service != "" && r.Service != service ||
+		r.Route != "" && r.Route != route ||
+		r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	for k, v := range r.Header {
+		if header.Get(k) != v {
+			return false
+		}
+	}
+	return rand.Float64()*100 < r.Percent
+}
+
+// Rules is the content of a rules file and of the admin route.
+type Rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Parse parses and validates rules in JSON.
+func Parse(b []byte) ([]Rule, error) {
+	var rules Rules
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("fault: malformed rules: %v", err)
+	}
+	for _, r := range rules.Rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("fault: %v", err)
+		}
+	}
+	return rules.Rules, nil
+}
+
+// Injector holds the rules of one side of a service.
+type Injector struct {
+	side     string
+	log      logrus.FieldLogger
+	adminKey []byte
+
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+// New returns an Injector of side with rules, logging the rules it is given
+// to log.
+func New(side string, log logrus.FieldLogger, rules ...Rule) *Injector {
+	return &Injector{side: side, log: log, rules: rules}
+}
+
+// FromEnv returns the Injector of side with the rules in the file named by
+// FAULT_RULES, whose admin route is signed with FAULT_ADMIN_KEY. It returns
+// nil, and injects nothing, when neither is set.
+func FromEnv(side string, log logrus.FieldLogger) (*Injector, error) {
+	path, key := os.Getenv("FAULT_RULES"), os.Getenv("FAULT_ADMIN_KEY")
+	if path == "" && key == "" {
+		return nil, nil
+	}
+	inj := New(side, log)
+	inj.adminKey = []byte(key)
+	if path == "" {
+		return inj, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return inj, fmt.Errorf("fault: %v", err)
+	}
+	rules, err := Parse(b)
+	if err != nil {
+		return inj, err
+	}
+	inj.SetRules(rules)
+	return inj, nil
+}
+
+// Rules returns the rules of i.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return append([]Rule{}, i.rules...)
+}
+
+// SetRules replaces the rules of i.
+func (i *Injector) SetRules(rules []Rule) {
+	i.mu.Lock()
+	i.rules = rules
+	i.mu.Unlock()
+	i.log.WithField("rules", len(rules)).Warn("fault injection rules set")
+}
+
+// Match returns the first rule matching a request of method to route of
+// service, and whether one does. A nil Injector matches nothing.
+func (i *Injector) Match(service, route, method string, header http.Header) (Rule, bool) {
+	if i == nil {
+		return Rule{}, false
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, r := range i.rules {
+		if r.matches(i.side, service, route, method, header) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Record tags the injection of rule into a request to route of service: it
+// logs the fault to log, or to the logger of i when nil, and counts it in
+// telemetry.FaultsInjected.
+func (i *Injector) Record(ctx context.Context, log logrus.FieldLogger, rule Rule, service, route string) {
+	if log == nil {
+		log = i.log
+	}
+	log.WithFields(logrus.Fields{
+		Field:        rule.Kind(),
+		"fault_rule": rule.Name,
+		"fault_side": i.side,
+		"service":    service,
+		"route":      route,
+	}).Warn("fault injected")
+	telemetry.Count(ctx, telemetry.FaultsInjected, 1,
+		telemetry.ServiceKey.String(service),
+		telemetry.OperationKey.String(route),
+		telemetry.FaultKey.String(rule.Kind()),
+		telemetry.SideKey.String(i.side),
+	)
+}
+
+// Sleep waits for the delay of rule, or until ctx is done.
+func Sleep(ctx context.Context, rule Rule) error {
+	if rule.Delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(rule.Delay))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Malform truncates the JSON document b, so that it no longer parses.
+func Malform(b []byte) []byte {
+	if len(b) < 2 {
+		return []byte("{")
+	}
+	return b[:len(b)/2]
+}
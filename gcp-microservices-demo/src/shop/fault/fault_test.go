@@ -0,0 +1,174 @@
+package fault
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func discard() logrus.FieldLogger {
+	log := logrus.New()
+	log.Out = io.Discard
+	return log
+}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse([]byte(`{"rules":[
+		{"name":"slow catalog","service":"productcatalogservice","delay":"250ms","percent":100},
+		{"name":"flaky cart","route":"GetCart","status":503,"percent":10},
+		{"route":"PlaceOrder","side":"client","drop":true,"percent":100}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || time.Duration(rules[0].Delay) != 250*time.Millisecond || rules[1].Kind() != Status || rules[2].Kind() != Drop || rules[0].Kind() != Latency {
+		t.Errorf("parsed %+v", rules)
+	}
+
+	for _, bad := range []string{
+		`{"rules":[{"name":"nothing","percent":100}]}`,
+		`{"rules":[{"status":503,"drop":true,"percent":100}]}`,
+		`{"rules":[{"status":200,"percent":100}]}`,
+		`{"rules":[{"status":500}]}`,
+		`{"rules":[{"status":500,"percent":101}]}`,
+		`{"rules":[{"status":500,"side":"both","percent":100}]}`,
+		`{"rules":[{"delay":250,"percent":100}]}`,
+		`{"rules":`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Parse(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	inj := New(Server, discard(),
+		Rule{Name: "header", Route: "GetProduct", Header: map[string]string{"X-Chaos": "on"}, Status: 500, Percent: 100},
+		Rule{Name: "client only", Side: Client, Status: 502, Percent: 100},
+		Rule{Name: "posts", Service: "checkoutservice", Method: "post", Drop: true, Percent: 100},
+		Rule{Name: "some", Route: "ListProducts", Percent: 25, Malformed: true},
+	)
+	chaos := http.Header{"X-Chaos": []string{"on"}}
+	for _, tt := range []struct {
+		service, route, method string
+		header                 http.Header
+		want                   string
+	}{
+		{"productcatalogservice", "GetProduct", http.MethodGet, chaos, "header"},
+		{"productcatalogservice", "GetProduct", http.MethodGet, nil, ""},
+		{"checkoutservice", "PlaceOrder", http.MethodPost, nil, "posts"},
+		{"checkoutservice", "PlaceOrder", http.MethodGet, nil, ""},
+	} {
+		rule, ok := inj.Match(tt.service, tt.route, tt.method, tt.header)
+		if ok != (tt.want != "") || rule.Name != tt.want {
+			t.Errorf("%s %s %s matched %q, want %q", tt.method, tt.service, tt.route, rule.Name, tt.want)
+		}
+	}
+
+	const n = 10000
+	matched := 0
+	for i := 0; i < n; i++ {
+		if _, ok := inj.Match("productcatalogservice", "ListProducts", http.MethodGet, nil); ok {
+			matched++
+		}
+	}
+	if got := float64(matched) / n; got < 0.22 || got > 0.28 {
+		t.Errorf("faulted %.3f of the requests, want 0.25", got)
+	}
+
+	if _, ok := New(Server, discard(), Rule{Name: "unset", Status: 500}).Match("productcatalogservice", "GetProduct", http.MethodGet, nil); ok {
+		t.Error("a rule without a percent matched")
+	}
+
+	var none *Injector
+	if _, ok := none.Match("productcatalogservice", "GetProduct", http.MethodGet, chaos); ok {
+		t.Error("a nil Injector matched")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("FAULT_RULES", "")
+	t.Setenv("FAULT_ADMIN_KEY", "")
+	if inj, err := FromEnv(Server, discard()); inj != nil || err != nil {
+		t.Errorf("without settings: %v, %v", inj, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`{"rules":[{"route":"GetProduct","status":500,"percent":100}]}`), 0o644)
+	t.Setenv("FAULT_RULES", path)
+	inj, err := FromEnv(Server, discard())
+	if err != nil || len(inj.Rules()) != 1 {
+		t.Errorf("from %s: %v, %v", path, inj.Rules(), err)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	key := []byte("secret")
+	inj := New(Client, discard())
+	inj.adminKey = key
+	serve := func(method, target string, body []byte, sig string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, sig)
+		inj.AdminHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	put := func(change Change, key []byte) *httptest.ResponseRecorder {
+		body, sig := Sign(key, change)
+		return serve(http.MethodPut, AdminSuffix, body, sig)
+	}
+	soon := time.Now().Add(time.Minute).Unix()
+	slow := []Rule{{Name: "slow", Delay: Duration(time.Second), Percent: 100}}
+
+	if rec := put(Change{Rules: slow, Expires: soon}, []byte("guess")); rec.Code != http.StatusForbidden {
+		t.Errorf("wrong key: %d %s", rec.Code, rec.Body)
+	}
+	if rec := put(Change{Rules: slow, Expires: time.Now().Add(-time.Second).Unix()}, key); rec.Code != http.StatusForbidden {
+		t.Errorf("expired: %d %s", rec.Code, rec.Body)
+	}
+	if rec := put(Change{Rules: slow, Expires: time.Now().Add(time.Hour).Unix()}, key); rec.Code != http.StatusForbidden {
+		t.Errorf("expiring too late: %d %s", rec.Code, rec.Body)
+	}
+	if rec := put(Change{Rules: slow}, key); rec.Code != http.StatusForbidden {
+		t.Errorf("without expiry: %d %s", rec.Code, rec.Body)
+	}
+	if rec := put(Change{Rules: []Rule{{Name: "nothing", Percent: 100}}, Expires: soon}, key); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid rules: %d %s", rec.Code, rec.Body)
+	}
+	if len(inj.Rules()) != 0 {
+		t.Fatalf("rejected requests set the rules %+v", inj.Rules())
+	}
+	if rec := put(Change{Rules: slow, Expires: soon}, key); rec.Code != http.StatusOK {
+		t.Fatalf("signed rules: %d %s", rec.Code, rec.Body)
+	}
+
+	query, sig := SignList(key, soon)
+	if rec := serve(http.MethodGet, AdminSuffix+"?"+query, nil, sig); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"delay":"1s"`) {
+		t.Errorf("signed GET: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(http.MethodGet, AdminSuffix, nil, ""); rec.Code != http.StatusForbidden || strings.Contains(rec.Body.String(), "slow") {
+		t.Errorf("unsigned GET: %d %s", rec.Code, rec.Body)
+	}
+	later, _ := SignList(key, soon+1)
+	if rec := serve(http.MethodGet, AdminSuffix+"?"+later, nil, sig); rec.Code != http.StatusForbidden {
+		t.Errorf("GET with the signature of another expiry: %d %s", rec.Code, rec.Body)
+	}
+	query, sig = SignList(key, time.Now().Add(-time.Second).Unix())
+	if rec := serve(http.MethodGet, AdminSuffix+"?"+query, nil, sig); rec.Code != http.StatusForbidden {
+		t.Errorf("expired GET: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := httptest.NewRecorder()
+	New(Server, discard()).AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, AdminSuffix, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without a key: %d", rec.Code)
+	}
+}
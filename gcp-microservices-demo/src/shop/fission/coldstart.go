@@ -0,0 +1,110 @@
+package fission
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ColdStartSuffix ends the path of the debug route reporting the cold-start
+// timing of a function, as in GET /product/debug/coldstart.
+const ColdStartSuffix = "/debug/coldstart"
+
+// Log fields of the cold-start timing. Every entry logged while serving a
+// request carries ColdField; the "cold start" entry logged by the first
+// request of a process also carries the others, which the coldstart report
+// tool aggregates.
+const (
+	ColdField         = "cold"
+	ServiceField      = "service"
+	InitField         = "init_ms"
+	FirstRequestField = "first_request_ms"
+	ColdStartMessage  = "cold start"
+)
+
+// processStart approximates the start of the function: this package is
+// initialized before the function's own package, whose init does the
+// function's setup. In the Fission Go environment the function is loaded as
+// a plugin when the pool manager specializes a pod, so this is the start of
+// specialization rather than of the pod.
+var processStart = time.Now()
+
+// ColdStart is the cold-start timing of a function, served on its
+// ColdStartSuffix route.
+type ColdStart struct {
+	Service      string    `json:"service"`
+	ProcessStart time.Time `json:"process_start"`
+	InitDone     time.Time `json:"init_done"`
+	FirstRequest time.Time `json:"first_request,omitempty"`
+	// InitMs is the time from ProcessStart to InitDone, and FirstRequestMs
+	// the time from ProcessStart to FirstRequest.
+	InitMs         float64 `json:"init_ms"`
+	FirstRequestMs float64 `json:"first_request_ms,omitempty"`
+	Requests       int64   `json:"requests"`
+}
+
+// coldStart records the timing of the process serving a Router. A function
+// creates its Router last in its init, which marks the init done.
+type coldStart struct {
+	mu           sync.Mutex
+	initDone     time.Time
+	firstRequest time.Time
+	requests     int64
+}
+
+func newColdStart() *coldStart {
+	return &coldStart{initDone: time.Now()}
+}
+
+// request counts a request and reports whether it is the first one the
+// process serves.
+func (c *coldStart) request() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	if c.requests > 1 {
+		return false
+	}
+	c.firstRequest = time.Now()
+	return true
+}
+
+func (c *coldStart) report(service string) ColdStart {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs := ColdStart{
+		Service:      service,
+		ProcessStart: processStart,
+		InitDone:     c.initDone,
+		InitMs:       millis(c.initDone.Sub(processStart)),
+		Requests:     c.requests,
+	}
+	if !c.firstRequest.IsZero() {
+		cs.FirstRequest = c.firstRequest
+		cs.FirstRequestMs = millis(c.firstRequest.Sub(processStart))
+	}
+	return cs
+}
+
+// logFields returns the fields of the "cold start" log entry.
+func (cs ColdStart) logFields() logrus.Fields {
+	return logrus.Fields{
+		ServiceField:      cs.Service,
+		InitField:         cs.InitMs,
+		FirstRequestField: cs.FirstRequestMs,
+		"process_start":   cs.ProcessStart.Format(time.RFC3339Nano),
+	}
+}
+
+func (rt *Router) serveColdStart(w http.ResponseWriter) {
+	body, _ := json.Marshal(rt.cold.report(rt.svc.String()))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,143 @@
+package fission
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// Error is an error that answers the request with Status.
+type Error struct {
+	Status int
+	// Details are listed in the details of the error envelope.
+	Details []string
+	Err     error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Errorf returns an error answering with status. The message is formatted
+// like fmt.Errorf, so %w wraps an error.
+func Errorf(status int, format string, args ...interface{}) error {
+	return &Error{Status: status, Err: fmt.Errorf(format, args...)}
+}
+
+// BadRequest returns an error answering 400.
+func BadRequest(format string, args ...interface{}) error {
+	return Errorf(http.StatusBadRequest, format, args...)
+}
+
+// Conflict returns an error answering 409.
+func Conflict(format string, args ...interface{}) error {
+	return Errorf(http.StatusConflict, format, args...)
+}
+
+// Unprocessable returns an error answering 422, for well-formed requests
+// that cannot be carried out.
+func Unprocessable(format string, args ...interface{}) error {
+	return Errorf(http.StatusUnprocessableEntity, format, args...)
+}
+
+// NotFound returns an error answering 404.
+func NotFound(format string, args ...interface{}) error {
+	return Errorf(http.StatusNotFound, format, args...)
+}
+
+// Unavailable returns an error answering 503.
+func Unavailable(format string, args ...interface{}) error {
+	return Errorf(http.StatusServiceUnavailable, format, args...)
+}
+
+// WithDetails adds details to the envelope err answers with.
+func WithDetails(err error, details ...string) error {
+	e := &Error{Status: StatusOf(err), Err: err}
+	errors.As(err, &e)
+	return &Error{Status: e.Status, Details: append(append([]string(nil), e.Details...), details...), Err: err}
+}
+
+// StatusOf returns the status err answers with: the Status of an *Error it
+// wraps, or 500.
+func StatusOf(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// grpcError converts err into a gRPC status error with the code matching
+// its status.
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var c codes.Code
+	switch StatusOf(err) {
+	case http.StatusBadRequest:
+		c = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		c = codes.Unauthenticated
+	case http.StatusForbidden:
+		c = codes.PermissionDenied
+	case http.StatusNotFound:
+		c = codes.NotFound
+	case http.StatusConflict:
+		c = codes.Aborted
+	case http.StatusUnprocessableEntity:
+		c = codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		c = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		c = codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		c = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		c = codes.DeadlineExceeded
+	default:
+		c = codes.Internal
+	}
+	return status.Error(c, err.Error())
+}
+
+// errorCodes are the envelope codes of the statuses the functions answer with.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusNotFound:            "not_found",
+	http.StatusMethodNotAllowed:    "method_not_allowed",
+	http.StatusConflict:            "conflict",
+	http.StatusUnprocessableEntity: "unprocessable",
+	http.StatusTooManyRequests:     "too_many_requests",
+	http.StatusInternalServerError: "internal",
+	http.StatusServiceUnavailable:  "unavailable",
+	http.StatusGatewayTimeout:      "timeout",
+}
+
+// envelope returns the ErrorResponse for err answering with code. Internal
+// errors are not described to the caller; they are logged instead.
+func envelope(err error, code int, requestID string) *shop.ErrorResponse {
+	env := &shop.ErrorResponse{
+		Code:      errorCodes[code],
+		Message:   err.Error(),
+		RequestId: requestID,
+		Retryable: code == http.StatusServiceUnavailable || code == http.StatusTooManyRequests || code == http.StatusGatewayTimeout,
+	}
+	if env.Code == "" {
+		env.Code = strings.ReplaceAll(strings.ToLower(http.StatusText(code)), " ", "_")
+	}
+	if code == http.StatusInternalServerError {
+		env.Message = "internal error"
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		env.Details = e.Details
+	}
+	return env
+}
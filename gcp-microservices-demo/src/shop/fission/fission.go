@@ -0,0 +1,75 @@
+// Package fission is the plumbing shared by the Go Fission functions of the
+// shop: routing by method and query shape, decoding and validating input,
+// encoding output, mapping errors to status codes and recovering panics.
+//
+// A function declares its operations as Routes and serves them with a
+// Router:
+//
+//	router = fission.NewRouter(shop.CatalogService, log,
+//		fission.Route{Name: "GetProduct", Method: http.MethodGet, Params: []string{"id"},
+//			New: func() interface{} { return new(GetProductRequest) }, Call: getProduct},
+//	)
+//
+//	func Handler(w http.ResponseWriter, r *http.Request) { router.ServeHTTP(w, r) }
+//
+// The same routes are served over Connect on the function's route and, via
+// RPC, over gRPC.
+package fission
+
+import (
+	"context"
+	"net/http"
+)
+
+// Route is one operation of a function.
+type Route struct {
+	// Name is the operation name, e.g. "GetProduct". It is also the gRPC
+	// and Connect method name.
+	Name string
+	// Method is the HTTP method of the route.
+	Method string
+	// Params are the query parameters the route requires, and Optional
+	// those it also takes. A GET or DELETE request matches the first route
+	// whose Params it carries, with no parameter outside Params and
+	// Optional; each is decoded into the field of the input message with
+	// the same JSON name. Other methods decode the input from the JSON body.
+	Params   []string
+	Optional []string
+	// New allocates the input message. Routes without input leave it nil.
+	New func() interface{}
+	// Call handles the decoded input and returns the output message. Errors
+	// made with Errorf and its helpers set the response status; any other
+	// error is an internal error. Failures are answered with a
+	// shop.ErrorResponse.
+	Call func(ctx context.Context, in interface{}) (interface{}, error)
+}
+
+// Validator is implemented by input messages that check themselves after
+// decoding. A failed validation answers 422 with the error as detail.
+type Validator interface {
+	Validate() error
+}
+
+// hasQueryInput reports whether requests with method carry their input in
+// the query string.
+func hasQueryInput(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodDelete
+}
+
+// matches reports whether the query parameters of r are all of rt.Params
+// and any of rt.Optional.
+func (rt *Route) matches(r *http.Request) bool {
+	q := r.URL.Query()
+	for _, p := range rt.Params {
+		if _, ok := q[p]; !ok {
+			return false
+		}
+	}
+	n := len(rt.Params)
+	for _, p := range rt.Optional {
+		if _, ok := q[p]; ok {
+			n++
+		}
+	}
+	return n == len(q)
+}
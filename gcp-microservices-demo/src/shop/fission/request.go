@@ -0,0 +1,107 @@
+package fission
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// Log fields identifying the request a log entry belongs to. The frontend
+// logs under the same names, so one query finds a request in the logs of
+// every function it reached.
+const (
+	RequestIDField = "http.req.id"
+	TraceIDField   = "trace_id"
+)
+
+// request is the state of a request being served.
+type request struct {
+	log   logrus.FieldLogger
+	call  *telemetry.Call
+	route string
+}
+
+// setRoute names the route serving req, once it is known.
+func (req *request) setRoute(name string) {
+	req.route = name
+	req.call.SetOperation(name)
+}
+
+type requestKey struct{}
+
+// Logger returns the logger of the request served with ctx, which logs the
+// request and trace IDs with every entry. Outside a request it returns the
+// standard logger.
+func Logger(ctx context.Context) logrus.FieldLogger {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req.log
+	}
+	return logrus.StandardLogger()
+}
+
+// startRequest returns a copy of ctx carrying the request metadata, the
+// server span of the request and its logger, and reports whether it
+// started them: a Connect call is started by ServeHTTP before the route
+// serving it is known. A request sent without an ID is assigned one, which
+// the calls made while serving it carry on. The first request of the process
+// is flagged cold, and logs the cold-start timing.
+func (rt *Router) startRequest(ctx context.Context) (context.Context, *request, bool) {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return ctx, req, false
+	}
+	md, _ := shop.FromContext(ctx)
+	if md.RequestID == "" {
+		md.RequestID = newRequestID()
+	}
+	ctx = shop.NewContext(ctx, md)
+	req := new(request)
+	ctx, req.call = telemetry.StartServer(ctx, rt.svc.String(), "")
+
+	cold := rt.cold.request()
+	fields := logrus.Fields{RequestIDField: md.RequestID, ColdField: cold}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		fields[TraceIDField] = sc.TraceID().String()
+	}
+	req.log = rt.log.WithFields(fields)
+	if cold {
+		req.log.WithFields(rt.cold.report(rt.svc.String()).logFields()).Info(ColdStartMessage)
+	}
+	return context.WithValue(ctx, requestKey{}, req), req, true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder records the status of a response, and calls written with
+// it before the response is sent.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written func(status int)
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+		s.written(code)
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+		s.written(s.status)
+	}
+	return s.ResponseWriter.Write(b)
+}
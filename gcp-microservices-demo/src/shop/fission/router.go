@@ -0,0 +1,414 @@
+package fission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fault"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// MetricsSuffix ends the path of the metrics route of every function, as in
+// GET /product/metrics.
+const MetricsSuffix = "/metrics"
+
+// Router serves the routes of one function, its metrics on MetricsSuffix
+// and its cold-start timing on ColdStartSuffix.
+type Router struct {
+	svc     shop.Service
+	log     logrus.FieldLogger
+	logging *logging.Logger
+	faults  *fault.Injector
+	routes  []Route
+	rpc     *rpc.Service
+	cold    *coldStart
+}
+
+// NewRouter returns a Router serving routes as the operations of svc, and
+// logging failures to log. When log is a *logging.Logger, the access logs
+// of successful requests are sampled by route name, and its level can be
+// changed on logging.AdminSuffix. Faults are injected as configured by
+// fault.FromEnv, and their rules served on fault.AdminSuffix. A function
+// creates its Router last in its init, which NewRouter records as the end
+// of the function's initialization.
+func NewRouter(svc shop.Service, log logrus.FieldLogger, routes ...Route) *Router {
+	rt := &Router{svc: svc, log: log, routes: routes, cold: newColdStart()}
+	rt.logging, _ = log.(*logging.Logger)
+	var err error
+	if rt.faults, err = fault.FromEnv(fault.Server, log); err != nil {
+		log.Warnf("fault injection: %v", err)
+	}
+	if rpc.ServiceName(svc) != "" {
+		methods := make([]rpc.Method, len(routes))
+		for i := range routes {
+			methods[i] = rt.rpcMethod(&routes[i])
+		}
+		rt.rpc = rpc.NewService(svc, methods...)
+	}
+	return rt
+}
+
+// RPC returns the routes as an rpc.Service, or nil if svc is not served over
+// gRPC.
+func (rt *Router) RPC() *rpc.Service {
+	return rt.rpc
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Scrapes and debug requests are served before the request is
+	// started, so that they do not count in what they report, nor fill the
+	// logs.
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, MetricsSuffix) {
+		telemetry.MetricsHandler().ServeHTTP(w, r)
+		return
+	}
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, ColdStartSuffix) {
+		rt.serveColdStart(w)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, logging.AdminSuffix) {
+		if rt.logging == nil {
+			http.NotFound(w, r)
+			return
+		}
+		rt.logging.AdminHandler().ServeHTTP(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, fault.AdminSuffix) {
+		rt.faults.AdminHandler().ServeHTTP(w, r)
+		return
+	}
+	ctx := telemetry.Extract(r.Context(), r.Header)
+	ctx, req, _ := rt.startRequest(shop.NewContext(ctx, shop.MetadataFromHeader(r.Header)))
+	r = r.WithContext(ctx)
+	log := req.log
+	md, _ := shop.FromContext(ctx)
+	w.Header().Set(shop.RequestIDHeader, md.RequestID)
+
+	start := time.Now()
+	hijacker, _ := w.(http.Hijacker)
+	// The span ends before the response is sent, so that it is recorded
+	// by the time the caller sees the response.
+	rec := &statusRecorder{ResponseWriter: w, written: func(status int) { req.call.End(ctx, status, nil) }}
+	w = rec
+	var injected string
+	defer func() {
+		if injected == fault.Drop {
+			req.call.End(ctx, 0, fault.ErrDropped)
+		} else if rec.status == 0 {
+			rec.WriteHeader(http.StatusOK)
+		}
+		// Failures and faults are always logged.
+		if rec.status < 500 && injected == "" && !rt.sampled(req.route, md.RequestID) {
+			return
+		}
+		entry := log.WithFields(logrus.Fields{
+			"http.req.path":     r.URL.Path,
+			"http.req.method":   r.Method,
+			"http.resp.took_ms": int64(time.Since(start) / time.Millisecond),
+			"http.resp.status":  rec.status,
+		})
+		if injected != "" {
+			entry = entry.WithField(fault.Field, injected)
+		}
+		entry.Info("request complete")
+	}()
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			log.Errorf("panic serving %s %s: %v\n%s", r.Method, r.URL, v, debug.Stack())
+			rt.fail(w, r, fmt.Errorf("panic: %v", v))
+		}
+	}()
+	if rt.rpc != nil && rt.rpc.ServeConnect(w, r) {
+		return
+	}
+
+	route, err := rt.route(r)
+	if err != nil {
+		rt.fail(w, r, err)
+		return
+	}
+	req.setRoute(route.Name)
+	rule, faulted := rt.faults.Match(rt.svc.String(), route.Name, r.Method, r.Header)
+	if faulted {
+		injected = rule.Kind()
+		rt.faults.Record(ctx, log, rule, rt.svc.String(), route.Name)
+		if err := fault.Sleep(ctx, rule); err != nil {
+			rt.fail(w, r, Unavailable("%v", err))
+			return
+		}
+		switch injected {
+		case fault.Status:
+			rt.fail(w, r, Errorf(rule.Status, fault.Message))
+			return
+		case fault.Drop:
+			drop(hijacker)
+			return
+		}
+	}
+	in, err := decode(route, r)
+	if err != nil {
+		rt.fail(w, r, err)
+		return
+	}
+	out, err := route.Call(ctx, in)
+	if err != nil {
+		rt.fail(w, r, err)
+		return
+	}
+	body, err := json.Marshal(out)
+	if err != nil {
+		rt.fail(w, r, err)
+		return
+	}
+	if injected == fault.Malformed {
+		body = fault.Malform(body)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		log.Errorf("%s: writing the response: %v", route.Name, err)
+	}
+}
+
+// drop closes the connection of a request without answering. Without a
+// Hijacker, it aborts the handler, which the HTTP server answers by closing
+// the connection.
+func drop(hijacker http.Hijacker) {
+	if hijacker != nil {
+		if conn, _, err := hijacker.Hijack(); err == nil {
+			conn.Close()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// sampled reports whether the access log of a request to route is written.
+func (rt *Router) sampled(route, requestID string) bool {
+	return rt.logging == nil || rt.logging.Sampled(route, requestID)
+}
+
+// route finds the route for r.
+func (rt *Router) route(r *http.Request) (*Route, error) {
+	var allowed []string
+	for i := range rt.routes {
+		route := &rt.routes[i]
+		if route.Method != r.Method {
+			allowed = append(allowed, route.Method)
+			continue
+		}
+		if !hasQueryInput(r.Method) || route.matches(r) {
+			return route, nil
+		}
+	}
+	if len(allowed) == len(rt.routes) {
+		return nil, &methodError{method: r.Method, allowed: allowed}
+	}
+	return nil, BadRequest("no %s operation takes the query parameters %s", r.Method, paramNames(r))
+}
+
+func paramNames(r *http.Request) string {
+	var names []string
+	for k := range r.URL.Query() {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// methodError answers 405 with an Allow header.
+type methodError struct {
+	method  string
+	allowed []string
+}
+
+func (e *methodError) Error() string {
+	return fmt.Sprintf("method %s is not supported", e.method)
+}
+
+func (rt *Router) fail(w http.ResponseWriter, r *http.Request, err error) {
+	code := StatusOf(err)
+	if me, ok := err.(*methodError); ok {
+		code = http.StatusMethodNotAllowed
+		w.Header().Set("Allow", strings.Join(uniq(me.allowed), ", "))
+	}
+	log := Logger(r.Context())
+	trace.SpanFromContext(r.Context()).RecordError(err)
+	if code >= 500 {
+		log.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+	} else {
+		log.Warnf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	md, _ := shop.FromContext(r.Context())
+	body, _ := json.Marshal(envelope(err, code, md.RequestID))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+func uniq(ss []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range ss {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// decode reads the input message of route from r and validates it.
+func decode(route *Route, r *http.Request) (interface{}, error) {
+	if route.New == nil {
+		return nil, nil
+	}
+	in := route.New()
+	if hasQueryInput(r.Method) {
+		if err := decodeQuery(r, in); err != nil {
+			return nil, err
+		}
+	} else {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, BadRequest("reading the body: %v", err)
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, in); err != nil {
+				return nil, BadRequest("malformed JSON body: %v", err)
+			}
+		}
+	}
+	return in, validate(in)
+}
+
+func validate(in interface{}) error {
+	if v, ok := in.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return WithDetails(Unprocessable("invalid request"), err.Error())
+		}
+	}
+	return nil
+}
+
+// decodeQuery sets the fields of the struct pointed to by in from the query
+// parameters with their JSON names. Slices take a comma-separated list.
+func decodeQuery(r *http.Request, in interface{}) error {
+	v := reflect.ValueOf(in).Elem()
+	t := v.Type()
+	q := r.URL.Query()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		values, ok := q[name]
+		if name == "" || !ok {
+			continue
+		}
+		if len(values) > 1 {
+			return BadRequest("query parameter %s is given more than once", name)
+		}
+		if err := setField(v.Field(i), values[0]); err != nil {
+			return BadRequest("query parameter %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(f reflect.Value, s string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", f.Type())
+		}
+		f.Set(reflect.ValueOf(strings.Split(s, ",")))
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+// rpcMethod serves route over gRPC and Connect.
+func (rt *Router) rpcMethod(route *Route) rpc.Method {
+	newIn := route.New
+	if newIn == nil {
+		newIn = func() interface{} { return new(struct{}) }
+	}
+	return rpc.Method{
+		Name: route.Name,
+		New:  newIn,
+		Call: func(ctx context.Context, in interface{}) (out interface{}, err error) {
+			ctx, req, started := rt.startRequest(ctx)
+			log := req.log
+			req.setRoute(route.Name)
+			if started {
+				defer func() {
+					status := http.StatusOK
+					if err != nil {
+						status = StatusOf(err)
+					}
+					req.call.End(ctx, status, err)
+				}()
+			}
+			defer func() {
+				if v := recover(); v != nil {
+					log.Errorf("panic serving %s: %v\n%s", route.Name, v, debug.Stack())
+					err = grpcError(fmt.Errorf("internal error"))
+				}
+			}()
+			// Over gRPC and Connect, only delays and errors are injected.
+			if rule, ok := rt.faults.Match(rt.svc.String(), route.Name, route.Method, nil); ok && (rule.Delay > 0 || rule.Status != 0) {
+				rt.faults.Record(ctx, log, rule, rt.svc.String(), route.Name)
+				if err := fault.Sleep(ctx, rule); err != nil {
+					return nil, grpcError(Unavailable("%v", err))
+				}
+				if rule.Status != 0 {
+					return nil, grpcError(Errorf(rule.Status, fault.Message))
+				}
+			}
+			if route.New == nil {
+				in = nil
+			} else if err := validate(in); err != nil {
+				return nil, grpcError(err)
+			}
+			out, err = route.Call(ctx, in)
+			if err != nil {
+				if StatusOf(err) >= 500 {
+					log.Errorf("%s: %v", route.Name, err)
+				}
+				return nil, grpcError(err)
+			}
+			return out, nil
+		},
+	}
+}
@@ -0,0 +1,495 @@
+package fission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fault"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/logging"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry/telemetrytest"
+)
+
+type getRequest struct {
+	Id    string   `json:"id,omitempty"`
+	Keys  []string `json:"keys,omitempty"`
+	Limit int32    `json:"limit,omitempty"`
+}
+
+type postRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+func (p *postRequest) Validate() error {
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func testRouter() *Router {
+	log := logrus.New()
+	log.Out = io.Discard
+	return NewRouter(shop.CatalogService, log,
+		Route{
+			Name:   "List",
+			Method: http.MethodGet,
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				return map[string]string{"op": "list"}, nil
+			},
+		},
+		Route{
+			Name:   "Get",
+			Method: http.MethodGet,
+			Params: []string{"id"},
+			New:    func() interface{} { return new(getRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				if id := in.(*getRequest).Id; id != "1" {
+					return nil, NotFound("no item %q", id)
+				}
+				return map[string]string{"op": "get"}, nil
+			},
+		},
+		Route{
+			Name:     "Search",
+			Method:   http.MethodGet,
+			Params:   []string{"keys"},
+			Optional: []string{"limit"},
+			New:      func() interface{} { return new(getRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				req := in.(*getRequest)
+				return map[string]interface{}{"keys": req.Keys, "limit": req.Limit}, nil
+			},
+		},
+		Route{
+			Name:   "Create",
+			Method: http.MethodPost,
+			New:    func() interface{} { return new(postRequest) },
+			Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+				if in.(*postRequest).Name == "panic" {
+					panic("boom")
+				}
+				if in.(*postRequest).Name == "bug" {
+					return nil, errors.New("something broke")
+				}
+				return in, nil
+			},
+		},
+	)
+}
+
+// strictRecorder fails the test when the status is set after the body was
+// written.
+type strictRecorder struct {
+	*httptest.ResponseRecorder
+	t     *testing.T
+	wrote bool
+}
+
+func (s *strictRecorder) WriteHeader(code int) {
+	if s.wrote {
+		s.t.Errorf("WriteHeader(%d) after Write", code)
+	}
+	s.ResponseRecorder.WriteHeader(code)
+}
+
+func (s *strictRecorder) Write(b []byte) (int, error) {
+	s.wrote = true
+	return s.ResponseRecorder.Write(b)
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		method, target, body string
+		status               int
+		want                 string
+	}{
+		{"GET", "/", "", 200, `{"op":"list"}`},
+		{"GET", "/?id=1", "", 200, `{"op":"get"}`},
+		{"GET", "/?id=2", "", 404, ""},
+		{"GET", "/?id=1&id=2", "", 400, ""},
+		{"GET", "/?keys=a,b&limit=3", "", 200, `{"keys":["a","b"],"limit":3}`},
+		{"GET", "/?keys=a&limit=x", "", 400, ""},
+		{"GET", "/?keys=a", "", 200, `{"keys":["a"],"limit":0}`},
+		{"GET", "/?limit=3", "", 400, ""},
+		{"GET", "/?keys=a&limit=3&id=1", "", 400, ""},
+		{"GET", "/?query=x", "", 400, ""},
+		{"POST", "/", `{"name":"x"}`, 200, `{"name":"x"}`},
+		{"POST", "/", `{"name":`, 400, ""},
+		{"POST", "/", `{}`, 422, ""},
+		{"POST", "/", `{"name":"bug"}`, 500, ""},
+		{"POST", "/", `{"name":"panic"}`, 500, ""},
+		{"DELETE", "/", "", 405, ""},
+	}
+	rt := testRouter()
+	for _, tt := range tests {
+		rec := &strictRecorder{ResponseRecorder: httptest.NewRecorder(), t: t}
+		rt.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s %s: status %d, want %d", tt.method, tt.target, tt.body, rec.Code, tt.status)
+		}
+		if tt.want != "" && rec.Body.String() != tt.want {
+			t.Errorf("%s %s: body %s, want %s", tt.method, tt.target, rec.Body, tt.want)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", nil))
+	if got := rec.Header().Get("Allow"); got != "GET, POST" {
+		t.Errorf("Allow = %q", got)
+	}
+}
+
+func TestRPCErrors(t *testing.T) {
+	rt := testRouter()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/product/hipstershop.ProductCatalogService/Get", strings.NewReader(`{"id":"2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rt.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"not_found"`) {
+		t.Errorf("Connect Get(2) = %d %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/product/hipstershop.ProductCatalogService/Create", strings.NewReader(`{"name":"panic"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rt.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Connect Create(panic) = %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	tests := []struct {
+		method, target, body string
+		want                 shop.ErrorResponse
+	}{
+		{"GET", "/?id=2", "", shop.ErrorResponse{Code: "not_found", Message: `no item "2"`, RequestId: "req-1"}},
+		{"POST", "/", `{}`, shop.ErrorResponse{Code: "unprocessable", Message: "invalid request", Details: []string{"name is required"}, RequestId: "req-1"}},
+		{"POST", "/", `{"name":"bug"}`, shop.ErrorResponse{Code: "internal", Message: "internal error", RequestId: "req-1"}},
+		{"PUT", "/", "", shop.ErrorResponse{Code: "method_not_allowed", Message: "method PUT is not supported", RequestId: "req-1"}},
+	}
+	rt := testRouter()
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("X-Request-ID", "req-1")
+		rt.ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: content type %q", tt.method, tt.target, ct)
+		}
+		got := shop.ErrorResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(got, tt.want); diff != "" {
+			t.Errorf("%s %s %s: %s", tt.method, tt.target, tt.body, diff)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	env := envelope(Unavailable("cart is down"), http.StatusServiceUnavailable, "")
+	if !env.Retryable || env.Code != "unavailable" || env.Message != "cart is down" {
+		t.Errorf("envelope = %+v", env)
+	}
+	if env := envelope(BadRequest("x"), http.StatusBadRequest, ""); env.Retryable {
+		t.Errorf("bad requests must not be retryable")
+	}
+}
+
+// TestRequestContext checks that the request and trace IDs sent by the
+// caller reach the route and its logs over every transport.
+func TestRequestContext(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.Out = &buf
+	log.Formatter = &logrus.JSONFormatter{}
+	rt := NewRouter(shop.CatalogService, log, Route{
+		Name:   "GetProduct",
+		Method: http.MethodGet,
+		Params: []string{"id"},
+		New:    func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			Logger(ctx).Info("getting product")
+			md, _ := shop.FromContext(ctx)
+			return &shop.Product{Id: md.RequestID, Name: trace.SpanContextFromContext(ctx).TraceID().String()}, nil
+		},
+	})
+	ctx := telemetry.Extract(context.Background(), http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}})
+	ctx = shop.NewContext(ctx, shop.Metadata{RequestID: "req-1"})
+	for transport, c := range rpctest.Clients(t, shop.CatalogService, rt, rt.RPC()) {
+		buf.Reset()
+		p, err := c.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: "OLJCESPC7Z"})
+		if err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if p.GetId() != "req-1" || p.GetName() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: route saw request ID %q, trace ID %q", transport, p.GetId(), p.GetName())
+		}
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(bytes.SplitN(buf.Bytes(), []byte("\n"), 2)[0], &entry); err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if entry[RequestIDField] != "req-1" || entry[TraceIDField] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: log entry %v", transport, entry)
+		}
+	}
+}
+
+func TestRequestIDAssigned(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?id=2", nil))
+	env := new(shop.ErrorResponse)
+	json.Unmarshal(rec.Body.Bytes(), env)
+	if id := rec.Header().Get(shop.RequestIDHeader); id == "" || id != env.GetRequestId() {
+		t.Errorf("X-Request-ID %q, envelope request_id %q", id, env.GetRequestId())
+	}
+}
+
+// TestSpans checks that each call is one client span with one server span
+// below it, whatever the transport.
+func TestSpans(t *testing.T) {
+	log := logrus.New()
+	log.Out = io.Discard
+	rt := NewRouter(shop.CatalogService, log, Route{
+		Name:   "GetProduct",
+		Method: http.MethodGet,
+		Params: []string{"id"},
+		New:    func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return &shop.Product{Id: in.(*shop.GetProductRequest).Id}, nil
+		},
+	})
+	clients := rpctest.Clients(t, shop.CatalogService, rt, rt.RPC())
+	for _, transport := range rpc.Transports {
+		rec := telemetrytest.New(t)
+		if _, err := clients[transport].Catalog.GetProduct(context.Background(), &shop.GetProductRequest{Id: "OLJCESPC7Z"}); err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		want := "productcatalogservice/GetProduct (client)\n  productcatalogservice/GetProduct (server)\n"
+		if got := rec.Tree(); got != want {
+			t.Errorf("%s: spans\n%s\nwant\n%s", transport, got, want)
+		}
+		attrs := []attribute.KeyValue{telemetry.ServiceKey.String("productcatalogservice"), telemetry.OperationKey.String("GetProduct"), telemetry.StatusKey.Int(200)}
+		for _, name := range []string{telemetry.ClientDuration, telemetry.ServerDuration} {
+			if m := rec.Metric(t, name, attrs...); m.Count != 1 {
+				t.Errorf("%s: %s count = %d", transport, name, m.Count)
+			}
+		}
+	}
+}
+
+func TestMetricsRoute(t *testing.T) {
+	telemetrytest.New(t) // restores the providers Setup replaces
+	if _, err := telemetry.Setup(context.Background(), telemetry.Config{Service: "productcatalogservice", Metrics: telemetry.Prometheus}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.Out = &buf
+	rt := NewRouter(shop.CatalogService, log, Route{
+		Name:   "GetProduct",
+		Method: http.MethodGet,
+		Params: []string{"id"},
+		New:    func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return nil, NotFound("no product with ID %q", in.(*shop.GetProductRequest).Id)
+		},
+	})
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/product?id=1", nil))
+	buf.Reset()
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/product"+MetricsSuffix, nil))
+	want := `shop_server_duration_count{http_status_code="404",service_name="productcatalogservice",shop_operation="GetProduct",shop_service="productcatalogservice"} 1`
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("GET /product/metrics = %d, want %s in\n%s", rec.Code, want, rec.Body)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("scrape logged:\n%s", &buf)
+	}
+}
+
+func TestColdStart(t *testing.T) {
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.Formatter = &logrus.JSONFormatter{}
+	log.Out = &buf
+	rt := NewRouter(shop.CatalogService, log, Route{
+		Name:   "ListProducts",
+		Method: http.MethodGet,
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return &shop.ListProductsResponse{}, nil
+		},
+	})
+	coldStart := func() ColdStart {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products"+ColdStartSuffix, nil))
+		var cs ColdStart
+		if err := json.Unmarshal(rec.Body.Bytes(), &cs); err != nil {
+			t.Fatalf("%s: %v", rec.Body, err)
+		}
+		return cs
+	}
+
+	if cs := coldStart(); cs.Requests != 0 || !cs.FirstRequest.IsZero() || cs.Service != "productcatalogservice" || cs.InitMs <= 0 {
+		t.Errorf("before the first request: %+v", cs)
+	}
+	for i := 0; i < 2; i++ {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+	}
+	cs := coldStart()
+	if cs.Requests != 2 || cs.FirstRequest.Before(cs.InitDone) || cs.FirstRequestMs < cs.InitMs {
+		t.Errorf("after two requests: %+v", cs)
+	}
+
+	var cold, warm, coldStarts int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := make(map[string]interface{})
+		json.Unmarshal([]byte(line), &entry)
+		if entry["msg"] == ColdStartMessage {
+			coldStarts++
+			if entry[ServiceField] != "productcatalogservice" || entry[FirstRequestField] != cs.FirstRequestMs {
+				t.Errorf("cold start entry %s, want first request at %vms", line, cs.FirstRequestMs)
+			}
+		}
+		if entry["msg"] == "request complete" {
+			if entry[ColdField] == true {
+				cold++
+			} else {
+				warm++
+			}
+		}
+	}
+	if coldStarts != 1 || cold != 1 || warm != 1 {
+		t.Errorf("logged %d cold start entries, %d cold and %d warm requests:\n%s", coldStarts, cold, warm, &buf)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	log := logging.New(logging.Config{Level: logrus.InfoLevel, Sampling: map[string]float64{"GetProduct": 0}, AdminKey: []byte("secret"), Out: &buf})
+	rt := NewRouter(shop.CatalogService, log, Route{
+		Name:   "GetProduct",
+		Method: http.MethodGet,
+		Params: []string{"id"},
+		New:    func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			if in.(*shop.GetProductRequest).Id == "broken" {
+				return nil, errors.New("broken")
+			}
+			return &shop.Product{}, nil
+		},
+	})
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/product?id=1", nil))
+	if strings.Contains(buf.String(), "request complete") {
+		t.Errorf("logged a request sampled out:\n%s", &buf)
+	}
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/product?id=broken", nil))
+	if !strings.Contains(buf.String(), "request complete") {
+		t.Errorf("did not log a failed request:\n%s", &buf)
+	}
+
+	body, sig := logging.Sign([]byte("secret"), logging.LevelChange{Level: "debug", Expires: time.Now().Add(time.Minute).Unix()})
+	req := httptest.NewRequest(http.MethodPut, "/product"+logging.AdminSuffix, bytes.NewReader(body))
+	req.Header.Set(logging.SignatureHeader, sig)
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || log.GetLevel() != logrus.DebugLevel {
+		t.Errorf("level change: %d %s, level %s", rec.Code, rec.Body, log.GetLevel())
+	}
+}
+
+func TestFaults(t *testing.T) {
+	metrics := telemetrytest.New(t)
+	var buf bytes.Buffer
+	log := logrus.New()
+	log.Formatter = &logrus.JSONFormatter{}
+	log.Out = &buf
+	rt := NewRouter(shop.CatalogService, log, Route{
+		Name:   "GetProduct",
+		Method: http.MethodGet,
+		Params: []string{"id"},
+		New:    func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return &shop.Product{Id: in.(*shop.GetProductRequest).Id, Name: "Sunglasses"}, nil
+		},
+	})
+	rt.faults = fault.New(fault.Server, log,
+		fault.Rule{Name: "down", Header: map[string]string{"X-Chaos": "down"}, Status: http.StatusServiceUnavailable, Percent: 100},
+		fault.Rule{Name: "drop", Header: map[string]string{"X-Chaos": "drop"}, Drop: true, Percent: 100},
+		fault.Rule{Name: "garbled", Header: map[string]string{"X-Chaos": "garbled"}, Malformed: true, Percent: 100},
+		fault.Rule{Name: "slow", Header: map[string]string{"X-Chaos": "slow"}, Delay: fault.Duration(50 * time.Millisecond), Percent: 100},
+	)
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+	// The transport resends requests whose reused connection was closed.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(chaos string) (*http.Response, []byte, error) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/product?id=1", nil)
+		req.Header.Set("X-Chaos", chaos)
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return res, body, err
+	}
+
+	if res, body, err := get("down"); err != nil || res.StatusCode != http.StatusServiceUnavailable || !strings.Contains(string(body), fault.Message) {
+		t.Errorf("status fault: %v %s %v", res, body, err)
+	}
+	if _, _, err := get("drop"); err == nil {
+		t.Error("the connection was not dropped")
+	}
+	if res, body, err := get("garbled"); err != nil || res.StatusCode != http.StatusOK || json.Valid(body) {
+		t.Errorf("malformed fault: %v %s %v", res, body, err)
+	}
+	start := time.Now()
+	if res, body, err := get("slow"); err != nil || res.StatusCode != http.StatusOK || !json.Valid(body) || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("latency fault: %v %s %v after %v", res, body, err, time.Since(start))
+	}
+	if res, _, err := get(""); err != nil || res.StatusCode != http.StatusOK {
+		t.Errorf("unfaulted request: %v %v", res, err)
+	}
+
+	injected := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		entry := make(map[string]interface{})
+		json.Unmarshal([]byte(line), &entry)
+		if entry["msg"] == "request complete" {
+			if kind, ok := entry[fault.Field].(string); ok {
+				injected[kind]++
+			}
+		}
+	}
+	if want := map[string]int{fault.Status: 1, fault.Drop: 1, fault.Malformed: 1, fault.Latency: 1}; !cmp.Equal(injected, want) {
+		t.Errorf("access logs tagged %v, want %v:\n%s", injected, want, &buf)
+	}
+	for _, kind := range []string{fault.Status, fault.Drop, fault.Malformed, fault.Latency} {
+		m := metrics.Metric(t, telemetry.FaultsInjected, telemetry.FaultKey.String(kind), telemetry.OperationKey.String("GetProduct"), telemetry.SideKey.String(fault.Server))
+		if got := m.Sum.AsInt64(); got != 1 {
+			t.Errorf("%s faults counted %d times", kind, got)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/openapi"
+)
+
+const header = "// Code generated by shopgen from docs/openapi.yaml. DO NOT EDIT.\n\npackage shop\n\n"
+
+type generated struct {
+	types  []byte
+	client []byte
+}
+
+func generate(specPath string) (*generated, error) {
+	spec, err := openapi.Load(specPath)
+	if err != nil {
+		return nil, err
+	}
+	types, err := genTypes(spec)
+	if err != nil {
+		return nil, fmt.Errorf("types: %v", err)
+	}
+	client, err := genClient(spec)
+	if err != nil {
+		return nil, fmt.Errorf("client: %v", err)
+	}
+	return &generated{types: types, client: client}, nil
+}
+
+func genTypes(spec *openapi.Spec) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(header)
+	for _, sc := range spec.Schemas {
+		if sc.Type != "object" {
+			return nil, fmt.Errorf("schema %s: only object schemas can be generated", sc.Name)
+		}
+		comment(&b, "", sc.Description)
+		fmt.Fprintf(&b, "type %s struct {\n", sc.Name)
+		for _, p := range sc.Properties {
+			typ, err := goType(p.Schema)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", sc.Name, p.Name, err)
+			}
+			comment(&b, "\t", p.Description)
+			fmt.Fprintf(&b, "\t%s %s `json:\"%s,omitempty\"`\n", goName(p.Name), typ, p.Name)
+		}
+		b.WriteString("}\n\n")
+
+		for _, p := range sc.Properties {
+			typ, _ := goType(p.Schema)
+			fmt.Fprintf(&b, "func (m *%s) Get%s() %s {\n\tif m != nil {\n\t\treturn m.%s\n\t}\n\treturn %s\n}\n\n",
+				sc.Name, goName(p.Name), typ, goName(p.Name), zero(typ))
+		}
+	}
+	return format.Source(b.Bytes())
+}
+
+func genClient(spec *openapi.Spec) ([]byte, error) {
+	var body bytes.Buffer
+	imports := map[string]bool{"context": true, "net/http": true}
+	for _, op := range spec.Operations {
+		if !strings.HasPrefix(op.Path, op.Tag.Route) {
+			return nil, fmt.Errorf("%s: path %s is not below route %s", op.ID, op.Path, op.Tag.Route)
+		}
+		recv := strings.ToLower(op.Tag.Name[:1]) + "c"
+		name := op.ID
+		if op.Unexported {
+			name = strings.ToLower(name[:1]) + name[1:]
+		}
+
+		params := "ctx context.Context"
+		if op.Request != "" {
+			params += ", in *" + op.Request
+		}
+		results := "error"
+		if op.Response != "" {
+			results = fmt.Sprintf("(*%s, error)", op.Response)
+		}
+
+		fmt.Fprintf(&body, "// %s calls %s %s on %s.\n", name, op.Method, op.Path, op.Tag.Service)
+		fmt.Fprintf(&body, "func (%s *%sClient) %s(%s) %s {\n", recv, op.Tag.Name, name, params, results)
+		if op.Response != "" {
+			fmt.Fprintf(&body, "\tout := new(%s)\n", op.Response)
+		}
+
+		fields := []string{
+			"svc: " + op.Tag.GoService,
+			fmt.Sprintf("op: %q", op.ID),
+			"method: http.Method" + goName(strings.ToLower(op.Method)),
+		}
+		if suffix := strings.TrimPrefix(op.Path, op.Tag.Route); suffix != "" {
+			fields = append(fields, fmt.Sprintf("suffix: %q", suffix))
+		}
+		if len(op.Params) > 0 {
+			if op.Body || op.Request == "" {
+				return nil, fmt.Errorf("%s: query parameters need x-go-request and no body", op.ID)
+			}
+			imports["net/url"] = true
+			body.WriteString("\tv := url.Values{}\n")
+			for _, p := range op.Params {
+				field := "in." + goName(p.Name)
+				// Optional parameters are left out of the query when zero,
+				// so that the route matches without them.
+				indent := "\t"
+				if !p.Required {
+					fmt.Fprintf(&body, "\tif %s {\n", nonZero(p, field))
+					indent = "\t\t"
+				}
+				switch {
+				case p.Schema.Type == "array" && !p.Explode:
+					imports["strings"] = true
+					fmt.Fprintf(&body, "%sv.Add(%q, strings.Join(%s, \",\"))\n", indent, p.Name, field)
+				case p.Schema.Type == "array":
+					fmt.Fprintf(&body, "%sfor _, s := range %s {\n%s\tv.Add(%q, s)\n%s}\n", indent, field, indent, p.Name, indent)
+				case p.Schema.Type == "string":
+					fmt.Fprintf(&body, "%sv.Add(%q, %s)\n", indent, p.Name, field)
+				default:
+					imports["fmt"] = true
+					fmt.Fprintf(&body, "%sv.Add(%q, fmt.Sprint(%s))\n", indent, p.Name, field)
+				}
+				if !p.Required {
+					body.WriteString("\t}\n")
+				}
+			}
+			fields = append(fields, "query: v", "req: in")
+		}
+		if op.Body {
+			fields = append(fields, "in: in")
+		}
+		if op.Idempotent {
+			fields = append(fields, "idempotent: true")
+		}
+		if op.Response != "" {
+			fields = append(fields, "out: out")
+			fmt.Fprintf(&body, "\terr := %s.c.do(ctx, call{%s})\n", recv, strings.Join(fields, ", "))
+			body.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n\n")
+		} else {
+			fmt.Fprintf(&body, "\treturn %s.c.do(ctx, call{%s})\n}\n\n", recv, strings.Join(fields, ", "))
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString(header)
+	b.WriteString("import (\n")
+	for _, imp := range []string{"context", "fmt", "net/http", "net/url", "strings"} {
+		if imports[imp] {
+			fmt.Fprintf(&b, "\t%q\n", imp)
+		}
+	}
+	b.WriteString(")\n\n")
+	b.Write(body.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func goType(sc *openapi.Schema) (string, error) {
+	if sc.Ref != "" {
+		return "*" + sc.Ref, nil
+	}
+	switch sc.Type {
+	case "string":
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "number":
+		return "float64", nil
+	case "integer":
+		if sc.Format == "int32" {
+			return "int32", nil
+		}
+		return "int64", nil
+	case "array":
+		if sc.Items == nil {
+			return "", fmt.Errorf("array without items")
+		}
+		item, err := goType(sc.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + item, nil
+	}
+	return "", fmt.Errorf("unsupported schema type %q", sc.Type)
+}
+
+func zero(typ string) string {
+	switch {
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	case typ == "int32" || typ == "int64" || typ == "float64":
+		return "0"
+	}
+	return "nil"
+}
+
+// nonZero returns the condition under which the query parameter p, read
+// from field, is sent.
+func nonZero(p *openapi.Param, field string) string {
+	switch p.Schema.Type {
+	case "array":
+		return "len(" + field + ") > 0"
+	case "boolean":
+		return field
+	case "string":
+		return field + ` != ""`
+	}
+	return field + " != 0"
+}
+
+// goName turns a JSON field name such as "price_usd" into "PriceUsd".
+// It also turns a lower-cased method such as "get" into "Get".
+func goName(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func comment(b *bytes.Buffer, indent, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(b, "%s// %s\n", indent, line)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestGeneratedFilesUpToDate fails when docs/openapi.yaml changed without
+// running go generate in src/shop.
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	files, err := generate("../../../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string][]byte{"../../types.gen.go": files.types, "../../client.gen.go": files.client} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale, run go generate in src/shop", path)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Command shopgen generates the shop package's message types and client
+// stubs from the OpenAPI document of the shop API.
+//
+//	go run ./internal/shopgen -spec ../../docs/openapi.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	spec := flag.String("spec", "../../docs/openapi.yaml", "path of the OpenAPI document")
+	types := flag.String("types", "types.gen.go", "output file for the message types")
+	client := flag.String("client", "client.gen.go", "output file for the client stubs")
+	flag.Parse()
+
+	files, err := generate(*spec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "shopgen:", err)
+		os.Exit(1)
+	}
+	for path, src := range map[string][]byte{*types: files.types, *client: files.client} {
+		if err := os.WriteFile(path, src, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "shopgen:", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AdminSuffix ends the path of the route changing the level of a Go
+// function, as in PUT /product/admin/loglevel. The frontend serves it on
+// PUT /admin/loglevel.
+const AdminSuffix = "/admin/loglevel"
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the body of a
+// level change, keyed with LOG_ADMIN_KEY.
+const SignatureHeader = "X-Log-Signature"
+
+// maxValidity bounds how far in the future a level change may expire, which
+// bounds how long a captured request can be replayed.
+const maxValidity = 10 * time.Minute
+
+// LevelChange is the body of a level change request.
+type LevelChange struct {
+	Level string `json:"level"`
+	// Expires is the Unix time after which the request is rejected.
+	Expires int64 `json:"expires"`
+}
+
+// Sign returns the body of change and its signature with key.
+func Sign(key []byte, change LevelChange) (body []byte, signature string) {
+	body, _ = json.Marshal(change)
+	return body, sign(key, body)
+}
+
+func sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AdminHandler changes the level of l to the one of a signed LevelChange,
+// and answers with the previous and new levels. Without an admin key it
+// answers 404.
+func (l *Logger) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(l.adminKey) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPut {
+			w.Header().Set("Allow", http.MethodPut)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+		want, _ := hex.DecodeString(sign(l.adminKey, body))
+		if err != nil || !hmac.Equal(got, want) {
+			l.WithField("remote_addr", r.RemoteAddr).Warn("rejected a log level change with a bad signature")
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		var change LevelChange
+		if err := json.Unmarshal(body, &change); err != nil {
+			http.Error(w, "malformed level change: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if expires := time.Unix(change.Expires, 0); time.Now().After(expires) || time.Until(expires) > maxValidity {
+			http.Error(w, fmt.Sprintf("the level change must expire within %v", maxValidity), http.StatusForbidden)
+			return
+		}
+		level, err := logrus.ParseLevel(change.Level)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prev := l.GetLevel()
+		l.SetLevel(level)
+		l.WithFields(logrus.Fields{"previous": prev.String(), "level": level.String()}).Warn("log level changed")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"previous": prev.String(), "level": level.String()})
+	})
+}
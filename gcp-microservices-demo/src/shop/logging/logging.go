@@ -0,0 +1,203 @@
+// Package logging sets up the logger of the frontend and the Go functions
+// from the environment: its level, format and field names, the sampling of
+// the per-request access logs, and the admin route changing the level at
+// runtime.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formats accepted by Config.
+const (
+	JSON   = "json"
+	Text   = "text"
+	Logfmt = "logfmt"
+)
+
+// DefaultRoute is the Sampling entry of the routes not listed.
+const DefaultRoute = "default"
+
+// DefaultFieldMap renames the standard fields the way the shop services
+// always logged them.
+var DefaultFieldMap = logrus.FieldMap{
+	logrus.FieldKeyTime:  "timestamp",
+	logrus.FieldKeyLevel: "severity",
+	logrus.FieldKeyMsg:   "message",
+}
+
+// Config configures a Logger.
+type Config struct {
+	Level logrus.Level
+	// Format is JSON, Text or Logfmt. Text is meant for terminals; Logfmt
+	// always writes key=value pairs.
+	Format string
+	// FieldMap renames the time, level and msg fields.
+	FieldMap logrus.FieldMap
+	// Sampling maps route names to the share of their access logs that is
+	// written, between 0 and 1. Routes not listed use the rate of the
+	// DefaultRoute entry, or 1.
+	Sampling map[string]float64
+	// AdminKey signs the requests changing the level at runtime. The admin
+	// route is disabled without it.
+	AdminKey []byte
+	// Out receives the logs. It defaults to os.Stdout.
+	Out io.Writer
+	// KeepPII disables the redaction of card numbers, CVVs, emails and
+	// street addresses from the logs.
+	KeepPII bool
+}
+
+// FromEnv returns the Config read from LOG_LEVEL (default info), LOG_FORMAT
+// (default json), LOG_FIELD_MAP (as in "msg=message,level=severity"),
+// LOG_SAMPLING (as in "default=0.1,GetProduct=0.5,/product/{id}=1"),
+// LOG_ADMIN_KEY and LOG_REDACT (default true). Invalid values are reported,
+// and replaced by their default.
+func FromEnv() (Config, error) {
+	cfg := Config{Level: logrus.InfoLevel, Format: JSON, FieldMap: DefaultFieldMap, AdminKey: []byte(os.Getenv("LOG_ADMIN_KEY"))}
+	var errs []string
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		level, err := logrus.ParseLevel(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			cfg.Level = level
+		}
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		if v != JSON && v != Text && v != Logfmt {
+			errs = append(errs, fmt.Sprintf("unknown log format %q", v))
+		} else {
+			cfg.Format = v
+		}
+	}
+	if v := os.Getenv("LOG_FIELD_MAP"); v != "" {
+		fields, err := parsePairs(v)
+		if err != nil {
+			errs = append(errs, "LOG_FIELD_MAP: "+err.Error())
+		} else {
+			cfg.FieldMap = make(logrus.FieldMap)
+			for k, name := range fields {
+				// FieldMap keys have an unexported type: only the
+				// constants can be used.
+				switch k {
+				case logrus.FieldKeyTime:
+					cfg.FieldMap[logrus.FieldKeyTime] = name
+				case logrus.FieldKeyLevel:
+					cfg.FieldMap[logrus.FieldKeyLevel] = name
+				case logrus.FieldKeyMsg:
+					cfg.FieldMap[logrus.FieldKeyMsg] = name
+				case logrus.FieldKeyLogrusError:
+					cfg.FieldMap[logrus.FieldKeyLogrusError] = name
+				default:
+					errs = append(errs, fmt.Sprintf("LOG_FIELD_MAP: cannot rename field %q", k))
+				}
+			}
+		}
+	}
+	if v := os.Getenv("LOG_SAMPLING"); v != "" {
+		sampling, err := ParseSampling(v)
+		if err != nil {
+			errs = append(errs, "LOG_SAMPLING: "+err.Error())
+		} else {
+			cfg.Sampling = sampling
+		}
+	}
+	if v := os.Getenv("LOG_REDACT"); v != "" {
+		redact, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("LOG_REDACT: %q is not a boolean", v))
+		} else {
+			cfg.KeepPII = !redact
+		}
+	}
+	if len(errs) > 0 {
+		return cfg, fmt.Errorf("logging: %s", strings.Join(errs, "; "))
+	}
+	return cfg, nil
+}
+
+// ParseSampling parses a comma-separated list of route=rate pairs.
+func ParseSampling(s string) (map[string]float64, error) {
+	pairs, err := parsePairs(s)
+	if err != nil {
+		return nil, err
+	}
+	sampling := make(map[string]float64)
+	for route, v := range pairs {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("rate of %s must be between 0 and 1, not %q", route, v)
+		}
+		sampling[route] = rate
+	}
+	return sampling, nil
+}
+
+func parsePairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := cut(strings.TrimSpace(pair), "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		pairs[k] = v
+	}
+	return pairs, nil
+}
+
+// cut is strings.Cut, which go 1.17 lacks.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// Logger is a logrus.Logger that samples access logs and serves the admin
+// route of its level.
+type Logger struct {
+	*logrus.Logger
+	sampling map[string]float64
+	adminKey []byte
+}
+
+// New returns the Logger configured by cfg.
+func New(cfg Config) *Logger {
+	log := logrus.New()
+	log.Level = cfg.Level
+	log.Out = cfg.Out
+	if log.Out == nil {
+		log.Out = os.Stdout
+	}
+	switch cfg.Format {
+	case Text:
+		log.Formatter = &logrus.TextFormatter{FullTimestamp: true, PadLevelText: true, FieldMap: cfg.FieldMap}
+	case Logfmt:
+		log.Formatter = &logrus.TextFormatter{DisableColors: true, FullTimestamp: true, TimestampFormat: time.RFC3339Nano, QuoteEmptyFields: true, FieldMap: cfg.FieldMap}
+	default:
+		log.Formatter = &logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano, FieldMap: cfg.FieldMap}
+	}
+	if !cfg.KeepPII {
+		log.AddHook(redactHook{})
+	}
+	return &Logger{Logger: log, sampling: cfg.Sampling, adminKey: cfg.AdminKey}
+}
+
+// NewFromEnv returns the Logger configured by the environment. A bad
+// setting is logged, and replaced by its default.
+func NewFromEnv() *Logger {
+	cfg, err := FromEnv()
+	log := New(cfg)
+	if err != nil {
+		log.Warn(err)
+	}
+	return log
+}
@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSamplingRatio(t *testing.T) {
+	log := New(Config{Sampling: map[string]float64{"GetProduct": 0.1, "/product/{id}": 0.5, "ListProducts": 0, DefaultRoute: 0.25}})
+	const n = 20000
+	rnd := rand.New(rand.NewSource(1))
+	for route, want := range map[string]float64{"GetProduct": 0.1, "/product/{id}": 0.5, "ListProducts": 0, "SearchProducts": 0.25} {
+		sampled := 0
+		for i := 0; i < n; i++ {
+			if log.Sampled(route, fmt.Sprintf("%016x%016x", rnd.Uint64(), rnd.Uint64())) {
+				sampled++
+			}
+		}
+		if got := float64(sampled) / n; got < want-0.02 || got > want+0.02 {
+			t.Errorf("%s: sampled %.3f of the requests, want %.2f", route, got, want)
+		}
+	}
+
+	if !New(Config{}).Sampled("GetProduct", "req-1") {
+		t.Error("a logger without sampling dropped an access log")
+	}
+	for i := 0; i < 10; i++ {
+		if log.Sampled("GetProduct", "req-1") != log.Sampled("GetProduct", "req-1") {
+			t.Fatal("the sampling of a request changed")
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "warning")
+	t.Setenv("LOG_FORMAT", "logfmt")
+	t.Setenv("LOG_FIELD_MAP", "msg=message,level=lvl")
+	t.Setenv("LOG_SAMPLING", "default=0.1,GetProduct=1")
+	cfg, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cfg.Out = &buf
+	log := New(cfg)
+	log.Info("dropped")
+	log.WithField("route", "GetProduct").Warn("kept")
+	if got := buf.String(); strings.Contains(got, "dropped") || !strings.Contains(got, `lvl=warning message=kept route=GetProduct`) {
+		t.Errorf("logged %q", got)
+	}
+	if cfg.Sampling[DefaultRoute] != 0.1 || cfg.Sampling["GetProduct"] != 1 {
+		t.Errorf("sampling = %v", cfg.Sampling)
+	}
+
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("LOG_SAMPLING", "GetProduct=2")
+	cfg, err = FromEnv()
+	if err == nil || cfg.Level != logrus.InfoLevel || cfg.Sampling != nil {
+		t.Errorf("bad settings gave %+v, %v", cfg, err)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	key := []byte("secret")
+	log := New(Config{Level: logrus.InfoLevel, AdminKey: key, Out: &bytes.Buffer{}})
+	put := func(body []byte, signature string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/product"+AdminSuffix, bytes.NewReader(body))
+		req.Header.Set(SignatureHeader, signature)
+		log.AdminHandler().ServeHTTP(rec, req)
+		return rec
+	}
+	expires := time.Now().Add(time.Minute).Unix()
+
+	body, _ := Sign([]byte("guess"), LevelChange{Level: "debug", Expires: expires})
+	_, sig := Sign(key, LevelChange{Level: "debug", Expires: expires})
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"unsigned":     put(body, ""),
+		"other key":    put(Sign([]byte("guess"), LevelChange{Level: "debug", Expires: expires})),
+		"altered body": put([]byte(strings.Replace(string(body), "debug", "trace", 1)), sig),
+		"expired":      put(Sign(key, LevelChange{Level: "debug", Expires: time.Now().Add(-time.Second).Unix()})),
+		"too long":     put(Sign(key, LevelChange{Level: "debug", Expires: time.Now().Add(time.Hour).Unix()})),
+	} {
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: %d %s", name, rec.Code, rec.Body)
+		}
+	}
+	if rec := put(Sign(key, LevelChange{Level: "loud", Expires: expires})); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown level: %d %s", rec.Code, rec.Body)
+	}
+	if log.GetLevel() != logrus.InfoLevel {
+		t.Fatalf("level changed to %s by a rejected request", log.GetLevel())
+	}
+
+	if rec := put(Sign(key, LevelChange{Level: "debug", Expires: expires})); rec.Code != http.StatusOK {
+		t.Fatalf("signed change: %d %s", rec.Code, rec.Body)
+	}
+	if log.GetLevel() != logrus.DebugLevel {
+		t.Errorf("level = %s, want debug", log.GetLevel())
+	}
+
+	rec := httptest.NewRecorder()
+	New(Config{}).AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, AdminSuffix, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("without a key: %d", rec.Code)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	for in, want := range map[string]string{
+		"charging 4432-8015-6152-0454 for $12":                     "charging ****0454 for $12",
+		"card 4432801561520454 expired":                            "card ****0454 expired",
+		`{"credit_card_number":"4432 8015","credit_card_cvv":672}`: `{"credit_card_number":"[REDACTED]","credit_card_cvv":[REDACTED]}`,
+		"cvv=672 rejected":                                         "cvv=[REDACTED] rejected",
+		`confirmation sent to "someone@example.com"`:               `confirmation sent to "***@example.com"`,
+		"shipping to 1600 Amphitheatre Parkway, Mountain View":     "shipping to [ADDRESS], Mountain View",
+		"took 1666170000000000000 ns":                              "took 1666170000000000000 ns",
+		"order 3f6c1b2a-0d4e-4a4b-9c7d-2f1e0a9b8c7d placed":        "order 3f6c1b2a-0d4e-4a4b-9c7d-2f1e0a9b8c7d placed",
+	} {
+		if got := Redact(in); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactHook(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(Config{Level: logrus.InfoLevel, Out: &buf})
+	entry := log.WithFields(logrus.Fields{
+		"email": "someone@example.com",
+		"error": fmt.Errorf("payment of 4432-8015-6152-0454 declined"),
+		"cvv":   672,
+		"items": 3,
+	})
+	entry.Infof("order confirmation sent to %s", "someone@example.com")
+	got := buf.String()
+	// The CVV is looked for with its key: its digits may turn up in the
+	// time of the entry.
+	for _, leak := range []string{"someone", "4432-8015", `"cvv":672`} {
+		if strings.Contains(got, leak) {
+			t.Errorf("%q leaked into %s", leak, got)
+		}
+	}
+	for _, kept := range []string{`"items":3`, "****0454", "***@example.com"} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("%q missing from %s", kept, got)
+		}
+	}
+	if entry.Data["email"] != "someone@example.com" {
+		t.Error("the hook changed the fields of the entry")
+	}
+
+	buf.Reset()
+	New(Config{Level: logrus.InfoLevel, Out: &buf, KeepPII: true}).Info("sent to someone@example.com")
+	if !strings.Contains(buf.String(), "someone@example.com") {
+		t.Errorf("KeepPII redacted %s", buf.String())
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	err := RedactError(fmt.Errorf("charge failed: %w", errors.New("card 4432-8015-6152-0454 declined")))
+	for _, got := range []string{err.Error(), fmt.Sprintf("%v", err), fmt.Sprintf("%+v", err)} {
+		if strings.Contains(got, "4432") || !strings.Contains(got, "****0454") {
+			t.Errorf("formatted as %q", got)
+		}
+	}
+	if errors.Unwrap(err) == nil || RedactError(nil) != nil {
+		t.Error("RedactError does not wrap its error")
+	}
+}
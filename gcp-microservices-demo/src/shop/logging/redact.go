@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sensitiveFields are the log fields, and the keys of JSON objects or
+// key=value pairs within messages, whose value is masked whatever it is.
+var sensitiveFields = map[string]bool{
+	"email":              true,
+	"credit_card_number": true,
+	"credit_card_cvv":    true,
+	"cvv":                true,
+	"cvc":                true,
+	"street_address":     true,
+}
+
+const sensitiveKeys = `email|credit_card_number|credit_card_cvv|cvv|cvc|street_address`
+
+var (
+	// quotedPair matches "key":"value" and key="value", unquotedPair
+	// "key":value and key=value.
+	quotedPair   = regexp.MustCompile(`(?i)("?\b(?:` + sensitiveKeys + `)"?\s*[:=]\s*)"(?:[^"\\]|\\.)*"`)
+	unquotedPair = regexp.MustCompile(`(?i)("?\b(?:` + sensitiveKeys + `)"?\s*[:=]\s*)([^\s",}&]+)`)
+	cardNumber   = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	email        = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// streetAddress matches a house number followed by up to four words
+	// and a street suffix, as in "1600 Amphitheatre Parkway".
+	streetAddress = regexp.MustCompile(`(?i)\b\d{1,6}\s+(?:[A-Za-z0-9.'-]+\s+){0,4}(?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|parkway|pkwy|place|pl|square|sq)\b\.?`)
+)
+
+// Redact masks the credit card numbers, CVVs, emails and street addresses
+// in s. Card numbers keep their last four digits, emails their domain.
+func Redact(s string) string {
+	s = quotedPair.ReplaceAllString(s, `$1"[REDACTED]"`)
+	s = unquotedPair.ReplaceAllString(s, `$1[REDACTED]`)
+	s = cardNumber.ReplaceAllStringFunc(s, func(m string) string {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(m)
+		if !luhn(digits) {
+			return m
+		}
+		return "****" + digits[len(digits)-4:]
+	})
+	s = email.ReplaceAllStringFunc(s, func(m string) string {
+		return "***" + m[strings.LastIndex(m, "@"):]
+	})
+	return streetAddress.ReplaceAllString(s, "[ADDRESS]")
+}
+
+// luhn reports whether digits pass the Luhn check of card numbers, which
+// spares most other long numbers, such as timestamps.
+func luhn(digits string) bool {
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// RedactError returns err formatted with Redact. Its %+v also redacts the
+// detailed chain, such as the stack trace of a github.com/pkg/errors error.
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return redactedError{err}
+}
+
+type redactedError struct{ err error }
+
+func (e redactedError) Error() string { return Redact(e.err.Error()) }
+
+func (e redactedError) Unwrap() error { return e.err }
+
+func (e redactedError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		fmt.Fprint(s, Redact(fmt.Sprintf("%+v", e.err)))
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprint(s, e.Error())
+	}
+}
+
+// redactHook redacts the message and the fields of every entry.
+type redactHook struct{}
+
+func (redactHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (redactHook) Fire(entry *logrus.Entry) error {
+	entry.Message = Redact(entry.Message)
+	// entry is a copy made for this entry: its Data is not shared.
+	for k, v := range entry.Data {
+		if sensitiveFields[strings.ToLower(k)] {
+			entry.Data[k] = "[REDACTED]"
+			continue
+		}
+		switch v := v.(type) {
+		case string:
+			entry.Data[k] = Redact(v)
+		case error:
+			entry.Data[k] = Redact(v.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// Sampled reports whether the access log of the request requestID to route
+// is written. The decision is a hash of the request ID, so that a request
+// is logged by every service it reaches, or by none. Logs of failed
+// requests should be written regardless.
+func (l *Logger) Sampled(route, requestID string) bool {
+	rate, ok := l.sampling[route]
+	if !ok {
+		rate, ok = l.sampling[DefaultRoute]
+	}
+	if !ok {
+		return true
+	}
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(requestID))
+	return float64(h.Sum64())/math.MaxUint64 < rate
+}
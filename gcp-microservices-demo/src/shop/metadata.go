@@ -0,0 +1,42 @@
+package shop
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestIDHeader carries the ID under which every function a request passes
+// through logs it.
+const RequestIDHeader = "X-Request-ID"
+
+// Metadata is the request-scoped context sent along with every call a Client
+// makes: in HTTP headers over HTTP/JSON and Connect, and in gRPC metadata
+// over gRPC. The trace context travels with it, see package telemetry.
+type Metadata struct {
+	RequestID string
+}
+
+type metadataKey struct{}
+
+// NewContext returns a copy of ctx carrying md.
+func NewContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// FromContext returns the Metadata carried by ctx.
+func FromContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(metadataKey{}).(Metadata)
+	return md, ok
+}
+
+// MetadataFromHeader returns the Metadata sent in h.
+func MetadataFromHeader(h http.Header) Metadata {
+	return Metadata{RequestID: h.Get(RequestIDHeader)}
+}
+
+// Inject sets the headers of md in h.
+func (md Metadata) Inject(h http.Header) {
+	if md.RequestID != "" {
+		h.Set(RequestIDHeader, md.RequestID)
+	}
+}
@@ -0,0 +1,256 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// checkedMethods are the methods CheckMethods expects a function to reject
+// unless the spec declares them.
+var checkedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch}
+
+// CheckType reports how the JSON fields of the struct v differ from the
+// properties of the named component schema.
+func (s *Spec) CheckType(name string, v interface{}) []error {
+	sc := s.Schema(name)
+	if sc == nil {
+		return []error{fmt.Errorf("%s: no such schema", name)}
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return []error{fmt.Errorf("%s: %s is not a struct", name, t)}
+	}
+
+	fields := make(map[string]bool)
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag, ok := f.Tag.Lookup("json")
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: field %s has no json tag", name, f.Name))
+			continue
+		}
+		jsonName := strings.Split(tag, ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		fields[jsonName] = true
+	}
+	props := make(map[string]bool)
+	for _, p := range sc.Properties {
+		props[p.Name] = true
+		if !fields[p.Name] {
+			errs = append(errs, fmt.Errorf("%s: %s has no field for property %q", name, t, p.Name))
+		}
+	}
+	for _, f := range sortedKeys(fields) {
+		if !props[f] {
+			errs = append(errs, fmt.Errorf("%s: %s has field %q which the spec does not declare", name, t, f))
+		}
+	}
+	return errs
+}
+
+// CheckMethods reports the methods the handler of service accepts although
+// the spec declares no operation for them.
+func (s *Spec) CheckMethods(service string, h http.Handler) []error {
+	declared := make(map[string]bool)
+	for _, op := range s.ServiceOperations(service) {
+		declared[op.Method] = true
+	}
+	var errs []error
+	for _, m := range checkedMethods {
+		if declared[m] {
+			continue
+		}
+		req := httptest.NewRequest(m, "/", strings.NewReader("{}"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code >= 200 && rec.Code < 300 {
+			errs = append(errs, fmt.Errorf("%s: %s is not in the spec but answered %d", service, m, rec.Code))
+		}
+	}
+	return errs
+}
+
+// CheckHandler replays the example request of every operation the spec
+// declares for service against h, and checks that each answers 200 with a
+// body matching the response schema. It also runs CheckMethods.
+func (s *Spec) CheckHandler(service string, h http.Handler) []error {
+	errs := s.CheckMethods(service, h)
+	ops := s.ServiceOperations(service)
+	if len(ops) == 0 {
+		return append(errs, fmt.Errorf("%s: the spec declares no operations", service))
+	}
+	for _, op := range ops {
+		req, err := s.ExampleRequest(op)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		errs = append(errs, s.CheckResponse(op, rec.Result())...)
+	}
+	return errs
+}
+
+// ExampleRequest builds a request for op from the examples in the spec.
+func (s *Spec) ExampleRequest(op *Operation) (*http.Request, error) {
+	v := url.Values{}
+	for _, p := range op.Params {
+		if p.Example == nil {
+			if p.Required {
+				return nil, fmt.Errorf("%s: parameter %s has no example", op.ID, p.Name)
+			}
+			continue
+		}
+		if items, ok := p.Example.([]interface{}); ok {
+			parts := make([]string, len(items))
+			for i, it := range items {
+				parts[i] = fmt.Sprint(it)
+			}
+			if p.Explode {
+				for _, part := range parts {
+					v.Add(p.Name, part)
+				}
+			} else {
+				v.Add(p.Name, strings.Join(parts, ","))
+			}
+			continue
+		}
+		v.Add(p.Name, fmt.Sprint(p.Example))
+	}
+	target := op.Path
+	if len(v) > 0 {
+		target += "?" + v.Encode()
+	}
+	var body io.Reader
+	if op.Body {
+		if op.BodyExample == nil {
+			return nil, fmt.Errorf("%s: request body has no example", op.ID)
+		}
+		b, err := json.Marshal(op.BodyExample)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op.ID, err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(op.Method, target, body)
+	if op.Body {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// CheckResponse reports how res diverges from the 200 response of op.
+func (s *Spec) CheckResponse(op *Operation, res *http.Response) []error {
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return []error{fmt.Errorf("%s: %v", op.ID, err)}
+	}
+	if res.StatusCode != http.StatusOK {
+		return []error{fmt.Errorf("%s: example request answered %d: %s", op.ID, res.StatusCode, body)}
+	}
+	if op.Response == "" {
+		return nil
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.Contains(ct, "application/json") {
+		return []error{fmt.Errorf("%s: content type %q is not application/json", op.ID, ct)}
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []error{fmt.Errorf("%s: %v", op.ID, err)}
+	}
+	return s.Validate(op.ID, &Schema{Ref: op.Response}, v)
+}
+
+// Validate reports how the decoded JSON value v diverges from sc. Numbers
+// must have been decoded as json.Number.
+func (s *Spec) Validate(path string, sc *Schema, v interface{}) []error {
+	sc = s.Resolve(sc)
+	if sc == nil {
+		return []error{fmt.Errorf("%s: unresolved schema", path)}
+	}
+	if v == nil {
+		return nil
+	}
+	var errs []error
+	mismatch := func() []error {
+		return []error{fmt.Errorf("%s: got %T, want %s", path, v, sc.Type)}
+	}
+	switch sc.Type {
+	case "object", "":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		props := make(map[string]*Schema)
+		for _, p := range sc.Properties {
+			props[p.Name] = p.Schema
+		}
+		for _, k := range sortedKeys(obj) {
+			psc, ok := props[k]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unexpected field %q", path, k))
+				continue
+			}
+			errs = append(errs, s.Validate(path+"."+k, psc, obj[k])...)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for i, it := range arr {
+			errs = append(errs, s.Validate(fmt.Sprintf("%s[%d]", path, i), sc.Items, it)...)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return mismatch()
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+		if _, err := n.Int64(); err != nil {
+			return mismatch()
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return mismatch()
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return mismatch()
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
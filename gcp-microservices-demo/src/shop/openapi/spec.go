@@ -0,0 +1,405 @@
+// Package openapi loads the OpenAPI 3 document describing the shop API
+// (docs/openapi.yaml) into the small model used by the shop code generator
+// and by the conformance checks of the Go functions.
+//
+// Only the subset of OpenAPI used by that document is understood: object,
+// array and scalar schemas, $ref to components/schemas, query parameters,
+// JSON request bodies and the x-go-* extensions described there.
+package openapi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a parsed API document. Schemas and operations keep the order in
+// which they appear in the document.
+type Spec struct {
+	Tags       []*Tag
+	Schemas    []*Schema
+	Operations []*Operation
+
+	schemas map[string]*Schema
+	tags    map[string]*Tag
+}
+
+// Tag groups the operations of one function.
+type Tag struct {
+	Name string
+	// Service is the function name, e.g. "productcatalogservice".
+	Service string
+	// GoService is the shop.Service constant for the function.
+	GoService string
+	// Route is the router path of the function, e.g. "/product".
+	Route string
+}
+
+// Operation is one callable API operation. Variants listed under
+// x-go-variants are expanded into operations of their own.
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Tag         *Tag
+	Description string
+	Params      []*Param
+	// Request names the schema the query parameters are read from
+	// (x-go-request), or the request body schema when Body is set.
+	Request string
+	Body    bool
+	// BodyExample is the example request body, if any.
+	BodyExample interface{}
+	// Response names the schema of the 200 response, empty when the
+	// response has no body.
+	Response string
+	// Unexported asks the generator for an unexported client method, to be
+	// wrapped by hand-written code.
+	Unexported bool
+	// Idempotent marks an operation the client may send again: every GET,
+	// and the others set x-go-idempotent.
+	Idempotent bool
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name     string
+	Required bool
+	Schema   *Schema
+	// Explode is false for arrays sent as a single comma-separated value.
+	Explode bool
+	Example interface{}
+}
+
+// Schema is an object, array or scalar schema. Named schemas come from
+// components/schemas; Ref is set on references to them.
+type Schema struct {
+	Name        string
+	Ref         string
+	Type        string
+	Format      string
+	Description string
+	Items       *Schema
+	Properties  []*Property
+}
+
+// Property is a named field of an object schema.
+type Property struct {
+	Name string
+	*Schema
+}
+
+// Schema returns the component schema called name, or nil.
+func (s *Spec) Schema(name string) *Schema {
+	return s.schemas[name]
+}
+
+// Resolve follows sc's reference, if any.
+func (s *Spec) Resolve(sc *Schema) *Schema {
+	if sc != nil && sc.Ref != "" {
+		return s.schemas[sc.Ref]
+	}
+	return sc
+}
+
+// ServiceOperations returns the operations served by the named function.
+func (s *Spec) ServiceOperations(service string) []*Operation {
+	var out []*Operation
+	for _, op := range s.Operations {
+		if op.Tag.Service == service {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// Load reads and parses the document at path.
+func Load(path string) (*Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse parses a YAML or JSON document.
+func Parse(b []byte) (*Spec, error) {
+	var doc rawDoc
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	s := &Spec{schemas: make(map[string]*Schema), tags: make(map[string]*Tag)}
+	for _, t := range doc.Tags {
+		tag := &Tag{Name: t.Name, Service: t.Service, GoService: t.GoService, Route: t.Route}
+		s.Tags = append(s.Tags, tag)
+		s.tags[tag.Name] = tag
+	}
+
+	err := eachEntry(&doc.Components.Schemas, func(name string, n *yaml.Node) error {
+		sc, err := parseSchema(n)
+		if err != nil {
+			return fmt.Errorf("schema %s: %v", name, err)
+		}
+		sc.Name = name
+		s.Schemas = append(s.Schemas, sc)
+		s.schemas[name] = sc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = eachEntry(&doc.Paths, func(path string, n *yaml.Node) error {
+		return eachEntry(n, func(method string, n *yaml.Node) error {
+			var raw rawOperation
+			if err := n.Decode(&raw); err != nil {
+				return err
+			}
+			ops, err := s.operations(path, strings.ToUpper(method), &raw)
+			if err != nil {
+				return fmt.Errorf("%s %s: %v", strings.ToUpper(method), path, err)
+			}
+			s.Operations = append(s.Operations, ops...)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, s.validate()
+}
+
+func (s *Spec) operations(path, method string, raw *rawOperation) ([]*Operation, error) {
+	if len(raw.Tags) != 1 || s.tags[raw.Tags[0]] == nil {
+		return nil, fmt.Errorf("operation %s needs exactly one declared tag", raw.OperationID)
+	}
+	params := make(map[string]*Param)
+	var order []*Param
+	for _, rp := range raw.Parameters {
+		if rp.In != "query" {
+			return nil, fmt.Errorf("parameter %s: only query parameters are supported", rp.Name)
+		}
+		p := &Param{Name: rp.Name, Required: rp.Required, Explode: true, Example: rp.Example}
+		if rp.Explode != nil {
+			p.Explode = *rp.Explode
+		}
+		sc, err := parseSchema(&rp.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %v", rp.Name, err)
+		}
+		p.Schema = sc
+		params[p.Name] = p
+		order = append(order, p)
+	}
+
+	op := &Operation{
+		ID:          raw.OperationID,
+		Method:      method,
+		Path:        path,
+		Tag:         s.tags[raw.Tags[0]],
+		Description: strings.TrimSpace(raw.Description),
+		Params:      order,
+		Request:     raw.GoRequest,
+		Unexported:  raw.GoUnexported,
+		Idempotent:  method == "GET" || raw.GoIdempotent,
+	}
+	if raw.RequestBody != nil {
+		media, ok := raw.RequestBody.Content["application/json"]
+		if !ok {
+			return nil, fmt.Errorf("request body must be application/json")
+		}
+		op.Body = true
+		op.Request = refName(media.Schema.Ref)
+		op.BodyExample = media.Example
+	}
+	if ok, found := raw.Responses["200"]; found {
+		if media, found := ok.Content["application/json"]; found {
+			op.Response = refName(media.Schema.Ref)
+		}
+	}
+
+	if len(raw.GoVariants) == 0 {
+		return []*Operation{op}, nil
+	}
+	var out []*Operation
+	for _, v := range raw.GoVariants {
+		vop := *op
+		vop.ID = v.OperationID
+		vop.Request = v.GoRequest
+		vop.Response = v.Response
+		vop.Params = nil
+		for _, vp := range v.Parameters {
+			p, ok := params[vp.Name]
+			if !ok {
+				return nil, fmt.Errorf("variant %s uses undeclared parameter %s", v.OperationID, vp.Name)
+			}
+			cp := *p
+			cp.Required = vp.Required == nil || *vp.Required
+			if vp.Example != nil {
+				cp.Example = vp.Example
+			}
+			vop.Params = append(vop.Params, &cp)
+		}
+		out = append(out, &vop)
+	}
+	return out, nil
+}
+
+// validate checks that every reference points at a declared schema.
+func (s *Spec) validate() error {
+	check := func(where, name string) error {
+		if name != "" && s.schemas[name] == nil {
+			return fmt.Errorf("%s: unknown schema %q", where, name)
+		}
+		return nil
+	}
+	var walk func(where string, sc *Schema) error
+	walk = func(where string, sc *Schema) error {
+		if sc == nil {
+			return nil
+		}
+		if err := check(where, sc.Ref); err != nil {
+			return err
+		}
+		if err := walk(where, sc.Items); err != nil {
+			return err
+		}
+		for _, p := range sc.Properties {
+			if err := walk(where+"."+p.Name, p.Schema); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, sc := range s.Schemas {
+		if err := walk(sc.Name, sc); err != nil {
+			return err
+		}
+	}
+	for _, op := range s.Operations {
+		if err := check(op.ID, op.Request); err != nil {
+			return err
+		}
+		if err := check(op.ID, op.Response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseSchema(n *yaml.Node) (*Schema, error) {
+	var raw rawSchema
+	if err := n.Decode(&raw); err != nil {
+		return nil, err
+	}
+	sc := &Schema{
+		Ref:         refName(raw.Ref),
+		Type:        raw.Type,
+		Format:      raw.Format,
+		Description: strings.TrimSpace(raw.Description),
+	}
+	if raw.Items.Kind != 0 {
+		items, err := parseSchema(&raw.Items)
+		if err != nil {
+			return nil, err
+		}
+		sc.Items = items
+	}
+	err := eachEntry(&raw.Properties, func(name string, n *yaml.Node) error {
+		p, err := parseSchema(n)
+		if err != nil {
+			return fmt.Errorf("property %s: %v", name, err)
+		}
+		sc.Properties = append(sc.Properties, &Property{Name: name, Schema: p})
+		return nil
+	})
+	return sc, err
+}
+
+// eachEntry calls fn for every key of the mapping node n, in document order.
+func eachEntry(n *yaml.Node, fn func(key string, value *yaml.Node) error) error {
+	if n.Kind == 0 {
+		return nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if err := fn(n.Content[i].Value, n.Content[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+type rawDoc struct {
+	Tags []struct {
+		Name      string `yaml:"name"`
+		Service   string `yaml:"x-service"`
+		GoService string `yaml:"x-go-service"`
+		Route     string `yaml:"x-route"`
+	} `yaml:"tags"`
+	Paths      yaml.Node `yaml:"paths"`
+	Components struct {
+		Schemas yaml.Node `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type rawOperation struct {
+	Tags         []string           `yaml:"tags"`
+	OperationID  string             `yaml:"operationId"`
+	Description  string             `yaml:"description"`
+	Parameters   []rawParam         `yaml:"parameters"`
+	RequestBody  *rawBody           `yaml:"requestBody"`
+	Responses    map[string]rawBody `yaml:"responses"`
+	GoRequest    string             `yaml:"x-go-request"`
+	GoUnexported bool               `yaml:"x-go-unexported"`
+	GoIdempotent bool               `yaml:"x-go-idempotent"`
+	GoVariants   []rawVariant       `yaml:"x-go-variants"`
+}
+
+type rawParam struct {
+	Name     string      `yaml:"name"`
+	In       string      `yaml:"in"`
+	Required bool        `yaml:"required"`
+	Explode  *bool       `yaml:"explode"`
+	Schema   yaml.Node   `yaml:"schema"`
+	Example  interface{} `yaml:"example"`
+}
+
+type rawBody struct {
+	Content map[string]struct {
+		Schema struct {
+			Ref string `yaml:"$ref"`
+		} `yaml:"schema"`
+		Example interface{} `yaml:"example"`
+	} `yaml:"content"`
+}
+
+type rawVariant struct {
+	OperationID string `yaml:"operationId"`
+	GoRequest   string `yaml:"x-go-request"`
+	Parameters  []struct {
+		Name string `yaml:"name"`
+		// Required defaults to true: a variant is told from the others by
+		// its parameters.
+		Required *bool       `yaml:"required"`
+		Example  interface{} `yaml:"example"`
+	} `yaml:"parameters"`
+	Response string `yaml:"response"`
+}
+
+type rawSchema struct {
+	Ref         string    `yaml:"$ref"`
+	Type        string    `yaml:"type"`
+	Format      string    `yaml:"format"`
+	Description string    `yaml:"description"`
+	Items       yaml.Node `yaml:"items"`
+	Properties  yaml.Node `yaml:"properties"`
+}
@@ -0,0 +1,96 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	s, err := Load("../../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, op := range s.ServiceOperations("productcatalogservice") {
+		ids = append(ids, op.ID)
+	}
+	if got := strings.Join(ids, ","); got != "ListProducts,GetProduct,SearchProducts,BrowseProducts" {
+		t.Errorf("catalog operations = %s", got)
+	}
+	for _, op := range s.ServiceOperations("adservice") {
+		req, err := s.ExampleRequest(op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := req.URL.Query().Get("context_keys"); got != "clothing,kitchen" {
+			t.Errorf("context_keys = %q, want a comma-separated list", got)
+		}
+	}
+	var idempotent []string
+	for _, op := range s.ServiceOperations("shippingservice") {
+		if op.Idempotent {
+			idempotent = append(idempotent, op.ID)
+		}
+	}
+	if got := strings.Join(idempotent, ","); got != "TrackShipment" {
+		t.Errorf("idempotent shipping operations = %s, want only the GET", got)
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	s, err := Load("../../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type cartItem struct {
+		ProductId string `json:"product_id,omitempty"`
+		Quantity  int32  `json:"quantity,omitempty"`
+	}
+	if errs := s.CheckType("CartItem", cartItem{}); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	type drifted struct {
+		ProductId string `json:"productId"`
+		Quantity  int32  `json:"quantity"`
+	}
+	if errs := s.CheckType("CartItem", drifted{}); len(errs) != 2 {
+		t.Errorf("got %v, want a missing and an undeclared field", errs)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Load("../../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func(src string) interface{} {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	ok := decode(`{"cost_usd":{"currency_code":"USD","units":8,"nanos":990000000}}`)
+	if errs := s.Validate("GetQuoteResponse", &Schema{Ref: "GetQuoteResponse"}, ok); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	bad := decode(`{"cost_usd":{"currency":"USD","units":"8"}}`)
+	if errs := s.Validate("GetQuoteResponse", &Schema{Ref: "GetQuoteResponse"}, bad); len(errs) != 2 {
+		t.Errorf("got %v, want an unexpected field and a type mismatch", errs)
+	}
+}
+
+func TestCheckMethods(t *testing.T) {
+	s, err := Load("../../../docs/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptAll := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if errs := s.CheckMethods("adservice", acceptAll); len(errs) != 4 {
+		t.Errorf("got %v, want POST, PUT, DELETE and PATCH reported", errs)
+	}
+}
@@ -0,0 +1,101 @@
+package shop
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/fault"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// AuthFunc adds credentials to an outgoing request.
+type AuthFunc func(r *http.Request)
+
+// TraceFunc propagates request-scoped context, such as trace headers, from
+// ctx onto an outgoing request.
+type TraceFunc func(ctx context.Context, r *http.Request)
+
+// WithBaseURL sets the address of the Fission router. Each service is reached
+// at its route below it, e.g. <baseURL>/product for the catalog.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithServiceURL sends requests for svc to addr instead of its route below
+// the base URL.
+func WithServiceURL(svc Service, addr string) Option {
+	return func(c *Client) {
+		c.addrs[svc] = addr
+	}
+}
+
+// Invoker sends the operation op of svc over a transport other than
+// HTTP/JSON, such as gRPC. in is the request message, or nil for operations
+// without one, and the response is decoded into out. Errors should be *Error
+// values with StatusCode set to the HTTP equivalent of the failure, so that
+// IsNotFound and IsRetryable keep working.
+type Invoker interface {
+	Invoke(ctx context.Context, svc Service, op string, in, out interface{}) error
+}
+
+// WithInvoker sends every operation of svc through inv instead of HTTP/JSON.
+// Auth and trace functions are not applied to such calls.
+func WithInvoker(svc Service, inv Invoker) Option {
+	return func(c *Client) {
+		c.invokers[svc] = inv
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAuth applies auth to every outgoing request.
+func WithAuth(auth AuthFunc) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// BearerToken returns an AuthFunc sending token in the Authorization header.
+func BearerToken(token string) AuthFunc {
+	return func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithTracing adds a TraceFunc that is applied to every outgoing request.
+// It may be given more than once.
+func WithTracing(trace TraceFunc) Option {
+	return func(c *Client) {
+		c.trace = append(c.trace, trace)
+	}
+}
+
+// WithRetries retries idempotent operations, as the spec marks them, up to
+// max times when they fail with a transport error or a retryable status. The wait before the n-th retry is
+// n times backoff.
+func WithRetries(max int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max
+		c.backoff = backoff
+	}
+}
+
+// WithFaults injects the faults of the client-side rules of inj into the
+// HTTP/JSON calls: delays, error responses of the rule's status, dropped
+// connections and malformed response bodies.
+func WithFaults(inj *fault.Injector) Option {
+	return func(c *Client) {
+		c.faults = inj
+	}
+}
@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+type grpcInvoker struct {
+	conn grpc.ClientConnInterface
+}
+
+// NewGRPCInvoker returns an Invoker calling the functions over conn.
+func NewGRPCInvoker(conn grpc.ClientConnInterface) shop.Invoker {
+	return &grpcInvoker{conn: conn}
+}
+
+// DialGRPC returns an Invoker calling the functions at the gRPC target addr
+// over a plaintext connection. The connection is established lazily.
+func DialGRPC(addr string) (shop.Invoker, error) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	return NewGRPCInvoker(conn), nil
+}
+
+func (g *grpcInvoker) Invoke(ctx context.Context, svc shop.Service, op string, in, out interface{}) error {
+	if in == nil {
+		in = struct{}{}
+	}
+	if out == nil {
+		out = new(json.RawMessage)
+	}
+	ctx = outgoingMetadata(ctx)
+	err := g.conn.Invoke(ctx, "/"+ServiceName(svc)+"/"+op, in, out, grpc.CallContentSubtype(codec{}.Name()))
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return &shop.Error{Err: err}
+	}
+	return &shop.Error{StatusCode: httpStatus(st.Code()), Err: err}
+}
+
+// outgoingMetadata returns a copy of ctx sending the shop.Metadata and trace
+// context of ctx as gRPC metadata, under the lower-cased header names.
+func outgoingMetadata(ctx context.Context) context.Context {
+	h := make(http.Header)
+	if md, ok := shop.FromContext(ctx); ok {
+		md.Inject(h)
+	}
+	telemetry.Inject(ctx, h)
+	var kv []string
+	for k, vs := range h {
+		for _, v := range vs {
+			kv = append(kv, strings.ToLower(k), v)
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// incomingMetadata returns a copy of ctx carrying the shop.Metadata and
+// trace context sent in the gRPC metadata of ctx.
+func incomingMetadata(ctx context.Context) context.Context {
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	h := make(http.Header)
+	for k, vs := range in {
+		for _, v := range vs {
+			h.Add(k, v)
+		}
+	}
+	return shop.NewContext(telemetry.Extract(ctx, h), shop.MetadataFromHeader(h))
+}
+
+type connectInvoker struct {
+	addr string
+	hc   *http.Client
+}
+
+// NewConnectInvoker returns an Invoker making Connect calls to the function
+// at addr, e.g. http://router.fission.svc.cluster.local/product.
+func NewConnectInvoker(addr string, hc *http.Client) shop.Invoker {
+	return &connectInvoker{addr: strings.TrimSuffix(addr, "/"), hc: hc}
+}
+
+func (c *connectInvoker) Invoke(ctx context.Context, svc shop.Service, op string, in, out interface{}) error {
+	payload := []byte("{}")
+	if in != nil {
+		var err error
+		if payload, err = json.Marshal(in); err != nil {
+			return &shop.Error{Err: err}
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.addr+"/"+ServiceName(svc)+"/"+op, bytes.NewReader(payload))
+	if err != nil {
+		return &shop.Error{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	req.Header.Set("User-Agent", "shop-go/"+shop.Version)
+	if md, ok := shop.FromContext(ctx); ok {
+		md.Inject(req.Header)
+	}
+	telemetry.Inject(ctx, req.Header)
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Connect-Timeout-Ms", strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
+
+	res, err := c.hc.Do(req)
+	if err != nil {
+		return &shop.Error{Err: err}
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return &shop.Error{StatusCode: res.StatusCode, Err: err}
+	}
+	if res.StatusCode != http.StatusOK {
+		ce := new(connectError)
+		if json.Unmarshal(body, ce) != nil || ce.Code == "" {
+			return &shop.Error{StatusCode: res.StatusCode, Body: body}
+		}
+		return &shop.Error{StatusCode: res.StatusCode, Body: body, Err: status.Error(codeOf(ce.Code), ce.Message)}
+	}
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return &shop.Error{StatusCode: res.StatusCode, Err: fmt.Errorf("decoding response: %w", err)}
+	}
+	return nil
+}
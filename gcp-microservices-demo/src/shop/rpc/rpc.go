@@ -0,0 +1,160 @@
+// Package rpc serves and calls the Go shop functions over gRPC and over the
+// Connect protocol, as an alternative to their HTTP/JSON API.
+//
+// Both transports carry the JSON encoding of the same message structs the
+// HTTP/JSON API uses, so no protobuf code is generated: gRPC calls use the
+// "json" content subtype registered by this package, and Connect calls are
+// unary POST requests with an application/json body. Connect requests are
+// plain HTTP/1.1 and pass through the Fission router; gRPC needs a direct
+// HTTP/2 connection to a function started with GRPC_ADDR set.
+//
+// The gRPC service and method names are those of the upstream
+// microservices-demo protos, e.g. /hipstershop.ProductCatalogService/GetProduct.
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+)
+
+// Transport names accepted by Option.
+const (
+	HTTP    = "http"
+	GRPC    = "grpc"
+	Connect = "connect"
+)
+
+// Transports lists every transport, HTTP/JSON first.
+var Transports = []string{HTTP, GRPC, Connect}
+
+var serviceNames = map[shop.Service]string{
+	shop.CatalogService:  "hipstershop.ProductCatalogService",
+	shop.ShippingService: "hipstershop.ShippingService",
+	shop.AdService:       "hipstershop.AdService",
+	shop.CheckoutService: "hipstershop.CheckoutService",
+}
+
+// ServiceName returns the gRPC service name of svc, or "" if svc is not
+// served over gRPC.
+func ServiceName(svc shop.Service) string {
+	return serviceNames[svc]
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec encodes gRPC messages as JSON.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (codec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (codec) Name() string                               { return "json" }
+
+// connectCodes are the Connect names of the gRPC status codes.
+var connectCodes = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+	codes.Unauthenticated:    "unauthenticated",
+}
+
+// httpStatus maps a gRPC status code to the HTTP status Connect uses for it.
+func httpStatus(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+func codeOf(name string) codes.Code {
+	for c, n := range connectCodes {
+		if n == name {
+			return c
+		}
+	}
+	return codes.Unknown
+}
+
+// Option returns the client option that sends the operations of svc over
+// transport. addr is the gRPC target (host:port) for GRPC, and the service
+// address, e.g. http://router.fission.svc.cluster.local/product, for
+// Connect. The HTTP transport needs no option and yields nil.
+func Option(transport string, svc shop.Service, addr string) (shop.Option, error) {
+	switch transport {
+	case HTTP, "":
+		return nil, nil
+	case GRPC, Connect:
+	default:
+		return nil, fmt.Errorf("unknown transport %q", transport)
+	}
+	if ServiceName(svc) == "" {
+		return nil, fmt.Errorf("%s is not served over %s", svc, transport)
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("no %s address for %s", transport, svc)
+	}
+	if transport == Connect {
+		return shop.WithInvoker(svc, NewConnectInvoker(addr, http.DefaultClient)), nil
+	}
+	inv, err := DialGRPC(addr)
+	if err != nil {
+		return nil, err
+	}
+	return shop.WithInvoker(svc, inv), nil
+}
+
+// Options returns the client options sending the operations of every service
+// in addrs over transport, see Option.
+func Options(transport string, addrs map[shop.Service]string) ([]shop.Option, error) {
+	var opts []shop.Option
+	for svc, addr := range addrs {
+		opt, err := Option(transport, svc, addr)
+		if err != nil {
+			return nil, err
+		}
+		if opt != nil {
+			opts = append(opts, opt)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,102 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc/rpctest"
+)
+
+var catalog = rpc.NewService(shop.CatalogService,
+	rpc.Method{
+		Name: "ListProducts",
+		New:  func() interface{} { return new(struct{}) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			return &shop.ListProductsResponse{Products: []*shop.Product{{Id: "OLJCESPC7Z"}}}, nil
+		},
+	},
+	rpc.Method{
+		Name: "GetProduct",
+		New:  func() interface{} { return new(shop.GetProductRequest) },
+		Call: func(ctx context.Context, in interface{}) (interface{}, error) {
+			id := in.(*shop.GetProductRequest).Id
+			if id != "OLJCESPC7Z" {
+				return nil, status.Errorf(codes.NotFound, "no product with ID %s", id)
+			}
+			if _, ok := ctx.Deadline(); !ok {
+				return nil, status.Error(codes.InvalidArgument, "no deadline")
+			}
+			return &shop.Product{Id: id, Name: "Sunglasses"}, nil
+		},
+	},
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	if catalog.ServeConnect(w, r) {
+		return
+	}
+	switch r.URL.Query().Get("id") {
+	case "":
+		w.Write([]byte(`{"products":[{"id":"OLJCESPC7Z"}]}`))
+	case "OLJCESPC7Z":
+		w.Write([]byte(`{"id":"OLJCESPC7Z","name":"Sunglasses"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestTransports(t *testing.T) {
+	for transport, c := range rpctest.Clients(t, shop.CatalogService, http.HandlerFunc(handler), catalog) {
+		t.Run(transport, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			list, err := c.Catalog.ListProducts(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.GetProducts()) != 1 {
+				t.Errorf("ListProducts = %+v", list)
+			}
+			p, err := c.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: "OLJCESPC7Z"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.GetName() != "Sunglasses" {
+				t.Errorf("GetProduct = %+v", p)
+			}
+			_, err = c.Catalog.GetProduct(ctx, &shop.GetProductRequest{Id: "missing"})
+			if !shop.IsNotFound(err) {
+				t.Errorf("GetProduct(missing) = %v, want a not-found error", err)
+			}
+		})
+	}
+}
+
+func TestServeConnectIgnoresOtherPaths(t *testing.T) {
+	for _, path := range []string{"/product", "/product/batch"} {
+		r, _ := http.NewRequest(http.MethodPost, "http://example.com"+path, nil)
+		if catalog.ServeConnect(nil, r) {
+			t.Errorf("ServeConnect served %s", path)
+		}
+	}
+}
+
+func TestOption(t *testing.T) {
+	if _, err := rpc.Option(rpc.GRPC, shop.CartService, "localhost:1"); err == nil {
+		t.Error("cartservice is not served over gRPC")
+	}
+	if _, err := rpc.Option("smoke-signals", shop.CatalogService, ""); err == nil {
+		t.Error("unknown transports must be rejected")
+	}
+	if opt, err := rpc.Option(rpc.HTTP, shop.CatalogService, ""); opt != nil || err != nil {
+		t.Errorf("Option(http) = %v, %v", opt, err)
+	}
+}
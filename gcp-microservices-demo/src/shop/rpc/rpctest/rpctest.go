@@ -0,0 +1,58 @@
+// Package rpctest runs shop functions in-process over every transport of
+// package rpc.
+package rpctest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/rpc"
+)
+
+// Options serves a function in-process over every transport and returns, per
+// transport name, the client option that reaches it as svc. h is the
+// function's Handler, which serves both HTTP/JSON and Connect calls, and s
+// the methods it serves over gRPC. Everything is shut down when the test
+// finishes.
+func Options(t testing.TB, svc shop.Service, h http.Handler, s *rpc.Service) map[string]shop.Option {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer()
+	s.Register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return map[string]shop.Option{
+		rpc.HTTP:    shop.WithServiceURL(svc, srv.URL),
+		rpc.GRPC:    shop.WithInvoker(svc, rpc.NewGRPCInvoker(conn)),
+		rpc.Connect: shop.WithInvoker(svc, rpc.NewConnectInvoker(srv.URL, srv.Client())),
+	}
+}
+
+// Clients is like Options but returns a client per transport name.
+func Clients(t testing.TB, svc shop.Service, h http.Handler, s *rpc.Service) map[string]*shop.Client {
+	t.Helper()
+	clients := make(map[string]*shop.Client)
+	for transport, opt := range Options(t, svc, h, s) {
+		clients[transport] = shop.New(opt)
+	}
+	return clients
+}
@@ -0,0 +1,170 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// Method is one unary operation of a Service.
+type Method struct {
+	// Name is the operation name, e.g. "GetProduct".
+	Name string
+	// New allocates the request message.
+	New func() interface{}
+	// Call runs the operation on the message returned by New. Errors created
+	// with status.Error keep their code; any other error is reported as
+	// codes.Unknown.
+	Call func(ctx context.Context, in interface{}) (interface{}, error)
+}
+
+// Service is a set of methods served under one gRPC service name.
+type Service struct {
+	Name    string
+	Methods []Method
+}
+
+// NewService returns the Service serving methods as the operations of svc.
+func NewService(svc shop.Service, methods ...Method) *Service {
+	return &Service{Name: ServiceName(svc), Methods: methods}
+}
+
+func (s *Service) method(name string) *Method {
+	for i := range s.Methods {
+		if s.Methods[i].Name == name {
+			return &s.Methods[i]
+		}
+	}
+	return nil
+}
+
+// Register adds s to g.
+func (s *Service) Register(g *grpc.Server) {
+	desc := &grpc.ServiceDesc{
+		ServiceName: s.Name,
+		HandlerType: (*interface{})(nil),
+	}
+	for i := range s.Methods {
+		m := &s.Methods[i]
+		fullMethod := "/" + s.Name + "/" + m.Name
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: m.Name,
+			Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, ic grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := m.New()
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				ctx = incomingMetadata(ctx)
+				if ic == nil {
+					return m.Call(ctx, in)
+				}
+				return ic(ctx, in, &grpc.UnaryServerInfo{Server: s, FullMethod: fullMethod}, m.Call)
+			},
+		})
+	}
+	g.RegisterService(desc, s)
+}
+
+// ServeConnect serves r if it is a Connect call of one of the methods of s,
+// that is a request whose path ends in /<service name>/<method>, and reports
+// whether it was. Handlers call it before their HTTP/JSON routing.
+func (s *Service) ServeConnect(w http.ResponseWriter, r *http.Request) bool {
+	i := strings.LastIndex(r.URL.Path, "/"+s.Name+"/")
+	if i < 0 {
+		return false
+	}
+	m := s.method(r.URL.Path[i+len(s.Name)+2:])
+	if m == nil {
+		writeConnectError(w, status.Errorf(codes.Unimplemented, "%s has no method %s", s.Name, r.URL.Path[i+len(s.Name)+2:]))
+		return true
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return true
+	}
+	if ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); ct != "application/json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return true
+	}
+
+	ctx := r.Context()
+	if _, ok := shop.FromContext(ctx); !ok {
+		ctx = shop.NewContext(telemetry.Extract(ctx, r.Header), shop.MetadataFromHeader(r.Header))
+	}
+	if ms, err := strconv.ParseInt(r.Header.Get("Connect-Timeout-Ms"), 10, 64); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+		defer cancel()
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeConnectError(w, status.Error(codes.InvalidArgument, err.Error()))
+		return true
+	}
+	in := m.New()
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, in); err != nil {
+			writeConnectError(w, status.Error(codes.InvalidArgument, err.Error()))
+			return true
+		}
+	}
+	out, err := m.Call(ctx, in)
+	if err != nil {
+		writeConnectError(w, err)
+		return true
+	}
+	res, err := json.Marshal(out)
+	if err != nil {
+		writeConnectError(w, status.Error(codes.Internal, err.Error()))
+		return true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(res)
+	return true
+}
+
+// connectError is the body of a failed Connect call.
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func writeConnectError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	body, _ := json.Marshal(&connectError{Code: connectCodes[st.Code()], Message: st.Message()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	w.Write(body)
+}
+
+// Serve serves svcs over gRPC on lis until it fails.
+func Serve(lis net.Listener, svcs ...*Service) error {
+	g := grpc.NewServer()
+	for _, s := range svcs {
+		s.Register(g)
+	}
+	return g.Serve(lis)
+}
+
+// ListenAndServe serves svcs over gRPC on the TCP address addr.
+func ListenAndServe(addr string, svcs ...*Service) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(lis, svcs...)
+}
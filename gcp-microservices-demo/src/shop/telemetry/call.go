@@ -0,0 +1,125 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer and meter of the shop
+// instrumentation.
+const InstrumentationName = "github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop"
+
+// Metric names.
+const (
+	ClientDuration = "shop.client.duration"
+	ServerDuration = "shop.server.duration"
+)
+
+// Attribute keys of the spans and metrics of calls.
+const (
+	ServiceKey   = attribute.Key("shop.service")
+	OperationKey = attribute.Key("shop.operation")
+	StatusKey    = attribute.Key("http.status_code")
+)
+
+// Tracer returns the tracer of the shop instrumentation from the global
+// tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Inject writes the trace context of ctx to h.
+func Inject(ctx context.Context, h http.Header) {
+	Propagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
+
+// Extract returns a copy of ctx carrying the trace context sent in h.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return Propagator().Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// Call is the span and timing of one call between the frontend and the
+// functions.
+type Call struct {
+	span      trace.Span
+	start     time.Time
+	metric    string
+	service   string
+	operation string
+}
+
+// StartClient starts the client span of a call to operation op of service.
+// The returned context carries the span to inject into the request.
+func StartClient(ctx context.Context, service, op string) (context.Context, *Call) {
+	return start(ctx, trace.SpanKindClient, ClientDuration, service, op)
+}
+
+// StartServer starts the server span of a call to operation op of service,
+// as a child of the trace context extracted into ctx. op may be set later
+// with SetOperation, once the request is routed.
+func StartServer(ctx context.Context, service, op string) (context.Context, *Call) {
+	return start(ctx, trace.SpanKindServer, ServerDuration, service, op)
+}
+
+func start(ctx context.Context, kind trace.SpanKind, metric, service, op string) (context.Context, *Call) {
+	c := &Call{start: time.Now(), metric: metric, service: service, operation: op}
+	ctx, c.span = Tracer().Start(ctx, c.name(), trace.WithSpanKind(kind), trace.WithAttributes(
+		ServiceKey.String(service),
+		OperationKey.String(op),
+	))
+	return ctx, c
+}
+
+func (c *Call) name() string {
+	if c.operation == "" {
+		return c.service
+	}
+	return c.service + "/" + c.operation
+}
+
+// SetOperation names the operation of c.
+func (c *Call) SetOperation(op string) {
+	c.operation = op
+	c.span.SetName(c.name())
+	c.span.SetAttributes(OperationKey.String(op))
+}
+
+// End ends c, which was answered with status, or failed with err before a
+// response, with status 0. Server errors and failed calls mark the span as
+// failed, and are counted as errors.
+func (c *Call) End(ctx context.Context, status int, err error) {
+	attrs := []attribute.KeyValue{
+		ServiceKey.String(c.service),
+		OperationKey.String(c.operation),
+		StatusKey.Int(status),
+	}
+	c.span.SetAttributes(StatusKey.Int(status))
+	if err != nil {
+		c.span.RecordError(err)
+	}
+	if status == 0 || status >= 500 {
+		msg := http.StatusText(status)
+		if err != nil {
+			msg = err.Error()
+		}
+		c.span.SetStatus(codes.Error, msg)
+	}
+	c.span.End()
+
+	ms := float64(time.Since(c.start)) / float64(time.Millisecond)
+	Duration(ctx, c.metric, ms, attrs...)
+	if status == 0 || status >= 500 {
+		errors := ServerErrors
+		if c.metric == ClientDuration {
+			errors = ClientErrors
+		}
+		Count(ctx, errors, 1, attrs...)
+	}
+}
@@ -0,0 +1,106 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/unit"
+)
+
+// Metric names besides the call durations. The call error counters are
+// recorded with the durations by Call.End, the frontend metrics by its
+// request logger, and the business counters by the service doing the
+// business.
+const (
+	ClientErrors = "shop.client.errors"
+	ServerErrors = "shop.server.errors"
+
+	FrontendRequests = "frontend.requests"
+	FrontendErrors   = "frontend.request.errors"
+	FrontendDuration = "frontend.request.duration"
+	FrontendDegraded = "frontend.degraded_sections"
+
+	OrdersPlaced  = "shop.orders.placed"
+	OrderRevenue  = "shop.orders.revenue"
+	CartAdds      = "shop.cart.adds"
+	AdImpressions = "shop.ads.impressions"
+
+	FaultsInjected = "shop.faults.injected"
+)
+
+// Attribute keys of the frontend and business metrics.
+const (
+	RouteKey    = attribute.Key("http.route")
+	MethodKey   = attribute.Key("http.method")
+	CurrencyKey = attribute.Key("shop.currency")
+	ProductKey  = attribute.Key("shop.product_id")
+	CategoryKey = attribute.Key("shop.ad.category")
+	SectionKey  = attribute.Key("frontend.section")
+	FaultKey    = attribute.Key("shop.fault")
+	SideKey     = attribute.Key("shop.fault.side")
+)
+
+var descriptions = map[string]string{
+	ClientDuration:   "Duration of the calls between the shop services, seen by the caller",
+	ServerDuration:   "Duration of the requests served by the Go functions",
+	ClientErrors:     "Calls between the shop services that failed before a response or with a server error",
+	ServerErrors:     "Requests to the Go functions answered with a server error",
+	FrontendRequests: "Page and form requests served by the frontend",
+	FrontendErrors:   "Frontend requests answered with a server error",
+	FrontendDuration: "Duration of the requests served by the frontend",
+	FrontendDegraded: "Optional page sections rendered as placeholders because their function failed or was too slow",
+	OrdersPlaced:     "Orders placed",
+	OrderRevenue:     "Total of the orders placed, in units of their currency",
+	CartAdds:         "Items added to a cart",
+	AdImpressions:    "Ads served",
+	FaultsInjected:   "Faults injected into the requests to the Go functions, or into the calls of the frontend",
+}
+
+// Count adds n to the counter name. Like the call metrics, counters are
+// looked up on the global meter provider on every use, so that they follow
+// the provider installed by Setup or a test.
+func Count(ctx context.Context, name string, n int64, attrs ...attribute.KeyValue) {
+	c, err := global.Meter(InstrumentationName).SyncInt64().Counter(name, instrument.WithDescription(descriptions[name]))
+	if err == nil {
+		c.Add(ctx, n, attrs...)
+	}
+}
+
+// Sum adds v to the floating-point counter name.
+func Sum(ctx context.Context, name string, v float64, attrs ...attribute.KeyValue) {
+	c, err := global.Meter(InstrumentationName).SyncFloat64().Counter(name, instrument.WithDescription(descriptions[name]))
+	if err == nil {
+		c.Add(ctx, v, attrs...)
+	}
+}
+
+// Duration records ms in the millisecond histogram name.
+func Duration(ctx context.Context, name string, ms float64, attrs ...attribute.KeyValue) {
+	h, err := global.Meter(InstrumentationName).SyncFloat64().Histogram(name,
+		instrument.WithUnit(unit.Milliseconds),
+		instrument.WithDescription(descriptions[name]),
+	)
+	if err == nil {
+		h.Record(ctx, ms, attrs...)
+	}
+}
+
+// metricsHandler holds the Prometheus exporter installed by Setup.
+var metricsHandler atomic.Value
+
+// MetricsHandler serves the metrics in the Prometheus text format when they
+// are exported with Prometheus, and answers 404 otherwise.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := metricsHandler.Load().(http.Handler)
+		if !ok {
+			http.Error(w, "metrics are not exported with Prometheus", http.StatusNotFound)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,208 @@
+// Package telemetry sets up OpenTelemetry tracing and metrics for the
+// frontend and the Go functions, and instruments the calls between them.
+//
+// Every call a shop.Client makes is a client span, and every request a
+// fission.Router serves a server span; both record their duration in a
+// histogram. Trace context crosses process boundaries in the W3C Trace
+// Context and B3 headers.
+//
+// Spans and metrics are exported as configured by Setup. Tests record them
+// in memory with package telemetrytest.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	selector "go.opentelemetry.io/otel/sdk/metric/selector/simple"
+)
+
+// Exporter names accepted by Config.
+const (
+	None   = "none"
+	Stdout = "stdout"
+	OTLP   = "otlp"
+	// Prometheus serves the metrics to scrapers from MetricsHandler. It
+	// only applies to metrics.
+	Prometheus = "prometheus"
+)
+
+// Config selects where spans and metrics go.
+type Config struct {
+	// Service is the service.name of the resource.
+	Service string
+	// Traces and Metrics are the exporters of spans and metrics: None,
+	// Stdout or OTLP, or Prometheus for metrics. Spans are created, and
+	// their IDs logged, even when they are not exported.
+	Traces  string
+	Metrics string
+	// Endpoint is the host:port of the OTLP/HTTP collector. It defaults to
+	// OTEL_EXPORTER_OTLP_ENDPOINT, or localhost:4318.
+	Endpoint string
+	// Insecure sends OTLP over plain HTTP.
+	Insecure bool
+	// Out receives the stdout exports. It defaults to os.Stdout.
+	Out io.Writer
+	// Interval is the period metrics are exported at. It defaults to 10s.
+	Interval time.Duration
+}
+
+// FromEnv returns the Config of service from OTEL_TRACES_EXPORTER,
+// OTEL_METRICS_EXPORTER and OTEL_EXPORTER_OTLP_INSECURE. Traces default to
+// None and metrics to Prometheus.
+func FromEnv(service string) Config {
+	cfg := Config{
+		Service:  service,
+		Traces:   os.Getenv("OTEL_TRACES_EXPORTER"),
+		Metrics:  os.Getenv("OTEL_METRICS_EXPORTER"),
+		Insecure: os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true",
+	}
+	if cfg.Traces == "" {
+		cfg.Traces = None
+	}
+	if cfg.Metrics == "" {
+		cfg.Metrics = Prometheus
+	}
+	return cfg
+}
+
+// Setup installs the global tracer and meter providers configured by cfg
+// and returns a function flushing and stopping them.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(cfg.Service))
+
+	spans, err := spanExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	if spans != nil {
+		opts = append(opts, sdktrace.WithBatcher(spans))
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+
+	metrics, temporality, err := metricExporter(ctx, cfg)
+	if err != nil {
+		tp.Shutdown(ctx)
+		return nil, err
+	}
+	var ctrl *controller.Controller
+	if cfg.Metrics == Prometheus {
+		// Prometheus pulls: the exporter collects on every scrape, and
+		// the processor remembers the instruments not updated since the
+		// previous one.
+		ctrl = controller.New(
+			processor.NewFactory(selector.NewWithHistogramDistribution(), aggregation.CumulativeTemporalitySelector(), processor.WithMemory(true)),
+			controller.WithResource(res),
+		)
+		exp, err := prometheus.New(prometheus.Config{}, ctrl)
+		if err != nil {
+			tp.Shutdown(ctx)
+			return nil, err
+		}
+		metricsHandler.Store(http.Handler(exp))
+		global.SetMeterProvider(exp.MeterProvider())
+	} else if metrics != nil {
+		ctrl = controller.New(
+			processor.NewFactory(selector.NewWithHistogramDistribution(), temporality),
+			controller.WithExporter(metrics),
+			controller.WithResource(res),
+			controller.WithCollectPeriod(cfg.Interval),
+		)
+		if err := ctrl.Start(ctx); err != nil {
+			tp.Shutdown(ctx)
+			return nil, err
+		}
+		global.SetMeterProvider(ctrl)
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(Propagator())
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if ctrl != nil {
+			if cerr := ctrl.Stop(ctx); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+func spanExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Traces {
+	case None, "":
+		return nil, nil
+	case Stdout:
+		return stdouttrace.New(stdouttrace.WithWriter(cfg.Out))
+	case OTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", cfg.Traces)
+}
+
+func metricExporter(ctx context.Context, cfg Config) (export.Exporter, aggregation.TemporalitySelector, error) {
+	switch cfg.Metrics {
+	case None, Prometheus, "":
+		return nil, nil, nil
+	case Stdout:
+		exp, err := stdoutmetric.New(stdoutmetric.WithWriter(cfg.Out))
+		return exp, exp, err
+	case OTLP:
+		var opts []otlpmetrichttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		exp, err := otlpmetrichttp.New(ctx, opts...)
+		return exp, aggregation.CumulativeTemporalitySelector(), err
+	}
+	return nil, nil, fmt.Errorf("unknown metric exporter %q", cfg.Metrics)
+}
+
+// Propagator reads and writes the trace context in the W3C Trace Context
+// and B3 headers. It is used whether or not Setup was called, so that trace
+// context passes through uninstrumented processes.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)),
+	)
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSetupStdout(t *testing.T) {
+	prevTP, prevMP := otel.GetTracerProvider(), global.MeterProvider()
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		global.SetMeterProvider(prevMP)
+	}()
+
+	var out bytes.Buffer
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, Config{Service: "frontend", Traces: Stdout, Metrics: Stdout, Out: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, c := StartClient(ctx, "productcatalogservice", "GetProduct")
+	c.End(ctx, http.StatusOK, nil)
+	if err := shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"Name":"productcatalogservice/GetProduct"`, `"frontend"`, ClientDuration} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("export does not contain %s:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	prevTP, prevMP := otel.GetTracerProvider(), global.MeterProvider()
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		global.SetMeterProvider(prevMP)
+	}()
+
+	ctx := context.Background()
+	if _, err := Setup(ctx, FromEnv("checkoutservice")); err != nil {
+		t.Fatal(err)
+	}
+	_, c := StartClient(ctx, "shippingservice", "ShipOrder")
+	c.End(ctx, http.StatusServiceUnavailable, nil)
+	Count(ctx, OrdersPlaced, 1, CurrencyKey.String("EUR"))
+
+	// The processor remembers the metrics of earlier scrapes.
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		for _, want := range []string{
+			`shop_client_duration_bucket{http_status_code="503",service_name="checkoutservice",shop_operation="ShipOrder",shop_service="shippingservice",le="+Inf"} 1`,
+			`shop_client_errors{http_status_code="503",service_name="checkoutservice",shop_operation="ShipOrder",shop_service="shippingservice"} 1`,
+			`shop_orders_placed{service_name="checkoutservice",shop_currency="EUR"} 1`,
+		} {
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("scrape %d does not contain %s:\n%s", i, want, rec.Body)
+			}
+		}
+	}
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Traces: "zipkin"}); err == nil {
+		t.Error("Setup with an unknown trace exporter succeeded")
+	}
+	if _, err := Setup(context.Background(), Config{Metrics: "statsd"}); err == nil {
+		t.Error("Setup with an unknown metric exporter succeeded")
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	h := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	ctx := Extract(context.Background(), h)
+	out := http.Header{}
+	Inject(ctx, out)
+	if got := out.Get("Traceparent"); got != h.Get("Traceparent") {
+		t.Errorf("traceparent = %q, want %q", got, h.Get("Traceparent"))
+	}
+	if got, want := out.Get("X-B3-Traceid"), trace.SpanContextFromContext(ctx).TraceID().String(); got != want {
+		t.Errorf("X-B3-TraceId = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,121 @@
+// Package telemetrytest records the spans and metrics of a test in memory.
+package telemetrytest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/global"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric/metrictest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eb-k8s/serverless-demos-in-fission/gcp-microservices-demo/src/shop/telemetry"
+)
+
+// Recorder holds the spans and metrics recorded since New.
+type Recorder struct {
+	spans   *tracetest.SpanRecorder
+	metrics *sdkmetric.Exporter
+	// records holds the latest cumulative record of every metric collected
+	// so far: the SDK only exports the records updated since the previous
+	// collection.
+	records map[string]sdkmetric.ExportRecord
+}
+
+// New installs global tracer and meter providers recording in memory for
+// the rest of the test.
+func New(t testing.TB) *Recorder {
+	r := &Recorder{spans: tracetest.NewSpanRecorder(), records: make(map[string]sdkmetric.ExportRecord)}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(r.spans))
+	mp, exp := sdkmetric.NewTestMeterProvider()
+	r.metrics = exp
+
+	prevTP, prevMP, prevProp := otel.GetTracerProvider(), global.MeterProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	global.SetMeterProvider(mp)
+	otel.SetTextMapPropagator(telemetry.Propagator())
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		global.SetMeterProvider(prevMP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return r
+}
+
+// Spans returns the spans ended so far, in the order they started.
+func (r *Recorder) Spans() []sdktrace.ReadOnlySpan {
+	spans := r.spans.Ended()
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartTime().Before(spans[j].StartTime()) })
+	return spans
+}
+
+// Tree renders the ended spans as an indented tree, one span per line with
+// its kind, children below their parent in the order they started:
+//
+//	POST /cart/checkout (server)
+//	  checkoutservice/PlaceOrder (client)
+//	    checkoutservice/PlaceOrder (server)
+func (r *Recorder) Tree() string {
+	spans := r.Spans()
+	ended := make(map[trace.SpanID]bool)
+	for _, s := range spans {
+		ended[s.SpanContext().SpanID()] = true
+	}
+	children := make(map[trace.SpanID][]sdktrace.ReadOnlySpan)
+	var roots []sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		if p := s.Parent().SpanID(); s.Parent().IsValid() && ended[p] {
+			children[p] = append(children[p], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+	var b strings.Builder
+	var walk func(s sdktrace.ReadOnlySpan, depth int)
+	walk = func(s sdktrace.ReadOnlySpan, depth int) {
+		b.WriteString(strings.Repeat("  ", depth) + s.Name() + " (" + s.SpanKind().String() + ")\n")
+		for _, c := range children[s.SpanContext().SpanID()] {
+			walk(c, depth+1)
+		}
+	}
+	for _, s := range roots {
+		walk(s, 0)
+	}
+	return b.String()
+}
+
+// Metric returns the record of the metric name whose attributes include
+// attrs, collecting the metrics recorded so far.
+func (r *Recorder) Metric(t testing.TB, name string, attrs ...attribute.KeyValue) sdkmetric.ExportRecord {
+	t.Helper()
+	if err := r.metrics.Collect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range r.metrics.Records {
+		set := attribute.NewSet(rec.Attributes...)
+		r.records[rec.InstrumentName+"|"+string(set.Encoded(attribute.DefaultEncoder()))] = rec
+	}
+	for _, rec := range r.records {
+		if rec.InstrumentName == name && hasAttributes(rec.Attributes, attrs) {
+			return rec
+		}
+	}
+	t.Fatalf("metric %s%v not recorded", name, attrs)
+	return sdkmetric.ExportRecord{}
+}
+
+func hasAttributes(have, want []attribute.KeyValue) bool {
+	set := attribute.NewSet(have...)
+	for _, kv := range want {
+		if v, ok := set.Value(kv.Key); !ok || v != kv.Value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,1002 @@
+// Code generated by shopgen from docs/openapi.yaml. DO NOT EDIT.
+
+package shop
+
+// Represents an amount of money with its currency type.
+type Money struct {
+	// The 3-letter currency code defined in ISO 4217.
+	CurrencyCode string `json:"currency_code,omitempty"`
+	// The whole units of the amount.
+	// For example if `currencyCode` is `"USD"`, then 1 unit is one US dollar.
+	Units int64 `json:"units,omitempty"`
+	// Number of nano (10^-9) units of the amount.
+	// The value must be between -999,999,999 and +999,999,999 inclusive.
+	// If `units` is positive, `nanos` must be positive or zero.
+	// If `units` is zero, `nanos` can be positive, zero, or negative.
+	// If `units` is negative, `nanos` must be negative or zero.
+	// For example $-1.75 is represented as `units`=-1 and `nanos`=-750,000,000.
+	Nanos int32 `json:"nanos,omitempty"`
+}
+
+func (m *Money) GetCurrencyCode() string {
+	if m != nil {
+		return m.CurrencyCode
+	}
+	return ""
+}
+
+func (m *Money) GetUnits() int64 {
+	if m != nil {
+		return m.Units
+	}
+	return 0
+}
+
+func (m *Money) GetNanos() int32 {
+	if m != nil {
+		return m.Nanos
+	}
+	return 0
+}
+
+type Product struct {
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Picture     string `json:"picture,omitempty"`
+	PriceUsd    *Money `json:"price_usd,omitempty"`
+	// Categories such as "clothing" or "kitchen" that can be used to look up
+	// other related products.
+	Categories []string `json:"categories,omitempty"`
+}
+
+func (m *Product) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Product) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Product) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Product) GetPicture() string {
+	if m != nil {
+		return m.Picture
+	}
+	return ""
+}
+
+func (m *Product) GetPriceUsd() *Money {
+	if m != nil {
+		return m.PriceUsd
+	}
+	return nil
+}
+
+func (m *Product) GetCategories() []string {
+	if m != nil {
+		return m.Categories
+	}
+	return nil
+}
+
+type ListProductsResponse struct {
+	Products []*Product `json:"products,omitempty"`
+}
+
+func (m *ListProductsResponse) GetProducts() []*Product {
+	if m != nil {
+		return m.Products
+	}
+	return nil
+}
+
+type GetProductRequest struct {
+	Id string `json:"id,omitempty"`
+}
+
+func (m *GetProductRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type SearchProductsRequest struct {
+	Query string `json:"query,omitempty"`
+}
+
+func (m *SearchProductsRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+type BrowseProductsRequest struct {
+	Category    string `json:"category,omitempty"`
+	MinPriceUsd int64  `json:"min_price_usd,omitempty"`
+	MaxPriceUsd int64  `json:"max_price_usd,omitempty"`
+	Sort        string `json:"sort,omitempty"`
+	Page        int32  `json:"page,omitempty"`
+	PageSize    int32  `json:"page_size,omitempty"`
+}
+
+func (m *BrowseProductsRequest) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *BrowseProductsRequest) GetMinPriceUsd() int64 {
+	if m != nil {
+		return m.MinPriceUsd
+	}
+	return 0
+}
+
+func (m *BrowseProductsRequest) GetMaxPriceUsd() int64 {
+	if m != nil {
+		return m.MaxPriceUsd
+	}
+	return 0
+}
+
+func (m *BrowseProductsRequest) GetSort() string {
+	if m != nil {
+		return m.Sort
+	}
+	return ""
+}
+
+func (m *BrowseProductsRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *BrowseProductsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+type BrowseProductsResponse struct {
+	Products []*Product `json:"products,omitempty"`
+	// The number of products matching the filters, on every page.
+	TotalSize int32 `json:"total_size,omitempty"`
+	// Every category of the catalog, sorted, for navigation.
+	Categories []string `json:"categories,omitempty"`
+}
+
+func (m *BrowseProductsResponse) GetProducts() []*Product {
+	if m != nil {
+		return m.Products
+	}
+	return nil
+}
+
+func (m *BrowseProductsResponse) GetTotalSize() int32 {
+	if m != nil {
+		return m.TotalSize
+	}
+	return 0
+}
+
+func (m *BrowseProductsResponse) GetCategories() []string {
+	if m != nil {
+		return m.Categories
+	}
+	return nil
+}
+
+type SearchProductsResponse struct {
+	Results []*Product `json:"results,omitempty"`
+}
+
+func (m *SearchProductsResponse) GetResults() []*Product {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type CartItem struct {
+	ProductId string `json:"product_id,omitempty"`
+	Quantity  int32  `json:"quantity,omitempty"`
+}
+
+func (m *CartItem) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+func (m *CartItem) GetQuantity() int32 {
+	if m != nil {
+		return m.Quantity
+	}
+	return 0
+}
+
+type AddItemRequest struct {
+	UserId string    `json:"user_id,omitempty"`
+	Item   *CartItem `json:"item,omitempty"`
+}
+
+func (m *AddItemRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *AddItemRequest) GetItem() *CartItem {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+type GetCartRequest struct {
+	UserId string `json:"user_id,omitempty"`
+}
+
+func (m *GetCartRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+type Cart struct {
+	UserId string      `json:"user_id,omitempty"`
+	Items  []*CartItem `json:"items,omitempty"`
+}
+
+func (m *Cart) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *Cart) GetItems() []*CartItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type EmptyCartRequest struct {
+	UserId string `json:"user_id,omitempty"`
+}
+
+func (m *EmptyCartRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+type UpdateItemRequest struct {
+	UserId string    `json:"user_id,omitempty"`
+	Item   *CartItem `json:"item,omitempty"`
+}
+
+func (m *UpdateItemRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *UpdateItemRequest) GetItem() *CartItem {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+type RemoveItemRequest struct {
+	UserId    string `json:"user_id,omitempty"`
+	ProductId string `json:"product_id,omitempty"`
+}
+
+func (m *RemoveItemRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *RemoveItemRequest) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+type GetSupportedCurrenciesResponse struct {
+	CurrencyCodes []string `json:"currency_codes,omitempty"`
+}
+
+func (m *GetSupportedCurrenciesResponse) GetCurrencyCodes() []string {
+	if m != nil {
+		return m.CurrencyCodes
+	}
+	return nil
+}
+
+type CurrencyConversionRequest struct {
+	From *Money `json:"from,omitempty"`
+	// The 3-letter currency code defined in ISO 4217.
+	ToCode string `json:"to_code,omitempty"`
+}
+
+func (m *CurrencyConversionRequest) GetFrom() *Money {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *CurrencyConversionRequest) GetToCode() string {
+	if m != nil {
+		return m.ToCode
+	}
+	return ""
+}
+
+type CurrencyConversionBatchRequest struct {
+	From []*Money `json:"from,omitempty"`
+	// The 3-letter currency code defined in ISO 4217.
+	ToCode string `json:"to_code,omitempty"`
+}
+
+func (m *CurrencyConversionBatchRequest) GetFrom() []*Money {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *CurrencyConversionBatchRequest) GetToCode() string {
+	if m != nil {
+		return m.ToCode
+	}
+	return ""
+}
+
+type CurrencyConversionBatchResponse struct {
+	// Converted amounts, in the same order as the request's From list.
+	Results []*Money `json:"results,omitempty"`
+}
+
+func (m *CurrencyConversionBatchResponse) GetResults() []*Money {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+type Address struct {
+	StreetAddress string `json:"street_address,omitempty"`
+	City          string `json:"city,omitempty"`
+	State         string `json:"state,omitempty"`
+	Country       string `json:"country,omitempty"`
+	ZipCode       int32  `json:"zip_code,omitempty"`
+}
+
+func (m *Address) GetStreetAddress() string {
+	if m != nil {
+		return m.StreetAddress
+	}
+	return ""
+}
+
+func (m *Address) GetCity() string {
+	if m != nil {
+		return m.City
+	}
+	return ""
+}
+
+func (m *Address) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Address) GetCountry() string {
+	if m != nil {
+		return m.Country
+	}
+	return ""
+}
+
+func (m *Address) GetZipCode() int32 {
+	if m != nil {
+		return m.ZipCode
+	}
+	return 0
+}
+
+type GetQuoteRequest struct {
+	Address *Address    `json:"address,omitempty"`
+	Items   []*CartItem `json:"items,omitempty"`
+}
+
+func (m *GetQuoteRequest) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *GetQuoteRequest) GetItems() []*CartItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type GetQuoteResponse struct {
+	CostUsd *Money `json:"cost_usd,omitempty"`
+}
+
+func (m *GetQuoteResponse) GetCostUsd() *Money {
+	if m != nil {
+		return m.CostUsd
+	}
+	return nil
+}
+
+type ShipOrderRequest struct {
+	Address *Address    `json:"address,omitempty"`
+	Items   []*CartItem `json:"items,omitempty"`
+}
+
+func (m *ShipOrderRequest) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *ShipOrderRequest) GetItems() []*CartItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type ShipOrderResponse struct {
+	TrackingId string `json:"tracking_id,omitempty"`
+}
+
+func (m *ShipOrderResponse) GetTrackingId() string {
+	if m != nil {
+		return m.TrackingId
+	}
+	return ""
+}
+
+type TrackShipmentRequest struct {
+	TrackingId string `json:"tracking_id,omitempty"`
+}
+
+func (m *TrackShipmentRequest) GetTrackingId() string {
+	if m != nil {
+		return m.TrackingId
+	}
+	return ""
+}
+
+type ShipmentEvent struct {
+	// label_created, in_transit, out_for_delivery or delivered.
+	Status      string `json:"status,omitempty"`
+	Description string `json:"description,omitempty"`
+	// When the event happened, or is expected to.
+	Time string `json:"time,omitempty"`
+	// Whether the event has happened.
+	Done bool `json:"done,omitempty"`
+}
+
+func (m *ShipmentEvent) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *ShipmentEvent) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *ShipmentEvent) GetTime() string {
+	if m != nil {
+		return m.Time
+	}
+	return ""
+}
+
+func (m *ShipmentEvent) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+type TrackShipmentResponse struct {
+	TrackingId string `json:"tracking_id,omitempty"`
+	// The status of the last event that happened.
+	Status            string           `json:"status,omitempty"`
+	Events            []*ShipmentEvent `json:"events,omitempty"`
+	EstimatedDelivery string           `json:"estimated_delivery,omitempty"`
+}
+
+func (m *TrackShipmentResponse) GetTrackingId() string {
+	if m != nil {
+		return m.TrackingId
+	}
+	return ""
+}
+
+func (m *TrackShipmentResponse) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *TrackShipmentResponse) GetEvents() []*ShipmentEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *TrackShipmentResponse) GetEstimatedDelivery() string {
+	if m != nil {
+		return m.EstimatedDelivery
+	}
+	return ""
+}
+
+type CreditCardInfo struct {
+	CreditCardNumber          string `json:"credit_card_number,omitempty"`
+	CreditCardCvv             int32  `json:"credit_card_cvv,omitempty"`
+	CreditCardExpirationYear  int32  `json:"credit_card_expiration_year,omitempty"`
+	CreditCardExpirationMonth int32  `json:"credit_card_expiration_month,omitempty"`
+}
+
+func (m *CreditCardInfo) GetCreditCardNumber() string {
+	if m != nil {
+		return m.CreditCardNumber
+	}
+	return ""
+}
+
+func (m *CreditCardInfo) GetCreditCardCvv() int32 {
+	if m != nil {
+		return m.CreditCardCvv
+	}
+	return 0
+}
+
+func (m *CreditCardInfo) GetCreditCardExpirationYear() int32 {
+	if m != nil {
+		return m.CreditCardExpirationYear
+	}
+	return 0
+}
+
+func (m *CreditCardInfo) GetCreditCardExpirationMonth() int32 {
+	if m != nil {
+		return m.CreditCardExpirationMonth
+	}
+	return 0
+}
+
+type ChargeRequest struct {
+	Amount     *Money          `json:"amount,omitempty"`
+	CreditCard *CreditCardInfo `json:"credit_card,omitempty"`
+}
+
+func (m *ChargeRequest) GetAmount() *Money {
+	if m != nil {
+		return m.Amount
+	}
+	return nil
+}
+
+func (m *ChargeRequest) GetCreditCard() *CreditCardInfo {
+	if m != nil {
+		return m.CreditCard
+	}
+	return nil
+}
+
+type ChargeResponse struct {
+	TransactionId string `json:"transaction_id,omitempty"`
+}
+
+func (m *ChargeResponse) GetTransactionId() string {
+	if m != nil {
+		return m.TransactionId
+	}
+	return ""
+}
+
+type OrderItem struct {
+	Item *CartItem `json:"item,omitempty"`
+	Cost *Money    `json:"cost,omitempty"`
+}
+
+func (m *OrderItem) GetItem() *CartItem {
+	if m != nil {
+		return m.Item
+	}
+	return nil
+}
+
+func (m *OrderItem) GetCost() *Money {
+	if m != nil {
+		return m.Cost
+	}
+	return nil
+}
+
+type OrderResult struct {
+	OrderId            string       `json:"order_id,omitempty"`
+	ShippingTrackingId string       `json:"shipping_tracking_id,omitempty"`
+	ShippingCost       *Money       `json:"shipping_cost,omitempty"`
+	ShippingAddress    *Address     `json:"shipping_address,omitempty"`
+	Items              []*OrderItem `json:"items,omitempty"`
+}
+
+func (m *OrderResult) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+func (m *OrderResult) GetShippingTrackingId() string {
+	if m != nil {
+		return m.ShippingTrackingId
+	}
+	return ""
+}
+
+func (m *OrderResult) GetShippingCost() *Money {
+	if m != nil {
+		return m.ShippingCost
+	}
+	return nil
+}
+
+func (m *OrderResult) GetShippingAddress() *Address {
+	if m != nil {
+		return m.ShippingAddress
+	}
+	return nil
+}
+
+func (m *OrderResult) GetItems() []*OrderItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type SendOrderConfirmationRequest struct {
+	Email string       `json:"email,omitempty"`
+	Order *OrderResult `json:"order,omitempty"`
+}
+
+func (m *SendOrderConfirmationRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *SendOrderConfirmationRequest) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+type PlaceOrderRequest struct {
+	UserId       string          `json:"user_id,omitempty"`
+	UserCurrency string          `json:"user_currency,omitempty"`
+	Address      *Address        `json:"address,omitempty"`
+	Email        string          `json:"email,omitempty"`
+	CreditCard   *CreditCardInfo `json:"credit_card,omitempty"`
+}
+
+func (m *PlaceOrderRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetUserCurrency() string {
+	if m != nil {
+		return m.UserCurrency
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *PlaceOrderRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetCreditCard() *CreditCardInfo {
+	if m != nil {
+		return m.CreditCard
+	}
+	return nil
+}
+
+type PlaceOrderResponse struct {
+	Order *OrderResult `json:"order,omitempty"`
+}
+
+func (m *PlaceOrderResponse) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+// An order as checkoutservice keeps it for the order history.
+type Order struct {
+	Order    *OrderResult `json:"order,omitempty"`
+	Total    *Money       `json:"total,omitempty"`
+	PlacedAt string       `json:"placed_at,omitempty"`
+}
+
+func (m *Order) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+func (m *Order) GetTotal() *Money {
+	if m != nil {
+		return m.Total
+	}
+	return nil
+}
+
+func (m *Order) GetPlacedAt() string {
+	if m != nil {
+		return m.PlacedAt
+	}
+	return ""
+}
+
+type ListOrdersRequest struct {
+	UserId    string `json:"user_id,omitempty"`
+	PageSize  int32  `json:"page_size,omitempty"`
+	PageToken string `json:"page_token,omitempty"`
+}
+
+func (m *ListOrdersRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *ListOrdersRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListOrdersRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+type ListOrdersResponse struct {
+	Orders []*Order `json:"orders,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+func (m *ListOrdersResponse) GetOrders() []*Order {
+	if m != nil {
+		return m.Orders
+	}
+	return nil
+}
+
+func (m *ListOrdersResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
+type GetOrderRequest struct {
+	UserId  string `json:"user_id,omitempty"`
+	OrderId string `json:"order_id,omitempty"`
+}
+
+func (m *GetOrderRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *GetOrderRequest) GetOrderId() string {
+	if m != nil {
+		return m.OrderId
+	}
+	return ""
+}
+
+type AdRequest struct {
+	// List of important key words from the current page describing the context.
+	ContextKeys []string `json:"context_keys,omitempty"`
+}
+
+func (m *AdRequest) GetContextKeys() []string {
+	if m != nil {
+		return m.ContextKeys
+	}
+	return nil
+}
+
+type Ad struct {
+	// url to redirect to when an ad is clicked.
+	RedirectUrl string `json:"redirect_url,omitempty"`
+	// short advertisement text to display.
+	Text string `json:"text,omitempty"`
+}
+
+func (m *Ad) GetRedirectUrl() string {
+	if m != nil {
+		return m.RedirectUrl
+	}
+	return ""
+}
+
+func (m *Ad) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+type AdResponse struct {
+	Ads []*Ad `json:"ads,omitempty"`
+}
+
+func (m *AdResponse) GetAds() []*Ad {
+	if m != nil {
+		return m.Ads
+	}
+	return nil
+}
+
+type ListRecommendationsRequest struct {
+	UserId     string   `json:"user_id,omitempty"`
+	ProductIds []string `json:"product_ids,omitempty"`
+}
+
+func (m *ListRecommendationsRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *ListRecommendationsRequest) GetProductIds() []string {
+	if m != nil {
+		return m.ProductIds
+	}
+	return nil
+}
+
+type ListRecommendationsResponse struct {
+	ProductIds []string `json:"product_ids,omitempty"`
+}
+
+func (m *ListRecommendationsResponse) GetProductIds() []string {
+	if m != nil {
+		return m.ProductIds
+	}
+	return nil
+}
+
+// The error envelope of the Go functions.
+type ErrorResponse struct {
+	// Machine-readable error code, e.g. not_found.
+	Code string `json:"code,omitempty"`
+	// Human-readable description of the failure.
+	Message string `json:"message,omitempty"`
+	// Further facts about the failure, e.g. the invalid fields.
+	Details []string `json:"details,omitempty"`
+	// ID of the failed request, for correlating logs.
+	RequestId string `json:"request_id,omitempty"`
+	// Whether sending the same request again may succeed.
+	Retryable bool `json:"retryable,omitempty"`
+}
+
+func (m *ErrorResponse) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+func (m *ErrorResponse) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *ErrorResponse) GetDetails() []string {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
+func (m *ErrorResponse) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *ErrorResponse) GetRetryable() bool {
+	if m != nil {
+		return m.Retryable
+	}
+	return false
+}